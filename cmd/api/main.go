@@ -2,24 +2,57 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"log/slog"
 	"os"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/kiennyo/syncwatch-be/internal/config"
 	"github.com/kiennyo/syncwatch-be/internal/db"
+	"github.com/kiennyo/syncwatch-be/internal/db/keyset"
 	"github.com/kiennyo/syncwatch-be/internal/domain/users"
+	"github.com/kiennyo/syncwatch-be/internal/health"
 	"github.com/kiennyo/syncwatch-be/internal/http"
+	"github.com/kiennyo/syncwatch-be/internal/http/cors"
+	"github.com/kiennyo/syncwatch-be/internal/http/idempotency"
+	"github.com/kiennyo/syncwatch-be/internal/http/version"
+	"github.com/kiennyo/syncwatch-be/internal/logger"
 	"github.com/kiennyo/syncwatch-be/internal/mail"
+	"github.com/kiennyo/syncwatch-be/internal/ratelimit"
 	"github.com/kiennyo/syncwatch-be/internal/security"
+	"github.com/kiennyo/syncwatch-be/internal/tracing"
+	"github.com/kiennyo/syncwatch-be/migrations"
 )
 
 func main() {
 	ctx := context.Background()
 
-	cfg := config.Load()
+	// until the configured level and format are known
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		if !errors.Is(err, config.ErrPrinted) && !errors.Is(err, flag.ErrHelp) {
+			slog.Error("Invalid configuration", "reason", err.Error()) // Fatal
+		}
+		return
+	}
+
+	slog.SetDefault(logger.New(os.Stdout, cfg.Log))
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		slog.Error("Failed to set up tracing", "reason", err.Error()) // Fatal
+		return
+	}
 
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
-	slog.SetDefault(logger)
+	defer func() {
+		if err := shutdownTracing(context.WithoutCancel(ctx)); err != nil {
+			slog.Error("Failed to flush traces", "reason", err.Error())
+		}
+	}()
 
 	postgres, err := db.New(ctx, cfg.DB)
 	if err != nil {
@@ -27,16 +60,76 @@ func main() {
 		return
 	}
 
-	mailer := mail.New(cfg.SMTP)
+	prometheus.MustRegister(db.NewPoolCollector(postgres))
+
+	migrator, err := db.NewMigrator(postgres, migrations.FS)
+	if err != nil {
+		slog.Error("Failed to load migrations", "reason", err.Error()) // Fatal
+		return
+	}
+
+	if cfg.DB.MigrateOnStartup {
+		if err = migrator.Up(ctx); err != nil {
+			slog.Error("Failed to migrate db", "reason", err.Error()) // Fatal
+			return
+		}
+	}
+
+	tx := db.NewTxManager(postgres)
+
+	// mail module setup
+	templates, err := mail.LoadTemplates()
+	if err != nil {
+		slog.Error("Failed to load mail templates", "reason", err.Error()) // Fatal
+		return
+	}
+	mailRepo := mail.NewRepository(tx)
+	mailer := mail.New(cfg.SMTP, mailRepo, templates)
+	mailHandler := mail.NewHandler(mailRepo, tx, templates, mailer, cfg.SMTP.WebhookSecret)
+
 	tokens := security.NewTokenFactory(cfg.Security)
 
+	var limitStore ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimit.Store == "postgres" {
+		limitStore = ratelimit.NewPostgresStore(tx)
+	}
+
+	limiter, err := ratelimit.New(cfg.RateLimit, limitStore)
+	if err != nil {
+		slog.Error("Invalid rate limit configuration", "reason", err.Error()) // Fatal
+		return
+	}
+
+	// anonymous idempotency keys are scoped by the client address, as rate limits are
+	proxies, err := ratelimit.ParseProxies(cfg.RateLimit.TrustedProxies)
+	if err != nil {
+		slog.Error("Invalid RATE_LIMIT_TRUSTED_PROXIES", "reason", err.Error()) // Fatal
+		return
+	}
+
 	// users module setup
-	userRepo := users.NewRepository(postgres)
-	userService := users.NewService(userRepo, tokens, mailer)
-	usersHandler := users.NewHandler(userService)
+	userRepo := users.NewRepository(tx)
+	userService := users.NewService(userRepo, tx, tokens, mailer)
+	usersHandler := users.NewHandler(userService, limiter, keyset.NewSigner(cfg.Security.JWTSecret))
+
+	checker := health.New(cfg.Health.CheckTimeout,
+		health.Postgres(postgres),
+		health.SMTP(cfg.SMTP.Host, cfg.SMTP.Port),
+		health.Migrations(migrator),
+		health.Backlog(cfg.Health.MaxBacklog),
+	)
 
-	server := http.New(cfg.HTTP, tokens).
-		AddRoutes("/users", usersHandler.Handlers())
+	server := http.New(cfg.HTTP, security.NewAuthMiddleware(tokens, cfg.Security)).
+		AddCORS(cors.New(cfg.CORS)).
+		AddIdempotency(idempotency.New(cfg.Idempotency, idempotency.NewPostgresStore(tx), proxies)).
+		AddHealth(checker).
+		AddVersion(version.Version{Name: "v1"}).
+		AddRoutes("v1", "/users", usersHandler).
+		AddRoutes("v1", "/mail", mailHandler)
+
+	if !cfg.App.Production() {
+		server.AddResponseValidation()
+	}
 
 	if err = server.Serve(); err != nil {
 		slog.Error("Failed to start server", "reason", err.Error()) // Fatal
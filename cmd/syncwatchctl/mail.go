@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/kiennyo/syncwatch-be/internal/mail"
+)
+
+var mailCommands = map[string]command{
+	"send-test": {
+		usage: "[--template user_welcome.gohtml] [--locale en] [--data '{\"key\":\"value\"}'] <recipient>",
+		run:   mailSendTest,
+	},
+}
+
+func mailSendTest(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("mail send-test", flag.ContinueOnError)
+	template := fs.String("template", "user_welcome.gohtml", "template to render")
+	locale := fs.String("locale", mail.DefaultLocale, "locale of the template")
+	rawData := fs.String("data", "{}", "template data as a JSON object")
+
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal([]byte(*rawData), &data); err != nil {
+		return fmt.Errorf("%w: --data must be a JSON object: %w", errUsage, err)
+	}
+
+	templates, err := mail.LoadTemplates()
+	if err != nil {
+		return err
+	}
+
+	pool, err := a.db(ctx)
+	if err != nil {
+		return err
+	}
+
+	sender := mail.New(a.cfg.SMTP, mail.NewRepository(pool), templates)
+	if err = sender.Send(ctx, fs.Arg(0), *locale, *template, data); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(a.out, "sent %s (%s) to %s\n", *template, *locale, fs.Arg(0))
+
+	return err
+}
@@ -0,0 +1,179 @@
+// Command syncwatchctl administers a syncwatch deployment: users, roles, migrations,
+// tokens and mail. It reads the same configuration as the API server.
+//
+// Usage:
+//
+//	syncwatchctl [config flags] <group> <command> [flags] [args]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/kiennyo/syncwatch-be/internal/config"
+	"github.com/kiennyo/syncwatch-be/internal/db"
+)
+
+var errUsage = errors.New("invalid usage")
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, a *app, args []string) error
+}
+
+// commands is keyed by group and then by command name.
+var commands = map[string]map[string]command{
+	"user":    userCommands,
+	"role":    roleCommands,
+	"migrate": migrateCommands,
+	"token":   tokenCommands,
+	"mail":    mailCommands,
+}
+
+// app holds what commands share, the database pool is only opened by commands needing it.
+type app struct {
+	cfg  config.Config
+	pool *pgxpool.Pool
+	out  io.Writer
+}
+
+func (a *app) db(ctx context.Context) (*pgxpool.Pool, error) {
+	if a.pool != nil {
+		return a.pool, nil
+	}
+
+	pool, err := db.New(ctx, a.cfg.DB)
+	if err != nil {
+		return nil, fmt.Errorf("connect to db: %w", err)
+	}
+	a.pool = pool
+
+	return pool, nil
+}
+
+func (a *app) table() *tabwriter.Writer {
+	return tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+}
+
+func (a *app) close() {
+	if a.pool != nil {
+		a.pool.Close()
+	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	// everything before the group name is passed on to the config loader
+	i := slices.IndexFunc(args, func(arg string) bool {
+		_, ok := commands[arg]
+		return ok
+	})
+
+	if i < 0 {
+		printUsage(stderr, "")
+		return 2
+	}
+
+	group := args[i]
+	if i+1 >= len(args) {
+		printUsage(stderr, group)
+		return 2
+	}
+
+	name := args[i+1]
+
+	cmd, ok := commands[group][name]
+	if !ok {
+		printUsage(stderr, group)
+		return 2
+	}
+
+	cfg, err := config.Load(args[:i])
+	switch {
+	case errors.Is(err, config.ErrPrinted), errors.Is(err, flag.ErrHelp):
+		return 0
+	case err != nil:
+		_, _ = fmt.Fprintf(stderr, "invalid configuration: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	a := &app{cfg: cfg, out: stdout}
+	defer a.close()
+
+	err = cmd.run(ctx, a, args[i+2:])
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		_, _ = fmt.Fprintf(stderr, "%v\nusage: syncwatchctl %s %s %s\n", err, group, name, cmd.usage)
+		return 2
+	case err != nil:
+		_, _ = fmt.Fprintf(stderr, "%s %s: %v\n", group, name, err)
+		return 1
+	}
+
+	return 0
+}
+
+// printUsage lists the commands of the group, or of all groups when it's empty.
+func printUsage(w io.Writer, group string) {
+	_, _ = fmt.Fprintln(w, "usage: syncwatchctl [config flags] <group> <command> [flags] [args]")
+	_, _ = fmt.Fprintln(w, "\ncommands:")
+
+	groups := []string{group}
+	if group == "" {
+		groups = groups[:0]
+		for g := range commands {
+			groups = append(groups, g)
+		}
+		sort.Strings(groups)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, g := range groups {
+		names := make([]string, 0, len(commands[g]))
+		for name := range commands[g] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			_, _ = fmt.Fprintf(tw, "  %s %s\t%s\n", g, name, commands[g][name].usage)
+		}
+	}
+	_ = tw.Flush()
+}
+
+// parse parses the command flags and checks the number of positional arguments.
+func parse(fs *flag.FlagSet, args []string, positional int) error {
+	fs.SetOutput(io.Discard)
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return errUsage
+		}
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+
+	if fs.NArg() != positional {
+		return fmt.Errorf("%w: expected %d argument(s), got %d", errUsage, positional, fs.NArg())
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiennyo/syncwatch-be/internal/config"
+	"github.com/kiennyo/syncwatch-be/internal/security"
+)
+
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("DB_URL", "postgres://localhost/syncwatch")
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("SMTP_HOST", "localhost")
+	t.Setenv("SMTP_SENDER", "no-reply@syncwatch.io")
+}
+
+func TestRun_Usage(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		code     int
+		expected string
+	}{
+		{
+			name:     "No command",
+			args:     nil,
+			code:     2,
+			expected: "migrate status",
+		},
+		{
+			name:     "Unknown command",
+			args:     []string{"user", "delete"},
+			code:     2,
+			expected: "user set-role",
+		},
+		{
+			name:     "Missing argument",
+			args:     []string{"user", "activate"},
+			code:     2,
+			expected: "expected 1 argument(s), got 0",
+		},
+		{
+			name:     "Invalid flags",
+			args:     []string{"token", "mint", "--user", "id", "--sub", "service"},
+			code:     2,
+			expected: "exactly one of --user and --sub must be set",
+		},
+	}
+
+	setRequiredEnv(t)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+
+			code := run(tc.args, stdout, stderr)
+
+			assert.Equal(t, tc.code, code)
+			assert.Contains(t, stderr.String(), tc.expected)
+		})
+	}
+}
+
+func TestRun_TokenMint(t *testing.T) {
+	setRequiredEnv(t)
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	code := run([]string{"--jwt-secret", "flag-secret", "token", "mint", "--sub", "billing", "--scopes", "a, b"},
+		stdout, stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+
+	cfg := config.Security{JWTSecret: "flag-secret", Iss: "syncwatch.io", Aud: "syncwatch.io"}
+	principal, err := security.NewTokenFactory(cfg).VerifyToken(strings.TrimSpace(stdout.String()))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "billing", principal.Sub)
+	assert.Equal(t, "a b", principal.Scopes)
+}
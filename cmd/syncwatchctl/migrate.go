@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/kiennyo/syncwatch-be/internal/db"
+	"github.com/kiennyo/syncwatch-be/migrations"
+)
+
+var migrateCommands = map[string]command{
+	"up": {
+		usage: "",
+		run:   migrateUp,
+	},
+	"down": {
+		usage: "[--steps 1]",
+		run:   migrateDown,
+	},
+	"goto": {
+		usage: "<version>",
+		run:   migrateGoto,
+	},
+	"force": {
+		usage: "<version>",
+		run:   migrateForce,
+	},
+	"status": {
+		usage: "",
+		run:   migrateStatus,
+	},
+}
+
+func migrator(ctx context.Context, a *app) (*db.Migrator, error) {
+	pool, err := a.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.NewMigrator(pool, migrations.FS)
+}
+
+func migrateUp(ctx context.Context, a *app, args []string) error {
+	if err := parse(flag.NewFlagSet("migrate up", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+
+	return migrateAndReport(ctx, a, (*db.Migrator).Up)
+}
+
+func migrateDown(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+	steps := fs.Int("steps", 1, "number of migrations to revert")
+
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	if *steps < 1 {
+		return fmt.Errorf("%w: --steps must be at least 1", errUsage)
+	}
+
+	return migrateAndReport(ctx, a, func(m *db.Migrator, ctx context.Context) error {
+		return m.Down(ctx, *steps)
+	})
+}
+
+func migrateGoto(ctx context.Context, a *app, args []string) error {
+	version, err := versionArg("migrate goto", args)
+	if err != nil {
+		return err
+	}
+
+	return migrateAndReport(ctx, a, func(m *db.Migrator, ctx context.Context) error {
+		return m.Goto(ctx, version)
+	})
+}
+
+func migrateForce(ctx context.Context, a *app, args []string) error {
+	version, err := versionArg("migrate force", args)
+	if err != nil {
+		return err
+	}
+
+	return migrateAndReport(ctx, a, func(m *db.Migrator, ctx context.Context) error {
+		return m.Force(ctx, version)
+	})
+}
+
+func migrateStatus(ctx context.Context, a *app, args []string) error {
+	if err := parse(flag.NewFlagSet("migrate status", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+
+	m, err := migrator(ctx, a)
+	if err != nil {
+		return err
+	}
+
+	return printStatus(ctx, a, m, true)
+}
+
+func migrateAndReport(ctx context.Context, a *app, fn func(m *db.Migrator, ctx context.Context) error) error {
+	m, err := migrator(ctx, a)
+	if err != nil {
+		return err
+	}
+
+	if err = fn(m, ctx); err != nil {
+		return err
+	}
+
+	return printStatus(ctx, a, m, false)
+}
+
+func printStatus(ctx context.Context, a *app, m *db.Migrator, verbose bool) error {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(a.out, "version %d of %d, dirty %t\n", status.Version, status.Latest, status.Dirty)
+	if !verbose {
+		return nil
+	}
+
+	tw := a.table()
+	for _, name := range status.Applied {
+		_, _ = fmt.Fprintf(tw, "applied\t%s\n", name)
+	}
+	for _, name := range status.Pending {
+		_, _ = fmt.Fprintf(tw, "pending\t%s\n", name)
+	}
+
+	return tw.Flush()
+}
+
+func versionArg(name string, args []string) (int64, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if err := parse(fs, args, 1); err != nil {
+		return 0, err
+	}
+
+	version, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid version %q", errUsage, fs.Arg(0))
+	}
+
+	return version, nil
+}
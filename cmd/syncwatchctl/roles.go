@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/kiennyo/syncwatch-be/internal/db"
+	"github.com/kiennyo/syncwatch-be/internal/domain/roles"
+)
+
+var roleCommands = map[string]command{
+	"list": {
+		usage: "",
+		run:   roleList,
+	},
+	"grant": {
+		usage: "<role> <permission>",
+		run:   roleGrant,
+	},
+	"revoke": {
+		usage: "<role> <permission>",
+		run:   roleRevoke,
+	},
+}
+
+func roleService(ctx context.Context, a *app) (roles.Service, error) {
+	pool, err := a.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return roles.NewService(roles.NewRepository(db.NewTxManager(pool))), nil
+}
+
+func roleList(ctx context.Context, a *app, args []string) error {
+	if err := parse(flag.NewFlagSet("role list", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+
+	service, err := roleService(ctx, a)
+	if err != nil {
+		return err
+	}
+
+	list, err := service.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := a.table()
+	_, _ = fmt.Fprintln(tw, "SLUG\tTITLE\tPERMISSIONS")
+	for _, r := range list {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Slug, r.Title, strings.Join(r.Permissions, ","))
+	}
+
+	return tw.Flush()
+}
+
+func roleGrant(ctx context.Context, a *app, args []string) error {
+	return modifyRole(ctx, a, "role grant", args, roles.Service.Grant, "granted %s to %s\n")
+}
+
+func roleRevoke(ctx context.Context, a *app, args []string) error {
+	return modifyRole(ctx, a, "role revoke", args, roles.Service.Revoke, "revoked %s from %s\n")
+}
+
+func modifyRole(ctx context.Context, a *app, name string, args []string,
+	fn func(s roles.Service, ctx context.Context, role, permission string) error, format string) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if err := parse(fs, args, 2); err != nil {
+		return err
+	}
+
+	service, err := roleService(ctx, a)
+	if err != nil {
+		return err
+	}
+
+	role, permission := fs.Arg(0), fs.Arg(1)
+	if err = fn(service, ctx, role, permission); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(a.out, format, permission, role)
+
+	return err
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/kiennyo/syncwatch-be/internal/db"
+	"github.com/kiennyo/syncwatch-be/internal/domain/users"
+	"github.com/kiennyo/syncwatch-be/internal/mail"
+	"github.com/kiennyo/syncwatch-be/internal/validator"
+)
+
+var userCommands = map[string]command{
+	"create": {
+		usage: "--name <name> --email <email> --password <password> [--language en] [--role user-active]",
+		run:   userCreate,
+	},
+	"list": {
+		usage: "",
+		run:   userList,
+	},
+	"activate": {
+		usage: "<user id>",
+		run:   userActivate,
+	},
+	"disable": {
+		usage: "<user id>",
+		run:   userDisable,
+	},
+	"set-role": {
+		usage: "<user id> <role>",
+		run:   userSetRole,
+	},
+}
+
+// userService is built without a token creator and mailer, none of the admin
+// operations send emails.
+func userService(ctx context.Context, a *app) (users.Service, error) {
+	pool, err := a.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := db.NewTxManager(pool)
+
+	return users.NewService(users.NewRepository(tx), tx, nil, nil), nil
+}
+
+func userCreate(ctx context.Context, a *app, args []string) error {
+	u := &users.User{}
+	var password string
+
+	fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+	fs.StringVar(&u.Name, "name", "", "user name")
+	fs.StringVar(&u.Email, "email", "", "user email")
+	fs.StringVar(&password, "password", "", "user password")
+	fs.StringVar(&u.Language, "language", mail.DefaultLocale, "preferred language of emails")
+	fs.StringVar(&u.Role, "role", "", "role slug, user-active by default")
+
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	v := validator.New()
+	if err := u.Validate(v, password); err != nil {
+		return err
+	}
+
+	if !v.Valid() {
+		problems := make([]string, 0, len(v.Errors()))
+		for field, msg := range v.Errors() {
+			problems = append(problems, fmt.Sprintf("%s %s", field, msg))
+		}
+
+		return fmt.Errorf("%w: %s", errUsage, strings.Join(problems, ", "))
+	}
+
+	if err := u.SetPassword(password); err != nil {
+		return err
+	}
+
+	service, err := userService(ctx, a)
+	if err != nil {
+		return err
+	}
+
+	if err = service.Create(ctx, u); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(a.out, "created user %s (%s) with role %s\n", u.ID, u.Email, u.Role)
+
+	return err
+}
+
+func userList(ctx context.Context, a *app, args []string) error {
+	if err := parse(flag.NewFlagSet("user list", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+
+	service, err := userService(ctx, a)
+	if err != nil {
+		return err
+	}
+
+	list, err := service.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := a.table()
+	_, _ = fmt.Fprintln(tw, "ID\tEMAIL\tNAME\tROLE\tACTIVATED\tCREATED")
+	for _, u := range list {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\t%s\n",
+			u.ID, u.Email, u.Name, u.Role, u.Activated, u.CreatedAt.Format("2006-01-02 15:04"))
+	}
+
+	return tw.Flush()
+}
+
+func userActivate(ctx context.Context, a *app, args []string) error {
+	return withUser(ctx, a, "user activate", args, func(service users.Service, id string) error {
+		return service.Activate(ctx, id, nil)
+	})
+}
+
+func userDisable(ctx context.Context, a *app, args []string) error {
+	return withUser(ctx, a, "user disable", args, func(service users.Service, id string) error {
+		return service.Disable(ctx, id, nil)
+	})
+}
+
+func userSetRole(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("user set-role", flag.ContinueOnError)
+	if err := parse(fs, args, 2); err != nil {
+		return err
+	}
+
+	service, err := userService(ctx, a)
+	if err != nil {
+		return err
+	}
+
+	if err = service.SetRole(ctx, fs.Arg(0), fs.Arg(1)); err != nil {
+		return err
+	}
+
+	return printUser(ctx, a, service, fs.Arg(0))
+}
+
+func withUser(ctx context.Context, a *app, name string, args []string,
+	fn func(service users.Service, id string) error) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	service, err := userService(ctx, a)
+	if err != nil {
+		return err
+	}
+
+	if err = fn(service, fs.Arg(0)); err != nil {
+		return err
+	}
+
+	return printUser(ctx, a, service, fs.Arg(0))
+}
+
+func printUser(ctx context.Context, a *app, service users.Service, id string) error {
+	u, err := service.Get(ctx, id)
+	if err != nil {
+		return errors.Join(errors.New("changed, but failed to reload the user"), err)
+	}
+
+	_, err = fmt.Fprintf(a.out, "user %s (%s): role %s, activated %t\n", u.ID, u.Email, u.Role, u.Activated)
+
+	return err
+}
@@ -1,118 +1,211 @@
+// Package config loads the application configuration.
+//
+// Every setting is declared on the structs below with tags:
+//
+//	env:"KEY"        environment variable, also the --key flag and the section.key file entry
+//	default:"value"  used when no source sets the key
+//	validate:"..."   comma separated rules: required, min=N, max=N, oneof=a|b|c
+//	secret:"true"    redacted by --print-config
+//	usage:"..."      flag help text
+//
+// Rules spanning several keys go to a Validate() error method on the section.
+// Sources are applied in increasing order of precedence:
+//
+//  1. defaults
+//  2. config file (YAML or TOML, given by --config or CONFIG_FILE)
+//  3. environment variables, .env included; KEY_FILE reads the value of KEY from a file
+//  4. command line flags
+//
+// File entries are grouped by section, the section prefix is dropped from the key,
+// e.g. DB_MAX_OPEN_CONN is read from max_open_conn in the db section.
 package config
 
 import (
-	"flag"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
-	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	HTTP     HTTP
-	DB       DB
-	Security Security
-	SMTP     SMPT
+	App         App
+	HTTP        HTTP
+	DB          DB
+	Security    Security
+	SMTP        SMTP
+	Health      Health
+	Tracing     Tracing
+	Log         Log
+	RateLimit   RateLimit
+	CORS        CORS
+	Idempotency Idempotency
 }
 
-type HTTP struct {
-	Port int
+// App describes the deployment. Outside production responses are checked against the
+// OpenAPI document, at the cost of buffering them.
+type App struct {
+	Env string `env:"APP_ENV" default:"production" validate:"oneof=development|staging|production" usage:"Environment"`
 }
 
-type DB struct {
-	URL         string
-	MaxOpenConn int
-	MaxIdleConn int
-	MaxIdleTime string
+func (a App) Production() bool {
+	return a.Env == "production"
 }
 
-type Security struct {
-	JWTSecret string
-	Iss       string
-	Aud       string
+type HTTP struct {
+	Host       string `env:"HTTP_HOST" usage:"API server interface, all interfaces when empty"`
+	Port       int    `env:"PORT" default:"4000" validate:"min=1,max=65535" usage:"API server port"`
+	UnixSocket string `env:"HTTP_UNIX_SOCKET" usage:"Unix socket path to listen on instead of host and port"`
+
+	ReadTimeout     time.Duration `env:"HTTP_READ_TIMEOUT" default:"10s" validate:"min=1ms" usage:"Read timeout"`
+	HeaderTimeout   time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" default:"5s" validate:"min=1ms" usage:"Header timeout"`
+	WriteTimeout    time.Duration `env:"HTTP_WRITE_TIMEOUT" default:"30s" validate:"min=1ms" usage:"Write timeout"`
+	IdleTimeout     time.Duration `env:"HTTP_IDLE_TIMEOUT" default:"1m" validate:"min=1ms" usage:"Keep-alive timeout"`
+	ShutdownTimeout time.Duration `env:"HTTP_SHUTDOWN_TIMEOUT" default:"5s" validate:"min=1ms" usage:"Shutdown timeout"`
+	DrainDelay      time.Duration `env:"HTTP_DRAIN_DELAY" default:"0s" usage:"Wait after failing readiness on shutdown"`
+
+	TLSCertFile       string        `env:"HTTP_TLS_CERT_FILE" usage:"TLS certificate, enables HTTPS"`
+	TLSKeyFile        string        `env:"HTTP_TLS_KEY_FILE" usage:"TLS private key"`
+	TLSReloadInterval time.Duration `env:"HTTP_TLS_RELOAD_INTERVAL" default:"1m" validate:"min=1s" usage:"TLS files poll"`
+	RedirectPort      int           `env:"HTTP_REDIRECT_PORT" validate:"max=65535" usage:"HTTP to HTTPS redirect port"`
+
+	H2C bool `env:"HTTP_H2C" usage:"Serve HTTP/2 over cleartext for internal traffic"`
+
+	AdminPort int `env:"HTTP_ADMIN_PORT" default:"9090" validate:"max=65535" usage:"Metrics port, disabled when 0"`
+
+	HSTSMaxAge time.Duration `env:"HTTP_HSTS_MAX_AGE" default:"8760h" usage:"HSTS max-age, 0 disables"`
+	CSP        string        `env:"HTTP_CSP" default:"default-src 'none'; frame-ancestors 'none'" usage:"CSP header"`
 }
 
-type SMPT struct {
-	Host     string
-	Port     int
-	Username string
-	Password string
-	Sender   string
+func (h HTTP) TLS() bool {
+	return h.TLSCertFile != ""
 }
 
-func Load() Config {
-	err := godotenv.Load()
-	if err != nil {
-		slog.Warn("Error loading .env file, defaulting to environment variables")
+func (h HTTP) Validate() error {
+	var errs []error
+
+	if (h.TLSCertFile == "") != (h.TLSKeyFile == "") {
+		errs = append(errs, errors.New("HTTP_TLS_CERT_FILE and HTTP_TLS_KEY_FILE must be set together"))
 	}
 
-	config := Config{
-		HTTP:     loadHTTPConfig(),
-		DB:       loadDBConfig(),
-		Security: loadSecurity(),
-		SMTP:     loadSMTPConfig(),
+	if h.RedirectPort != 0 && !h.TLS() {
+		errs = append(errs, errors.New("HTTP_REDIRECT_PORT: requires TLS to be configured"))
 	}
 
-	flag.Parse()
+	if h.H2C && h.TLS() {
+		errs = append(errs, errors.New("HTTP_H2C: can't be used with TLS, HTTP/2 is negotiated over TLS already"))
+	}
+
+	if h.AdminPort != 0 && (h.AdminPort == h.Port || h.AdminPort == h.RedirectPort) {
+		errs = append(errs, errors.New("HTTP_ADMIN_PORT: must differ from PORT and HTTP_REDIRECT_PORT"))
+	}
 
-	return config
+	return errors.Join(errs...)
 }
 
-func loadHTTPConfig() HTTP {
-	http := HTTP{}
-	setEnvInt(&http.Port, "PORT", "API server port")
+type DB struct {
+	URL         string        `env:"DB_URL" validate:"required" secret:"true" usage:"PostgreSQL DSN"`
+	MaxOpenConn int           `env:"DB_MAX_OPEN_CONN" default:"25" validate:"min=1" usage:"Max open connections"`
+	MaxIdleConn int           `env:"DB_MAX_OPEN_IDLE" default:"25" validate:"min=0" usage:"Max idle connections"`
+	MaxIdleTime time.Duration `env:"DB_MAX_IDLE_TIME" default:"15m" validate:"min=1s" usage:"Max connection idle time"`
 
-	return http
+	MigrateOnStartup bool `env:"DB_MIGRATE_ON_STARTUP" usage:"Apply pending migrations before serving"`
 }
 
-func loadDBConfig() DB {
-	db := DB{}
-	setEnv(&db.URL, "DB_URL", "PostgreSQL DSN")
-	setEnvInt(&db.MaxOpenConn, "DB_MAX_OPEN_CONN", "PostgreSQL max open connections")
-	setEnvInt(&db.MaxIdleConn, "DB_MAX_OPEN_IDLE", "PostgreSQL max idle connections")
-	setEnv(&db.MaxIdleTime, "DB_MAX_IDLE_TIME", "PostgreSQL max connection idle time (mins)")
+type Security struct {
+	JWTSecret string `env:"JWT_SECRET" validate:"required" secret:"true" usage:"Secret key to create and verify JWT"`
+	Iss       string `env:"JWT_ISS" default:"syncwatch.io" validate:"required" usage:"JWT issuer"`
+	Aud       string `env:"JWT_AUD" default:"syncwatch.io" validate:"required" usage:"JWT audience"`
+
+	AuthCookie string `env:"AUTH_COOKIE" usage:"Cookie carrying the JWT of browser clients, disabled when empty"`
+	CSRFCookie string `env:"CSRF_COOKIE" default:"csrf_token" validate:"required" usage:"Double submit CSRF cookie"`
+	// plain HTTP in development drops Secure cookies, requests over TLS get them anyway
+	CookieSecure bool `env:"COOKIE_SECURE" default:"true" usage:"Mark the cookies the API sets Secure"`
+}
+
+type SMTP struct {
+	Host     string `env:"SMTP_HOST" validate:"required" usage:"SMTP host"`
+	Port     int    `env:"SMTP_PORT" default:"587" validate:"min=1,max=65535" usage:"SMTP port"`
+	Username string `env:"SMTP_USERNAME" usage:"SMTP username"`
+	Password string `env:"SMTP_PASSWORD" secret:"true" usage:"SMTP password"`
+	Sender   string `env:"SMTP_SENDER" validate:"required" usage:"SMTP sender"`
+
+	WebhookSecret string `env:"SMTP_WEBHOOK_SECRET" secret:"true" usage:"Shared secret of the mail events webhook"`
+}
+
+type Health struct {
+	CheckTimeout time.Duration `env:"HEALTH_CHECK_TIMEOUT" default:"2s" validate:"min=1ms" usage:"Readiness check timeout"`
+	MaxBacklog   int           `env:"HEALTH_MAX_BACKLOG" default:"100" validate:"min=1" usage:"Max pending tasks"`
+}
 
-	return db
+type Tracing struct {
+	Exporter      string `env:"TRACING_EXPORTER" default:"none" validate:"oneof=none|otlp|stdout" usage:"Span exporter"`
+	Endpoint      string `env:"TRACING_OTLP_ENDPOINT" usage:"OTLP/HTTP collector URL"`
+	SamplePercent int    `env:"TRACING_SAMPLE_PERCENT" default:"100" validate:"min=0,max=100" usage:"Sampled traces"`
+	ServiceName   string `env:"TRACING_SERVICE_NAME" default:"syncwatch-api" usage:"Reported service name"`
 }
 
-func loadSecurity() Security {
-	security := Security{}
-	setEnv(&security.JWTSecret, "JWT_SECRET", "Secret key to create and verify JWT")
-	setEnv(&security.Iss, "JWT_ISS", "JWT issuer")
-	setEnv(&security.Aud, "JWT_AUD", "JWT audience")
+type Log struct {
+	Level  string `env:"LOG_LEVEL" default:"info" validate:"oneof=debug|info|warn|error" usage:"Minimum log level"`
+	Format string `env:"LOG_FORMAT" default:"json" validate:"oneof=json|text" usage:"Log line format"`
+}
 
-	return security
+type RateLimit struct {
+	Enabled        bool     `env:"RATE_LIMIT_ENABLED" default:"true" usage:"Throttle sensitive routes"`
+	Store          string   `env:"RATE_LIMIT_STORE" default:"memory" validate:"oneof=memory|postgres" usage:"Bucket store"`
+	TrustedProxies []string `env:"RATE_LIMIT_TRUSTED_PROXIES" usage:"CIDRs allowed to set X-Forwarded-For"`
+	Policies       []string `env:"RATE_LIMIT_POLICIES" usage:"Limit overrides, e.g. signup=5/1h"`
 }
 
-func loadSMTPConfig() SMPT {
-	smpt := SMPT{}
-	setEnv(&smpt.Host, "SMPT_HOST", "SMTP host")
-	setEnvInt(&smpt.Port, "SMPT_PORT", "SMTP port")
-	setEnv(&smpt.Username, "SMPT_USERNAME", "SMTP username")
-	setEnv(&smpt.Password, "SMPT_PASSWORD", "SMTP password")
-	setEnv(&smpt.Sender, "SMPT_SENDER", "SMTP sender")
+type CORS struct {
+	AllowedOrigins   []string      `env:"CORS_ALLOWED_ORIGINS" usage:"Browser origins, e.g. https://*.syncwatch.io"`
+	ExtraHeaders     []string      `env:"CORS_EXTRA_HEADERS" usage:"Request headers allowed besides the API ones"`
+	AllowCredentials bool          `env:"CORS_ALLOW_CREDENTIALS" usage:"Allow cookies on cross origin requests"`
+	MaxAge           time.Duration `env:"CORS_MAX_AGE" default:"10m" usage:"Preflight cache duration"`
+}
 
-	return smpt
+type Idempotency struct {
+	TTL         time.Duration `env:"IDEMPOTENCY_TTL" default:"24h" validate:"min=1m" usage:"Replay window"`
+	LockTimeout time.Duration `env:"IDEMPOTENCY_LOCK_TIMEOUT" default:"1m" validate:"min=1s" usage:"Stale claim takeover"`
+	Wait        time.Duration `env:"IDEMPOTENCY_WAIT" default:"5s" usage:"Duplicate wait before 409"`
 }
 
-func setEnvInt(configValue *int, key string, usage string) {
-	if envValue, exists := os.LookupEnv(key); exists {
-		if value, err := strconv.Atoi(envValue); err == nil {
-			flag.IntVar(configValue, key, value, usage)
-			return
+func (c CORS) Validate() error {
+	var errs []error
+
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			if c.AllowCredentials {
+				errs = append(errs, errors.New("CORS_ALLOWED_ORIGINS: * can't be used with CORS_ALLOW_CREDENTIALS"))
+			}
+			continue
+		}
+
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			u.Path != "" || u.RawQuery != "" || strings.Contains(strings.TrimPrefix(u.Host, "*."), "*") {
+			errs = append(errs, fmt.Errorf("CORS_ALLOWED_ORIGINS: invalid origin %q, expected scheme://[*.]host[:port]", origin))
 		}
 	}
 
-	panic(fmt.Sprintf("env var: %s, can't set or cannot be converted to number", key))
+	return errors.Join(errs...)
 }
 
-func setEnv(configValue *string, key string, usage string) {
-	if envValue, exists := os.LookupEnv(key); exists {
-		flag.StringVar(configValue, key, envValue, usage)
-		return
+// Load reads the configuration from all sources, reporting every invalid key at once.
+// When --print-config is passed the effective configuration is written to stdout and
+// ErrPrinted is returned, unless the configuration is invalid.
+func Load(args []string) (Config, error) {
+	err := godotenv.Load()
+	if err != nil {
+		slog.Warn("Error loading .env file, defaulting to environment variables")
 	}
 
-	panic(fmt.Sprintf("env var: %s, not set", key))
+	cfg := Config{}
+	err = newLoader(os.Stdout, os.LookupEnv).load(&cfg, args)
+
+	return cfg, err
 }
@@ -0,0 +1,493 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrPrinted is returned by Load after the configuration was printed with --print-config.
+var ErrPrinted = errors.New("configuration printed")
+
+const redacted = "[REDACTED]"
+
+// legacyPrefixes maps env prefixes to the misspelled ones still accepted for compatibility.
+var legacyPrefixes = map[string]string{
+	"SMTP_": "SMPT_",
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+type field struct {
+	key     string
+	section string
+	value   reflect.Value
+	tag     reflect.StructTag
+}
+
+func (f *field) fileKey() string {
+	return strings.TrimPrefix(strings.ToLower(f.key), f.section+"_")
+}
+
+func (f *field) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(f.key), "_", "-")
+}
+
+type loader struct {
+	out    io.Writer
+	lookup func(key string) (string, bool)
+}
+
+func newLoader(out io.Writer, lookup func(key string) (string, bool)) *loader {
+	return &loader{
+		out:    out,
+		lookup: lookup,
+	}
+}
+
+//nolint:revive,cognitive-complexity,cyclomatic
+func (l *loader) load(cfg any, args []string) error {
+	fields := collect(reflect.ValueOf(cfg).Elem())
+
+	fs := flag.NewFlagSet("syncwatch", flag.ContinueOnError)
+	fs.SetOutput(l.out)
+	configFile := fs.String("config", "", "Path to a YAML or TOML config file")
+	printConfig := fs.Bool("print-config", false, "Print the effective configuration with secrets redacted")
+
+	flagValues := make(map[string]string)
+	for _, f := range fields {
+		fs.Func(f.flagName(), f.tag.Get("usage"), func(value string) error {
+			flagValues[f.key] = value
+			return nil
+		})
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var errs []error
+
+	values := make(map[string]string)
+	for _, f := range fields {
+		if def, ok := f.tag.Lookup("default"); ok {
+			values[f.key] = def
+		}
+	}
+
+	envFile, _ := l.lookupSet("CONFIG_FILE")
+	if path := cmp.Or(*configFile, envFile); path != "" {
+		fileValues, err := readFile(path)
+		if err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+
+		for _, f := range fields {
+			name := f.section + "." + f.fileKey()
+			if value, ok := fileValues[name]; ok {
+				assign(values, f, value)
+				delete(fileValues, name)
+			}
+		}
+
+		for name := range fileValues {
+			errs = append(errs, fmt.Errorf("config file %s: unknown key %s", path, name))
+		}
+	}
+
+	for _, f := range fields {
+		value, ok, err := l.env(f.key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if ok {
+			assign(values, f, value)
+		}
+	}
+
+	for _, f := range fields {
+		if value, ok := flagValues[f.key]; ok {
+			assign(values, f, value)
+		}
+	}
+
+	for _, f := range fields {
+		if value, ok := values[f.key]; ok {
+			if err := set(f.value, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid value %q: %w", f.key, value, err))
+				continue
+			}
+		}
+
+		errs = append(errs, validate(f)...)
+	}
+
+	errs = append(errs, validateSections(reflect.ValueOf(cfg).Elem())...)
+
+	if *printConfig {
+		l.print(fields)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	if *printConfig {
+		return ErrPrinted
+	}
+
+	return nil
+}
+
+// lookupSet treats empty env vars as unset, KEY= in .env must not override the file.
+func (l *loader) lookupSet(key string) (string, bool) {
+	value, ok := l.lookup(key)
+	return value, ok && value != ""
+}
+
+// env resolves KEY, KEY_FILE and the legacy spelling of KEY, in that order.
+func (l *loader) env(key string) (string, bool, error) {
+	value, ok := l.lookupSet(key)
+
+	if path, fileOk := l.lookupSet(key + "_FILE"); fileOk {
+		if ok {
+			return "", false, fmt.Errorf("%s: both %s and %s_FILE are set", key, key, key)
+		}
+
+		content, err := os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			return "", false, fmt.Errorf("%s_FILE: %w", key, err)
+		}
+
+		return strings.TrimRight(string(content), "\r\n"), true, nil
+	}
+
+	if ok {
+		return value, true, nil
+	}
+
+	for prefix, legacy := range legacyPrefixes {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		legacyKey := legacy + strings.TrimPrefix(key, prefix)
+		if value, ok = l.lookupSet(legacyKey); ok {
+			slog.Warn("Deprecated env var, rename it", "env", legacyKey, "replacement", key)
+			return value, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+func (l *loader) print(fields []*field) {
+	section := ""
+	for _, f := range fields {
+		if f.section != section {
+			if section != "" {
+				_, _ = fmt.Fprintln(l.out)
+			}
+			section = f.section
+			_, _ = fmt.Fprintf(l.out, "# %s\n", section)
+		}
+
+		value := format(f.value)
+		if f.tag.Get("secret") == "true" && value != "" {
+			value = redacted
+		}
+
+		_, _ = fmt.Fprintf(l.out, "%s=%s\n", f.key, value)
+	}
+}
+
+// collect returns the tagged fields of every section struct of cfg.
+func collect(cfg reflect.Value) []*field {
+	var fields []*field
+
+	for i := range cfg.NumField() {
+		section := cfg.Field(i)
+		name := sectionName(cfg.Type().Field(i).Name)
+
+		for j := range section.NumField() {
+			sf := section.Type().Field(j)
+
+			key, ok := sf.Tag.Lookup("env")
+			if !ok {
+				continue
+			}
+
+			fields = append(fields, &field{
+				key:     key,
+				section: name,
+				value:   section.Field(j),
+				tag:     sf.Tag,
+			})
+		}
+	}
+
+	return fields
+}
+
+// sectionName turns the field name into snake case, e.g. RateLimit into rate_limit,
+// acronyms like HTTP are kept in one piece.
+func sectionName(field string) string {
+	var b strings.Builder
+
+	for i, r := range field {
+		if i > 0 && unicode.IsUpper(r) && unicode.IsLower(rune(field[i-1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
+
+// assign overrides the value of f from a lower layer. An empty value, e.g. KEY= in
+// .env, leaves non string keys unset, so the default or the file value still applies.
+func assign(values map[string]string, f *field, value string) {
+	if value == "" && f.value.Kind() != reflect.String {
+		return
+	}
+
+	values[f.key] = value
+}
+
+func set(v reflect.Value, raw string) error {
+	// an empty value leaves non string keys unset
+	if raw == "" && v.Kind() != reflect.String {
+		return nil
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+
+		return nil
+	}
+
+	//nolint:exhaustive
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return errors.New("not a number")
+		}
+		v.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.New("not a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+func format(v reflect.Value) string {
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+	case v.Kind() == reflect.Slice:
+		return strings.Join(v.Interface().([]string), ",")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+//nolint:revive,cognitive-complexity
+func validate(f *field) []error {
+	rules := f.tag.Get("validate")
+	if rules == "" {
+		return nil
+	}
+
+	var errs []error
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+
+		var msg string
+
+		switch name {
+		case "required":
+			if f.value.IsZero() {
+				msg = "must be provided"
+			}
+		case "min", "max":
+			ok, err := compare(f.value, name, arg)
+			if err != nil {
+				panic(fmt.Sprintf("config: invalid %s rule on %s: %s", name, f.key, err))
+			}
+			switch {
+			case !ok && name == "min":
+				msg = "must be at least " + arg
+			case !ok:
+				msg = "must be at most " + arg
+			}
+		case "oneof":
+			options := strings.Split(arg, "|")
+			if !f.value.IsZero() && !slices.Contains(options, format(f.value)) {
+				msg = "must be one of " + strings.Join(options, ", ")
+			}
+		default:
+			panic(fmt.Sprintf("config: unknown validation rule %q on %s", name, f.key))
+		}
+
+		if msg != "" {
+			errs = append(errs, fmt.Errorf("%s: %s", f.key, msg))
+		}
+	}
+
+	return errs
+}
+
+type sectionValidator interface {
+	Validate() error
+}
+
+func validateSections(cfg reflect.Value) []error {
+	var errs []error
+
+	for i := range cfg.NumField() {
+		if v, ok := cfg.Field(i).Interface().(sectionValidator); ok {
+			if err := v.Validate(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errs
+}
+
+// compare checks numbers and durations by value and strings by length.
+func compare(v reflect.Value, rule, arg string) (bool, error) {
+	var actual, limit int64
+
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return false, err
+		}
+		actual, limit = v.Int(), int64(d)
+	case v.Kind() == reflect.String:
+		n, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return false, err
+		}
+		actual, limit = int64(len(v.String())), n
+	default:
+		n, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return false, err
+		}
+		actual, limit = v.Int(), n
+	}
+
+	if rule == "min" {
+		return actual >= limit, nil
+	}
+
+	return actual <= limit, nil
+}
+
+// scalar tells whether a value of a file can be set as is, dates included.
+func scalar(value any) bool {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Invalid, reflect.Map, reflect.Slice, reflect.Array:
+		return false
+	default:
+		return true
+	}
+}
+
+// readFile flattens the sections of a YAML or TOML file to section.key entries.
+func readFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]any)
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &raw)
+	case ".toml":
+		err = toml.Unmarshal(content, &raw)
+	default:
+		return nil, errors.New("unsupported format, use .yaml, .yml or .toml")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+
+	for section, entries := range raw {
+		keys, ok := entries.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a section", section)
+		}
+
+		for key, value := range keys {
+			name := section + "." + key
+
+			switch value := value.(type) {
+			case nil:
+				continue // an empty value, e.g. host:, leaves the key to the default or env
+			case []any:
+				parts := make([]string, len(value))
+				for i, item := range value {
+					if !scalar(item) {
+						return nil, fmt.Errorf("%s must be a list of values", name)
+					}
+					parts[i] = fmt.Sprint(item)
+				}
+				values[name] = strings.Join(parts, ",")
+			default:
+				if !scalar(value) {
+					return nil, fmt.Errorf("%s must be a value, not a %T", name, value)
+				}
+				values[name] = fmt.Sprint(value)
+			}
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lookup(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+var requiredEnv = map[string]string{
+	"DB_URL":      "postgres://localhost/syncwatch",
+	"JWT_SECRET":  "secret",
+	"SMTP_HOST":   "localhost",
+	"SMTP_SENDER": "no-reply@syncwatch.io",
+}
+
+func TestLoader_Defaults(t *testing.T) {
+	cfg := Config{}
+	err := newLoader(new(bytes.Buffer), lookup(requiredEnv)).load(&cfg, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4000, cfg.HTTP.Port)
+	assert.Equal(t, 15*time.Minute, cfg.DB.MaxIdleTime)
+	assert.Equal(t, "syncwatch.io", cfg.Security.Iss)
+	assert.Equal(t, "postgres://localhost/syncwatch", cfg.DB.URL)
+}
+
+func TestLoader_Precedence(t *testing.T) {
+	yamlFile := writeFile(t, "config.yaml",
+		"http:\n  port: 5000\n  host: 127.0.0.1\ndb:\n  max_open_conn: 10\n  max_idle_time: 1m\n")
+	tomlFile := writeFile(t, "config.toml",
+		"[http]\nport = 5000\nhost = \"127.0.0.1\"\n[db]\nmax_open_conn = 10\nmax_idle_time = \"1m\"\n")
+
+	for _, file := range []string{yamlFile, tomlFile} {
+		t.Run(filepath.Ext(file), func(t *testing.T) {
+			env := map[string]string{"DB_MAX_OPEN_CONN": "20", "PORT": "6000", "DB_MAX_IDLE_TIME": "", "HTTP_HOST": ""}
+			for key, value := range requiredEnv {
+				env[key] = value
+			}
+
+			cfg := Config{}
+			args := []string{"--config", file, "--port", "7000"}
+			err := newLoader(new(bytes.Buffer), lookup(env)).load(&cfg, args)
+
+			assert.NoError(t, err)
+			assert.Equal(t, time.Minute, cfg.DB.MaxIdleTime) // file over default and empty env
+			assert.Equal(t, "127.0.0.1", cfg.HTTP.Host)      // empty env doesn't override
+			assert.Equal(t, 20, cfg.DB.MaxOpenConn)          // env over file
+			assert.Equal(t, 7000, cfg.HTTP.Port)             // flag over env
+		})
+	}
+}
+
+func TestLoader_EmptyValueKeepsDefault(t *testing.T) {
+	env := map[string]string{"SMTP_PORT": "", "HTTP_ADMIN_PORT": ""}
+	for key, value := range requiredEnv {
+		env[key] = value
+	}
+
+	cfg := Config{}
+	err := newLoader(new(bytes.Buffer), lookup(env)).load(&cfg, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 587, cfg.SMTP.Port)
+	assert.Equal(t, 9090, cfg.HTTP.AdminPort)
+}
+
+func TestLoader_FileValues(t *testing.T) {
+	t.Run("Empty value keeps default", func(t *testing.T) {
+		file := writeFile(t, "config.yaml", "http:\n  host:\n  port: 5000\ndb:\n  max_idle_time:\n")
+
+		cfg := Config{}
+		err := newLoader(new(bytes.Buffer), lookup(requiredEnv)).load(&cfg, []string{"--config", file})
+
+		assert.NoError(t, err)
+		assert.Empty(t, cfg.HTTP.Host)
+		assert.Equal(t, 15*time.Minute, cfg.DB.MaxIdleTime)
+		assert.Equal(t, 5000, cfg.HTTP.Port)
+	})
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "Nested map", content: "http:\n  host:\n    name: localhost\n", wantErr: "http.host must be a value"},
+		{name: "List of maps", content: "cors:\n  allowed_origins:\n    - origin: a\n",
+			wantErr: "cors.allowed_origins must be a list of values"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeFile(t, "config.yaml", tt.content)
+
+			err := newLoader(new(bytes.Buffer), lookup(requiredEnv)).load(&Config{}, []string{"--config", file})
+
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestLoader_SecretFile(t *testing.T) {
+	secret := writeFile(t, "jwt", "from-file\n")
+
+	env := map[string]string{"JWT_SECRET_FILE": secret}
+	for key, value := range requiredEnv {
+		if key != "JWT_SECRET" {
+			env[key] = value
+		}
+	}
+
+	cfg := Config{}
+	err := newLoader(new(bytes.Buffer), lookup(env)).load(&cfg, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "from-file", cfg.Security.JWTSecret)
+
+	env["JWT_SECRET"] = "from-env"
+	err = newLoader(new(bytes.Buffer), lookup(env)).load(&Config{}, nil)
+
+	assert.ErrorContains(t, err, "both JWT_SECRET and JWT_SECRET_FILE are set")
+}
+
+func TestLoader_LegacySMTPKeys(t *testing.T) {
+	env := map[string]string{
+		"DB_URL":      "postgres://localhost/syncwatch",
+		"JWT_SECRET":  "secret",
+		"SMPT_HOST":   "legacy-host",
+		"SMPT_SENDER": "no-reply@syncwatch.io",
+	}
+
+	cfg := Config{}
+	err := newLoader(new(bytes.Buffer), lookup(env)).load(&cfg, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "legacy-host", cfg.SMTP.Host)
+}
+
+func TestLoader_AggregatesErrors(t *testing.T) {
+	env := map[string]string{
+		"PORT":             "70000",
+		"DB_MAX_OPEN_CONN": "many",
+	}
+	file := writeFile(t, "config.yaml", "db:\n  unknown: 1\n")
+
+	err := newLoader(new(bytes.Buffer), lookup(env)).load(&Config{}, []string{"--config", file})
+
+	assert.Error(t, err)
+	for _, msg := range []string{
+		"PORT: must be at most 65535",
+		`DB_MAX_OPEN_CONN: invalid value "many": not a number`,
+		"DB_URL: must be provided",
+		"JWT_SECRET: must be provided",
+		"SMTP_HOST: must be provided",
+		"SMTP_SENDER: must be provided",
+		"unknown key db.unknown",
+	} {
+		assert.ErrorContains(t, err, msg)
+	}
+}
+
+func TestLoader_SectionRules(t *testing.T) {
+	env := map[string]string{
+		"HTTP_TLS_KEY_FILE":  "key.pem",
+		"HTTP_REDIRECT_PORT": "80",
+		"HTTP_H2C":           "",
+
+		"CORS_ALLOWED_ORIGINS":   "*,https://player.syncwatch.io,https://syncwatch.io/app,https://a.*.io",
+		"CORS_ALLOW_CREDENTIALS": "true",
+	}
+	for key, value := range requiredEnv {
+		env[key] = value
+	}
+
+	err := newLoader(new(bytes.Buffer), lookup(env)).load(&Config{}, nil)
+
+	assert.ErrorContains(t, err, "HTTP_TLS_CERT_FILE and HTTP_TLS_KEY_FILE must be set together")
+	assert.ErrorContains(t, err, "HTTP_REDIRECT_PORT: requires TLS to be configured")
+	assert.ErrorContains(t, err, "CORS_ALLOWED_ORIGINS: * can't be used with CORS_ALLOW_CREDENTIALS")
+	assert.ErrorContains(t, err, `CORS_ALLOWED_ORIGINS: invalid origin "https://syncwatch.io/app"`)
+	assert.ErrorContains(t, err, `CORS_ALLOWED_ORIGINS: invalid origin "https://a.*.io"`)
+	assert.NotContains(t, err.Error(), "player.syncwatch.io")
+}
+
+func TestLoader_PrintConfig(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := newLoader(out, lookup(requiredEnv)).load(&Config{}, []string{"--print-config"})
+
+	assert.ErrorIs(t, err, ErrPrinted)
+	assert.Contains(t, out.String(), "# db\nDB_URL=[REDACTED]\n")
+	assert.Contains(t, out.String(), "JWT_SECRET=[REDACTED]\n")
+	assert.Contains(t, out.String(), "SMTP_PASSWORD=\n")
+	assert.Contains(t, out.String(), "DB_MAX_IDLE_TIME=15m0s\n")
+	assert.NotContains(t, out.String(), "secret\n")
+}
+
+func TestLoader_SectionName(t *testing.T) {
+	file := writeFile(t, "config.yaml", "rate_limit:\n  store: postgres\n  policies:\n    - signup=5/1h\n    - signup_email=1/1m\n")
+
+	cfg := Config{}
+	err := newLoader(new(bytes.Buffer), lookup(requiredEnv)).load(&cfg, []string{"--config", file})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres", cfg.RateLimit.Store)
+	assert.Equal(t, []string{"signup=5/1h", "signup_email=1/1m"}, cfg.RateLimit.Policies)
+	assert.Equal(t, "http", sectionName("HTTP"))
+	assert.Equal(t, "rate_limit", sectionName("RateLimit"))
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Postgres error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	notNullViolation     = "23502"
+	foreignKeyViolation  = "23503"
+	uniqueViolation      = "23505"
+	checkViolation       = "23514"
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+	queryCanceled        = "57014"
+	undefinedTable       = "42P01"
+)
+
+var (
+	ErrUniqueViolation      = errors.New("unique violation")
+	ErrForeignKeyViolation  = errors.New("foreign key violation")
+	ErrCheckViolation       = errors.New("check violation")
+	ErrNotNullViolation     = errors.New("not null violation")
+	ErrSerializationFailure = errors.New("serialization failure")
+	ErrDeadlock             = errors.New("deadlock detected")
+	ErrQueryCanceled        = errors.New("query canceled")
+)
+
+var kinds = map[string]error{
+	notNullViolation:     ErrNotNullViolation,
+	foreignKeyViolation:  ErrForeignKeyViolation,
+	uniqueViolation:      ErrUniqueViolation,
+	checkViolation:       ErrCheckViolation,
+	serializationFailure: ErrSerializationFailure,
+	deadlockDetected:     ErrDeadlock,
+	queryCanceled:        ErrQueryCanceled,
+}
+
+// Error is a classified Postgres error. It matches errors.Is for its Kind, the domain
+// error registered for the constraint if any, and errors.As for the *pgconn.PgError.
+type Error struct {
+	Kind       error
+	Domain     error
+	Constraint string
+	Table      string
+	Column     string
+	pgErr      *pgconn.PgError
+}
+
+func (e *Error) Error() string {
+	if e.Domain != nil {
+		return e.Domain.Error()
+	}
+
+	return fmt.Sprintf("%s: %s", e.Kind, e.pgErr.Message)
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Domain != nil {
+		return []error{e.Domain, e.Kind, e.pgErr}
+	}
+
+	return []error{e.Kind, e.pgErr}
+}
+
+// Constraints maps constraint names of a domain to its sentinel errors. Not null
+// violations have no constraint, they are looked up by "table.column".
+type Constraints map[string]error
+
+// MapError classifies Postgres errors, anything else is returned unchanged.
+func (c Constraints) MapError(err error) error {
+	var mapped *Error
+	var pgErr *pgconn.PgError
+	if errors.As(err, &mapped) || !errors.As(err, &pgErr) {
+		return err
+	}
+
+	kind, ok := kinds[pgErr.Code]
+	if !ok {
+		return err
+	}
+
+	mapped = &Error{
+		Kind:       kind,
+		Constraint: pgErr.ConstraintName,
+		Table:      pgErr.TableName,
+		Column:     pgErr.ColumnName,
+		pgErr:      pgErr,
+	}
+
+	key := pgErr.ConstraintName
+	if pgErr.Code == notNullViolation {
+		key = pgErr.TableName + "." + pgErr.ColumnName
+	}
+
+	mapped.Domain = c[key]
+
+	return mapped
+}
+
+// MapError classifies Postgres errors without domain specific constraints.
+func MapError(err error) error {
+	return Constraints(nil).MapError(err)
+}
+
+// IsSerializationFailure reports whether err is a serialization failure, which goes
+// away when the transaction is run again.
+func IsSerializationFailure(err error) bool {
+	return errors.Is(MapError(err), ErrSerializationFailure)
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+//nolint:revive,function-length
+func TestConstraints_MapError(t *testing.T) {
+	errDuplicateEmail := errors.New("duplicate email")
+	errRoleNotFound := errors.New("role not found")
+
+	constraints := Constraints{
+		"user_email_key": errDuplicateEmail,
+		"user.role_id":   errRoleNotFound,
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		kind     error
+		domain   error
+		expected string
+	}{
+		{
+			name:     "Registered unique constraint",
+			err:      &pgconn.PgError{Code: uniqueViolation, ConstraintName: "user_email_key", Message: "duplicate key"},
+			kind:     ErrUniqueViolation,
+			domain:   errDuplicateEmail,
+			expected: "duplicate email",
+		},
+		{
+			name:     "Unregistered constraint",
+			err:      &pgconn.PgError{Code: foreignKeyViolation, ConstraintName: "fk_role", Message: "violates fk_role"},
+			kind:     ErrForeignKeyViolation,
+			expected: "foreign key violation: violates fk_role",
+		},
+		{
+			name:     "Not null by column",
+			err:      &pgconn.PgError{Code: notNullViolation, TableName: "user", ColumnName: "role_id"},
+			kind:     ErrNotNullViolation,
+			domain:   errRoleNotFound,
+			expected: "role not found",
+		},
+		{
+			name:     "Wrapped error",
+			err:      fmt.Errorf("query: %w", &pgconn.PgError{Code: serializationFailure, Message: "could not serialize"}),
+			kind:     ErrSerializationFailure,
+			expected: "serialization failure: could not serialize",
+		},
+		{
+			name:     "Query canceled",
+			err:      &pgconn.PgError{Code: queryCanceled, Message: "canceling statement due to statement timeout"},
+			kind:     ErrQueryCanceled,
+			expected: "query canceled: canceling statement due to statement timeout",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := constraints.MapError(tc.err)
+
+			assert.ErrorIs(t, err, tc.kind)
+			assert.EqualError(t, err, tc.expected)
+
+			if tc.domain != nil {
+				assert.ErrorIs(t, err, tc.domain)
+			}
+
+			var pgErr *pgconn.PgError
+			assert.ErrorAs(t, err, &pgErr)
+
+			// mapping twice, e.g. by a service on a repository error, keeps the first result
+			assert.Same(t, err, constraints.MapError(err))
+		})
+	}
+}
+
+func TestMapError_Passthrough(t *testing.T) {
+	plain := errors.New("plain")
+	assert.Same(t, plain, MapError(plain))
+
+	unknown := &pgconn.PgError{Code: undefinedTable}
+	assert.Same(t, unknown, MapError(unknown))
+
+	assert.Nil(t, MapError(nil))
+	assert.True(t, IsSerializationFailure(&pgconn.PgError{Code: serializationFailure}))
+	assert.False(t, IsSerializationFailure(&pgconn.PgError{Code: deadlockDetected}))
+}
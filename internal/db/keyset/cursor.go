@@ -0,0 +1,72 @@
+package keyset
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// Signer makes cursors opaque to clients and tamper proof, so only values the server
+// handed out reach the keyset conditions.
+type Signer struct {
+	key []byte
+}
+
+// NewSigner derives the cursor key from secret, so a secret shared with other uses
+// doesn't produce signatures valid elsewhere.
+func NewSigner(secret string) *Signer {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("page cursor"))
+
+	return &Signer{key: mac.Sum(nil)}
+}
+
+type cursor struct {
+	After []string `json:"a"`
+	Query string   `json:"q"`
+}
+
+func (s *Signer) encode(after []string, query string) string {
+	payload, _ := json.Marshal(cursor{After: after, Query: query})
+
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(s.sign(payload))
+}
+
+func (s *Signer) decode(raw, query string) ([]string, error) {
+	enc := base64.RawURLEncoding
+
+	payloadPart, sigPart, ok := strings.Cut(raw, ".")
+	if !ok {
+		return nil, errInvalidCursor
+	}
+
+	payload, err := enc.DecodeString(payloadPart)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	sig, err := enc.DecodeString(sigPart)
+	if err != nil || !hmac.Equal(sig, s.sign(payload)) {
+		return nil, errInvalidCursor
+	}
+
+	var c cursor
+	if err = json.Unmarshal(payload, &c); err != nil || c.Query != query {
+		return nil, errInvalidCursor
+	}
+
+	return c.After, nil
+}
+
+func (s *Signer) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(payload)
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,270 @@
+// Package keyset validates the limit, cursor, sort and filter parameters of lists
+// against a whitelist of fields and turns them into SQL for keyset pagination.
+//
+// Clients never get to write SQL: sort and filter parameters only select fields of the
+// Spec, whose columns are set by the list, and values are passed as named args.
+package keyset
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kiennyo/syncwatch-be/internal/validator"
+)
+
+// Op is a filter operator, written filter[field][op]=value; eq when omitted.
+type Op string
+
+const (
+	Eq       Op = "eq"
+	Ne       Op = "ne"
+	Lt       Op = "lt"
+	Lte      Op = "lte"
+	Gt       Op = "gt"
+	Gte      Op = "gte"
+	Contains Op = "contains"
+	In       Op = "in"
+)
+
+// Field is a column clients may sort or filter by. Value reads it from an item, it's
+// required for sortable fields as cursors are made of the sort values of the last item.
+type Field[T any] struct {
+	Name     string
+	Column   string
+	Type     Type
+	Sortable bool
+	Filters  []Op
+	Value    func(T) any
+}
+
+// Spec is the whitelist of a list endpoint. Key names a unique sortable field, it's
+// appended to every sort so items with equal sort values keep a stable order. Columns
+// used for keyset pagination must not be nullable.
+type Spec[T any] struct {
+	Fields       []Field[T]
+	Key          string
+	DefaultSort  string
+	DefaultLimit int
+	MaxLimit     int
+}
+
+type Order struct {
+	Field string
+	Desc  bool
+}
+
+type Filter struct {
+	Field string
+	Op    Op
+	Value any
+}
+
+// Query is a parsed, validated list request.
+type Query[T any] struct {
+	Limit   int
+	Sort    []Order
+	Filters []Filter
+	// After holds the sort values of the last item of the previous page.
+	After []any
+
+	spec      *Spec[T]
+	signature string
+}
+
+// Error reports the invalid parameters of a list request, by parameter.
+type Error struct {
+	Violations map[string]validator.Violation
+}
+
+func (e *Error) Error() string {
+	return "keyset: invalid list parameters"
+}
+
+var filterRX = regexp.MustCompile(`^filter\[([a-z0-9_]+)](?:\[([a-z]+)])?$`)
+
+func (s *Spec[T]) field(name string) (Field[T], bool) {
+	i := slices.IndexFunc(s.Fields, func(f Field[T]) bool { return f.Name == name })
+	if i < 0 {
+		return Field[T]{}, false
+	}
+
+	return s.Fields[i], true
+}
+
+// Parse validates the query parameters, violations are reported per parameter as an
+// *Error.
+func (s *Spec[T]) Parse(values url.Values, signer *Signer) (*Query[T], error) {
+	errs := make(map[string]validator.Violation)
+
+	q := &Query[T]{spec: s, Limit: s.DefaultLimit}
+
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > s.MaxLimit {
+			errs["limit"] = validator.Violation{Code: "between", Params: map[string]any{"min": 1, "max": s.MaxLimit}}
+		}
+		q.Limit = limit
+	}
+
+	if violation := q.parseSort(cmp.Or(values.Get("sort"), s.DefaultSort)); violation != nil {
+		errs["sort"] = *violation
+	}
+
+	q.parseFilters(values, errs)
+
+	if len(errs) > 0 {
+		return nil, &Error{Violations: errs}
+	}
+
+	q.signature = q.canonical()
+
+	if raw := values.Get("cursor"); raw != "" {
+		after, err := signer.decode(raw, q.signature)
+		if err == nil {
+			q.After, err = q.parseAfter(after)
+		}
+		if err != nil {
+			return nil, &Error{Violations: map[string]validator.Violation{"cursor": {Code: "invalid_cursor"}}}
+		}
+	}
+
+	return q, nil
+}
+
+func (q *Query[T]) parseSort(raw string) *validator.Violation {
+	for _, entry := range strings.Split(raw, ",") {
+		name, desc := strings.CutPrefix(strings.TrimSpace(entry), "-")
+
+		f, ok := q.spec.field(name)
+		if !ok || !f.Sortable {
+			return &validator.Violation{
+				Code:   "unsupported_sort",
+				Params: map[string]any{"field": name, "values": q.spec.Sortable()},
+			}
+		}
+
+		if slices.ContainsFunc(q.Sort, func(o Order) bool { return o.Field == name }) {
+			return &validator.Violation{Code: "duplicate_sort", Params: map[string]any{"field": name}}
+		}
+
+		q.Sort = append(q.Sort, Order{Field: name, Desc: desc})
+	}
+
+	// the key breaks ties in the direction of the last order
+	if !slices.ContainsFunc(q.Sort, func(o Order) bool { return o.Field == q.spec.Key }) {
+		q.Sort = append(q.Sort, Order{Field: q.spec.Key, Desc: q.Sort[len(q.Sort)-1].Desc})
+	}
+
+	return nil
+}
+
+func (q *Query[T]) parseFilters(values url.Values, errs map[string]validator.Violation) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys) // filters in a stable order, they are part of the cursor signature
+
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "filter[") {
+			continue
+		}
+
+		m := filterRX.FindStringSubmatch(key)
+		if m == nil {
+			errs[key] = validator.Violation{Code: "filter_syntax"}
+			continue
+		}
+
+		f, ok := q.spec.field(m[1])
+		op := Op(cmp.Or(m[2], string(Eq)))
+		if !ok || !slices.Contains(f.Filters, op) {
+			errs[key] = validator.Violation{Code: "unsupported_filter"}
+			continue
+		}
+
+		value, violation := f.Type.parseFilter(op, values.Get(key))
+		if violation != nil {
+			errs[key] = *violation
+			continue
+		}
+
+		q.Filters = append(q.Filters, Filter{Field: f.Name, Op: op, Value: value})
+	}
+}
+
+// parseAfter types the cursor values by their sort fields.
+func (q *Query[T]) parseAfter(raw []string) ([]any, error) {
+	if len(raw) != len(q.Sort) {
+		return nil, errInvalidCursor
+	}
+
+	after := make([]any, len(raw))
+	for i, o := range q.Sort {
+		f, _ := q.spec.field(o.Field)
+
+		v, violation := f.Type.parse(raw[i])
+		if violation != nil {
+			return nil, errInvalidCursor
+		}
+		after[i] = v
+	}
+
+	return after, nil
+}
+
+// canonical identifies the sort and filters, a cursor is only valid for the query it
+// was made for.
+func (q *Query[T]) canonical() string {
+	var b strings.Builder
+
+	for _, o := range q.Sort {
+		if o.Desc {
+			b.WriteByte('-')
+		}
+		b.WriteString(o.Field)
+		b.WriteByte(',')
+	}
+
+	for _, f := range q.Filters {
+		fmt.Fprintf(&b, "|%s:%s:%v", f.Field, f.Op, f.Value)
+	}
+
+	return b.String()
+}
+
+// Cut trims the extra item fetched by LimitArg and makes the cursor of the next page
+// from the sort values of the last item, empty on the last page.
+func (q *Query[T]) Cut(items []T, signer *Signer) ([]T, string) {
+	if len(items) <= q.Limit {
+		return items, ""
+	}
+
+	items = items[:q.Limit]
+	last := items[len(items)-1]
+
+	after := make([]string, len(q.Sort))
+	for i, o := range q.Sort {
+		f, _ := q.spec.field(o.Field)
+		after[i] = f.Type.format(f.Value(last))
+	}
+
+	return items, signer.encode(after, q.signature)
+}
+
+// Sortable lists the names of the fields clients may sort by.
+func (s *Spec[T]) Sortable() []string {
+	var names []string
+	for _, f := range s.Fields {
+		if f.Sortable {
+			names = append(names, f.Name)
+		}
+	}
+
+	return names
+}
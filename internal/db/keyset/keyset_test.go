@@ -0,0 +1,188 @@
+package keyset
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	ID      string
+	Name    string
+	Created time.Time
+}
+
+var spec = &Spec[item]{
+	Fields: []Field[item]{
+		{Name: "id", Column: "i.id", Type: UUID, Sortable: true, Value: func(i item) any { return i.ID }},
+		{Name: "name", Column: "i.name", Type: String, Sortable: true, Filters: []Op{Eq, Contains, In},
+			Value: func(i item) any { return i.Name }},
+		{Name: "created_at", Column: "i.created_at", Type: Time, Sortable: true, Filters: []Op{Gte, Lt},
+			Value: func(i item) any { return i.Created }},
+		{Name: "size", Column: "i.size", Type: Int, Filters: []Op{Eq}},
+	},
+	Key:          "id",
+	DefaultSort:  "created_at",
+	DefaultLimit: 2,
+	MaxLimit:     10,
+}
+
+var signer = NewSigner("secret")
+
+func parse(t *testing.T, query string, signer *Signer) (*Query[item], error) {
+	values, err := url.ParseQuery(query)
+	require.NoError(t, err)
+
+	return spec.Parse(values, signer)
+}
+
+// codes returns the codes of the violations of err.
+func codes(t *testing.T, err error) map[string]string {
+	var e *Error
+	require.True(t, errors.As(err, &e), "want *Error, got %v", err)
+
+	got := make(map[string]string, len(e.Violations))
+	for field, violation := range e.Violations {
+		got[field] = violation.Code
+	}
+
+	return got
+}
+
+func TestSpec_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    *Query[item]
+		wantErr map[string]string
+	}{
+		{
+			name:  "Defaults",
+			query: "",
+			want: &Query[item]{
+				Limit: 2,
+				Sort:  []Order{{Field: "created_at"}, {Field: "id"}},
+			},
+		},
+		{
+			name:  "Sort and filters",
+			query: "limit=5&sort=-name&filter[name][in]=a,b&filter[size]=3",
+			want: &Query[item]{
+				Limit: 5,
+				Sort:  []Order{{Field: "name", Desc: true}, {Field: "id", Desc: true}},
+				Filters: []Filter{
+					{Field: "name", Op: In, Value: []string{"a", "b"}},
+					{Field: "size", Op: Eq, Value: int64(3)},
+				},
+			},
+		},
+		{
+			name:  "Invalid parameters",
+			query: "limit=11&sort=size&filter[size][gt]=1&filter[created_at][gte]=yesterday&filter[x=1",
+			wantErr: map[string]string{
+				"limit":                   "between",
+				"sort":                    "unsupported_sort",
+				"filter[size][gt]":        "unsupported_filter",
+				"filter[created_at][gte]": "timestamp",
+				"filter[x":                "filter_syntax",
+			},
+		},
+		{
+			name:    "Duplicate sort",
+			query:   "sort=name,-name",
+			wantErr: map[string]string{"sort": "duplicate_sort"},
+		},
+		{
+			name:    "Forged cursor",
+			query:   "cursor=eyJhIjpbXX0.c2ln",
+			wantErr: map[string]string{"cursor": "invalid_cursor"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parse(t, tt.query, signer)
+
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, codes(t, err))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.Limit, got.Limit)
+			assert.Equal(t, tt.want.Sort, got.Sort)
+			assert.Equal(t, tt.want.Filters, got.Filters)
+		})
+	}
+}
+
+func TestQuery_Cut(t *testing.T) {
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	items := []item{
+		{ID: "0b6c3f0e-1111-4c1e-9d7a-1c5b0f3e2a01", Name: "a", Created: created},
+		{ID: "0b6c3f0e-2222-4c1e-9d7a-1c5b0f3e2a02", Name: "b", Created: created.Add(time.Minute)},
+		{ID: "0b6c3f0e-3333-4c1e-9d7a-1c5b0f3e2a03", Name: "c", Created: created.Add(2 * time.Minute)},
+	}
+
+	q, err := parse(t, "filter[name][contains]=x", signer)
+	require.NoError(t, err)
+
+	got, cursor := q.Cut(items, signer)
+	assert.Equal(t, items[:2], got)
+	require.NotEmpty(t, cursor)
+
+	t.Run("Next page", func(t *testing.T) {
+		next, err := parse(t, "filter[name][contains]=x&cursor="+cursor, signer)
+
+		require.NoError(t, err)
+		assert.Equal(t, []any{items[1].Created, items[1].ID}, next.After)
+	})
+
+	t.Run("Cursor of another query", func(t *testing.T) {
+		_, err := parse(t, "sort=name&cursor="+cursor, signer)
+
+		assert.Equal(t, map[string]string{"cursor": "invalid_cursor"}, codes(t, err))
+	})
+
+	t.Run("Cursor of another signer", func(t *testing.T) {
+		_, err := parse(t, "filter[name][contains]=x&cursor="+cursor, NewSigner("other"))
+
+		assert.Equal(t, map[string]string{"cursor": "invalid_cursor"}, codes(t, err))
+	})
+
+	t.Run("Last page", func(t *testing.T) {
+		got, cursor := q.Cut(items[:2], signer)
+
+		assert.Len(t, got, 2)
+		assert.Empty(t, cursor)
+	})
+}
+
+func TestQuery_SQL(t *testing.T) {
+	q, err := parse(t, "sort=-created_at,name&filter[name][contains]=50%25_off", signer)
+	require.NoError(t, err)
+
+	q.After = []any{time.Time{}, "b", "0b6c3f0e-1111-4c1e-9d7a-1c5b0f3e2a01"}
+	args := pgx.NamedArgs{}
+
+	assert.Equal(t, "i.name::TEXT ILIKE @page_f0 AND ("+
+		"(i.created_at < @page_c0) OR "+
+		"(i.created_at = @page_c0 AND i.name > @page_c1) OR "+
+		"(i.created_at = @page_c0 AND i.name = @page_c1 AND i.id > @page_c2))", q.Where(args))
+	assert.Equal(t, "i.created_at DESC, i.name ASC, i.id ASC", q.OrderBy())
+	assert.Equal(t, "@page_limit", q.LimitArg(args))
+	assert.Equal(t, pgx.NamedArgs{
+		"page_f0":    `%50\%\_off%`,
+		"page_c0":    time.Time{},
+		"page_c1":    "b",
+		"page_c2":    "0b6c3f0e-1111-4c1e-9d7a-1c5b0f3e2a01",
+		"page_limit": 3,
+	}, args)
+
+	assert.Equal(t, "TRUE", (&Query[item]{spec: spec}).Where(pgx.NamedArgs{}))
+}
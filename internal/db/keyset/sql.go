@@ -0,0 +1,103 @@
+package keyset
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var operators = map[Op]string{
+	Eq:  "=",
+	Ne:  "<>",
+	Lt:  "<",
+	Lte: "<=",
+	Gt:  ">",
+	Gte: ">=",
+}
+
+// Where is the condition of the filters and the keyset, TRUE when there are none. The
+// values are added to args with a page_ prefix.
+func (q *Query[T]) Where(args pgx.NamedArgs) string {
+	var conditions []string
+
+	for i, f := range q.Filters {
+		field, _ := q.spec.field(f.Field)
+		name := fmt.Sprintf("page_f%d", i)
+
+		switch f.Op {
+		case In:
+			args[name] = f.Value
+			conditions = append(conditions, fmt.Sprintf("%s = ANY(@%s)", field.Column, name))
+		case Contains:
+			args[name] = "%" + escapeLike(f.Value.(string)) + "%"
+			conditions = append(conditions, fmt.Sprintf("%s::TEXT ILIKE @%s", field.Column, name))
+		default:
+			args[name] = f.Value
+			conditions = append(conditions, fmt.Sprintf("%s %s @%s", field.Column, operators[f.Op], name))
+		}
+	}
+
+	if q.After != nil {
+		conditions = append(conditions, q.keyset(args))
+	}
+
+	if len(conditions) == 0 {
+		return "TRUE"
+	}
+
+	return strings.Join(conditions, " AND ")
+}
+
+// keyset selects the items after the cursor. Sorts can mix directions, so it's spelled
+// out as (a > @a) OR (a = @a AND b < @b) OR ... rather than a row comparison.
+func (q *Query[T]) keyset(args pgx.NamedArgs) string {
+	alternatives := make([]string, len(q.Sort))
+
+	for i, o := range q.Sort {
+		terms := make([]string, 0, i+1)
+
+		for j := range i {
+			field, _ := q.spec.field(q.Sort[j].Field)
+			terms = append(terms, fmt.Sprintf("%s = @page_c%d", field.Column, j))
+		}
+
+		field, _ := q.spec.field(o.Field)
+		op := ">"
+		if o.Desc {
+			op = "<"
+		}
+		terms = append(terms, fmt.Sprintf("%s %s @page_c%d", field.Column, op, i))
+
+		args[fmt.Sprintf("page_c%d", i)] = q.After[i]
+		alternatives[i] = "(" + strings.Join(terms, " AND ") + ")"
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")"
+}
+
+// OrderBy lists the sort columns, the key included.
+func (q *Query[T]) OrderBy() string {
+	orders := make([]string, len(q.Sort))
+
+	for i, o := range q.Sort {
+		field, _ := q.spec.field(o.Field)
+
+		orders[i] = field.Column + " ASC"
+		if o.Desc {
+			orders[i] = field.Column + " DESC"
+		}
+	}
+
+	return strings.Join(orders, ", ")
+}
+
+// LimitArg fetches one item more than the page size, it tells whether there is a next page.
+func (q *Query[T]) LimitArg(args pgx.NamedArgs) string {
+	args["page_limit"] = q.Limit + 1
+	return "@page_limit"
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
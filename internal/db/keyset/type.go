@@ -0,0 +1,119 @@
+package keyset
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kiennyo/syncwatch-be/internal/validator"
+)
+
+// Type parses filter and cursor values of a field.
+type Type int
+
+const (
+	String Type = iota
+	Int
+	Bool
+	Time
+	UUID
+)
+
+// maxIn bounds the values of an in filter.
+const maxIn = 100
+
+func (t Type) parse(raw string) (any, *validator.Violation) {
+	switch t {
+	case Int:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, &validator.Violation{Code: "number"}
+		}
+		return n, nil
+	case Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, &validator.Violation{Code: "boolean"}
+		}
+		return b, nil
+	case Time:
+		ts, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return nil, &validator.Violation{Code: "timestamp"}
+		}
+		return ts, nil
+	case UUID:
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, &validator.Violation{Code: "uuid"}
+		}
+		return id.String(), nil
+	default:
+		return raw, nil
+	}
+}
+
+// array types the values of an in filter, so pgx encodes them as a Postgres array.
+func (t Type) array(values []any) any {
+	switch t {
+	case Int:
+		return typed[int64](values)
+	case Bool:
+		return typed[bool](values)
+	case Time:
+		return typed[time.Time](values)
+	default:
+		return typed[string](values)
+	}
+}
+
+func typed[E any](values []any) []E {
+	out := make([]E, len(values))
+	for i, v := range values {
+		out[i] = v.(E)
+	}
+
+	return out
+}
+
+// format is the inverse of parse, for cursors.
+func (t Type) format(v any) string {
+	switch v := v.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (t Type) parseFilter(op Op, raw string) (any, *validator.Violation) {
+	switch op {
+	case In:
+		parts := strings.Split(raw, ",")
+		if len(parts) > maxIn {
+			return nil, &validator.Violation{Code: "max_items", Params: map[string]any{"max": maxIn}}
+		}
+
+		values := make([]any, len(parts))
+		for i, part := range parts {
+			v, violation := t.parse(strings.TrimSpace(part))
+			if violation != nil {
+				return nil, violation
+			}
+			values[i] = v
+		}
+		return t.array(values), nil
+	case Contains:
+		if raw == "" {
+			return nil, &validator.Violation{Code: "required"}
+		}
+		return raw, nil
+	default:
+		return t.parse(raw)
+	}
+}
@@ -0,0 +1,358 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrationsLockID is the key of the advisory lock held while migrating, so
+// instances starting at the same time don't apply the same migrations twice.
+const migrationsLockID = 5_729_183_001
+
+var (
+	ErrDirty            = errors.New("database is dirty, fix the failed migration and force the version")
+	ErrUnknownMigration = errors.New("unknown migration version")
+	ErrNoDownMigration  = errors.New("migration has no down file")
+	ErrInvalidSteps     = errors.New("steps must be at least 1")
+)
+
+var migrationFileRX = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// step applies (up) or reverts (down) a single migration.
+type step struct {
+	migration *migration
+	up        bool
+}
+
+type MigrationStatus struct {
+	Version int64
+	Dirty   bool
+	Latest  int64
+	Applied []string
+	Pending []string
+}
+
+// Migrator applies the migrations found in a directory of <version>_<name>.up.sql and
+// .down.sql files. Progress is tracked in schema_migrations like golang-migrate does,
+// so databases migrated by the migrate CLI keep working.
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []*migration
+}
+
+func NewMigrator(pool *pgxpool.Pool, fsys fs.FS) (*Migrator, error) {
+	migrations, err := parseMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		pool:       pool,
+		migrations: migrations,
+	}, nil
+}
+
+// Latest returns the version of the newest migration.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.migrate(ctx, func(current int64) ([]step, error) {
+		return m.plan(current, m.Latest())
+	})
+}
+
+// Down reverts the given number of applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("%w, got %d", ErrInvalidSteps, steps)
+	}
+
+	return m.migrate(ctx, func(current int64) ([]step, error) {
+		target := int64(0)
+
+		i := m.index(current)
+		if i-steps >= 0 {
+			target = m.migrations[i-steps].Version
+		}
+
+		return m.plan(current, target)
+	})
+}
+
+// Goto migrates up or down to the given version, 0 reverts everything.
+func (m *Migrator) Goto(ctx context.Context, version int64) error {
+	return m.migrate(ctx, func(current int64) ([]step, error) {
+		return m.plan(current, version)
+	})
+}
+
+// Force sets the version without running any migration and clears the dirty flag.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	if version != 0 && m.index(version) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownMigration, version)
+	}
+
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		return setVersion(ctx, conn, version, false)
+	})
+}
+
+// Status doesn't take the lock, so it can be polled while another instance migrates.
+func (m *Migrator) Status(ctx context.Context) (*MigrationStatus, error) {
+	status := &MigrationStatus{Latest: m.Latest()}
+
+	var err error
+	status.Version, status.Dirty, err = version(ctx, m.pool)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == undefinedTable {
+		err = nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mig := range m.migrations {
+		name := fmt.Sprintf("%d_%s", mig.Version, mig.Name)
+		if mig.Version <= status.Version {
+			status.Applied = append(status.Applied, name)
+		} else {
+			status.Pending = append(status.Pending, name)
+		}
+	}
+
+	return status, nil
+}
+
+func (m *Migrator) migrate(ctx context.Context, planner func(current int64) ([]step, error)) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		current, dirty, err := version(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		if dirty {
+			return fmt.Errorf("%w: version %d", ErrDirty, current)
+		}
+
+		if current != 0 && m.index(current) < 0 {
+			return fmt.Errorf("%w: database is at %d", ErrUnknownMigration, current)
+		}
+
+		steps, err := planner(current)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range steps {
+			if err = m.apply(ctx, conn, s); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// apply marks the database dirty while the migration runs, a failure leaves it dirty
+// as statements of a migration file are not necessarily run in a single transaction.
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, s step) error {
+	sql, done := s.migration.Up, s.migration.Version
+	direction := "up"
+
+	if !s.up {
+		sql, direction = s.migration.Down, "down"
+		if i := m.index(s.migration.Version); i > 0 {
+			done = m.migrations[i-1].Version
+		} else {
+			done = 0
+		}
+	}
+
+	slog.Info("applying migration", "version", s.migration.Version, "name", s.migration.Name, "direction", direction)
+
+	if err := setVersion(ctx, conn, s.migration.Version, true); err != nil {
+		return err
+	}
+
+	if _, err := conn.Exec(ctx, sql); err != nil {
+		return fmt.Errorf("migration %d_%s %s: %w", s.migration.Version, s.migration.Name, direction, err)
+	}
+
+	return setVersion(ctx, conn, done, false)
+}
+
+// plan returns the steps to get from the current to the target version.
+func (m *Migrator) plan(current, target int64) ([]step, error) {
+	if target != 0 && m.index(target) < 0 {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownMigration, target)
+	}
+
+	var steps []step
+
+	if target >= current {
+		for _, mig := range m.migrations {
+			if mig.Version > current && mig.Version <= target {
+				steps = append(steps, step{migration: mig, up: true})
+			}
+		}
+
+		return steps, nil
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if mig.Version <= current && mig.Version > target {
+			if mig.Down == "" {
+				return nil, fmt.Errorf("%w: %d_%s", ErrNoDownMigration, mig.Version, mig.Name)
+			}
+			steps = append(steps, step{migration: mig, up: false})
+		}
+	}
+
+	return steps, nil
+}
+
+func (m *Migrator) index(version int64) int {
+	return slices.IndexFunc(m.migrations, func(mig *migration) bool {
+		return mig.Version == version
+	})
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationsLockID); err != nil {
+		return err
+	}
+
+	defer func() {
+		// the session lock has to be released even if ctx got cancelled meanwhile
+		_, err := conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrationsLockID)
+		if err != nil {
+			slog.Error("Failed to release migrations lock", "reason", err.Error())
+		}
+	}()
+
+	query := `CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`
+	if _, err = conn.Exec(ctx, query); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func version(ctx context.Context, conn rowQuerier) (int64, bool, error) {
+	var v int64
+	var dirty bool
+
+	err := conn.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&v, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+
+	return v, dirty, err
+}
+
+func setVersion(ctx context.Context, conn *pgxpool.Conn, version int64, dirty bool) error {
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "TRUNCATE schema_migrations"); err != nil {
+			return err
+		}
+
+		if version == 0 && !dirty {
+			return nil
+		}
+
+		query := `INSERT INTO schema_migrations (version, dirty) VALUES (@version, @dirty)`
+		_, err := tx.Exec(ctx, query, pgx.NamedArgs{"version": version, "dirty": dirty})
+
+		return err
+	})
+}
+
+func parseMigrations(fsys fs.FS) ([]*migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*migration)
+
+	for _, file := range files {
+		match := migrationFileRX.FindStringSubmatch(file)
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s", file)
+		}
+
+		v, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %w", file, err)
+		}
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[v]
+		if !ok {
+			mig = &migration{Version: v, Name: match[2]}
+			byVersion[v] = mig
+		}
+
+		if match[3] == "up" {
+			mig.Up = string(content)
+		} else {
+			mig.Down = string(content)
+		}
+	}
+
+	migrations := make([]*migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, mig)
+	}
+
+	slices.SortFunc(migrations, func(a, b *migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return migrations, nil
+}
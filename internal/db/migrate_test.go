@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiennyo/syncwatch-be/migrations"
+)
+
+func TestParseMigrations(t *testing.T) {
+	embedded, err := parseMigrations(migrations.FS)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, embedded)
+
+	for _, mig := range embedded {
+		assert.NotEmpty(t, mig.Up, mig.Name)
+		assert.NotEmpty(t, mig.Down, mig.Name)
+	}
+
+	_, err = parseMigrations(fstest.MapFS{"1_users.sql": {}})
+	assert.ErrorContains(t, err, "invalid migration file name")
+
+	_, err = parseMigrations(fstest.MapFS{"1_users.down.sql": {Data: []byte("DROP TABLE users")}})
+	assert.ErrorContains(t, err, "has no up file")
+}
+
+// TestMigrator_DownSteps needs no database, the steps are checked before connecting.
+func TestMigrator_DownSteps(t *testing.T) {
+	m := &Migrator{}
+
+	for _, steps := range []int{0, -1} {
+		assert.ErrorIs(t, m.Down(context.Background(), steps), ErrInvalidSteps)
+	}
+}
+
+//nolint:revive,function-length
+func TestMigrator_Plan(t *testing.T) {
+	m := &Migrator{}
+	m.migrations, _ = parseMigrations(fstest.MapFS{
+		"3_third.up.sql":    {Data: []byte("3 up")},
+		"1_first.up.sql":    {Data: []byte("1 up")},
+		"1_first.down.sql":  {Data: []byte("1 down")},
+		"2_second.up.sql":   {Data: []byte("2 up")},
+		"2_second.down.sql": {Data: []byte("2 down")},
+	})
+
+	type expectedStep struct {
+		version int64
+		up      bool
+	}
+
+	tests := []struct {
+		name     string
+		current  int64
+		target   int64
+		expected []expectedStep
+		err      error
+	}{
+		{
+			name:     "Up from scratch",
+			current:  0,
+			target:   3,
+			expected: []expectedStep{{1, true}, {2, true}, {3, true}},
+		},
+		{
+			name:     "Up to version",
+			current:  1,
+			target:   2,
+			expected: []expectedStep{{2, true}},
+		},
+		{
+			name:     "Nothing to do",
+			current:  2,
+			target:   2,
+			expected: nil,
+		},
+		{
+			name:     "Down to nothing",
+			current:  2,
+			target:   0,
+			expected: []expectedStep{{2, false}, {1, false}},
+		},
+		{
+			name:    "Down without down file",
+			current: 3,
+			target:  2,
+			err:     ErrNoDownMigration,
+		},
+		{
+			name:    "Unknown target",
+			current: 1,
+			target:  5,
+			err:     ErrUnknownMigration,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			steps, err := m.plan(tc.current, tc.target)
+
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			var actual []expectedStep
+			for _, s := range steps {
+				actual = append(actual, expectedStep{s.migration.Version, s.up})
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+
+	assert.Equal(t, int64(3), m.Latest())
+}
@@ -16,14 +16,10 @@ func New(ctx context.Context, cfg config.DB) (*pgxpool.Pool, error) {
 		return nil, err
 	}
 
-	duration, err := time.ParseDuration(cfg.MaxIdleTime)
-	if err != nil {
-		return nil, err
-	}
-
 	parseConfig.MaxConns = int32(cfg.MaxOpenConn)
 	parseConfig.MinConns = int32(cfg.MaxIdleConn)
-	parseConfig.MaxConnIdleTime = duration
+	parseConfig.MaxConnIdleTime = cfg.MaxIdleTime
+	parseConfig.ConnConfig.Tracer = newQueryTracer()
 
 	db, err := pgxpool.NewWithConfig(ctx, parseConfig)
 	if err != nil {
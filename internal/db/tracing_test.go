@@ -0,0 +1,13 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperation(t *testing.T) {
+	assert.Equal(t, "WITH", operation("\n\t\tWITH user_insert AS (INSERT INTO ..."))
+	assert.Equal(t, "SELECT", operation("select 1"))
+	assert.Equal(t, "UNKNOWN", operation("  "))
+}
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// txAttempts is how many times a transaction failing with a serialization error is run.
+const txAttempts = 3
+
+// Querier is what repositories run their statements on. It's implemented by
+// *pgxpool.Pool, pgx.Tx and TxManager, which picks the transaction from the context.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Transactor runs fn in a transaction, services use it to make several repository
+// calls atomic.
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type conn interface {
+	Querier
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+type txKey struct{}
+
+// TxManager keeps the current transaction in the context. Repositories built on it
+// take part in a transaction started by a service without knowing about it.
+type TxManager struct {
+	conn    conn
+	backoff time.Duration
+}
+
+var (
+	_ Querier    = (*TxManager)(nil)
+	_ Transactor = (*TxManager)(nil)
+)
+
+// NewTxManager accepts a *pgxpool.Pool.
+func NewTxManager(c conn) *TxManager {
+	return &TxManager{
+		conn:    c,
+		backoff: 20 * time.Millisecond,
+	}
+}
+
+// InTx runs fn in a read committed transaction, see InTxOptions.
+func (m *TxManager) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return m.InTxOptions(ctx, pgx.TxOptions{}, fn)
+}
+
+// InTxOptions commits when fn returns nil and rolls back otherwise. Called within a
+// transaction, fn runs in a savepoint and opts are ignored. The outermost transaction is
+// retried on serialization failures, so fn must be safe to run more than once.
+func (m *TxManager) InTxOptions(ctx context.Context, opts pgx.TxOptions, fn func(ctx context.Context) error) error {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		// pgx turns a nested Begin into a savepoint
+		return run(ctx, func() (pgx.Tx, error) { return tx.Begin(ctx) }, fn)
+	}
+
+	var err error
+	for attempt := range txAttempts {
+		if attempt > 0 {
+			if err = m.wait(ctx, attempt); err != nil {
+				return err
+			}
+		}
+
+		err = run(ctx, func() (pgx.Tx, error) { return m.conn.BeginTx(ctx, opts) }, fn)
+		if !IsSerializationFailure(err) {
+			return err
+		}
+
+		slog.Warn("retrying transaction", "attempt", attempt+1, "reason", err.Error())
+	}
+
+	return err
+}
+
+func (m *TxManager) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return m.querier(ctx).Exec(ctx, sql, args...)
+}
+
+func (m *TxManager) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return m.querier(ctx).Query(ctx, sql, args...)
+}
+
+func (m *TxManager) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return m.querier(ctx).QueryRow(ctx, sql, args...)
+}
+
+// WithoutTx returns ctx without the transaction InTx put in it, keeping its other
+// values. Work that outlives the transaction, e.g. a background task started within it,
+// must not run on a transaction that is committed or rolled back by then.
+func WithoutTx(ctx context.Context) context.Context {
+	if ctx.Value(txKey{}) == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, txKey{}, nil)
+}
+
+func (m *TxManager) querier(ctx context.Context) Querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+
+	return m.conn
+}
+
+// wait sleeps an exponentially growing, jittered time before the next attempt.
+func (m *TxManager) wait(ctx context.Context, attempt int) error {
+	d := m.backoff << (attempt - 1)
+	d += rand.N(d + 1) //nolint:gosec
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func run(ctx context.Context, begin func() (pgx.Tx, error), fn func(ctx context.Context) error) error {
+	tx, err := begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// a no-op after commit, it also cleans up when fn panics
+		_ = tx.Rollback(context.WithoutCancel(ctx))
+	}()
+
+	if err = fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+// txFake records what happened to a transaction, nested ones are savepoints.
+type txFake struct {
+	pgx.Tx
+	name      string
+	log       *[]string
+	closed    bool
+	savepoint int
+}
+
+func (t *txFake) Begin(_ context.Context) (pgx.Tx, error) {
+	t.savepoint++
+	name := t.name + "/savepoint"
+	*t.log = append(*t.log, "begin "+name)
+
+	return &txFake{name: name, log: t.log}, nil
+}
+
+func (t *txFake) Commit(_ context.Context) error {
+	t.closed = true
+	*t.log = append(*t.log, "commit "+t.name)
+
+	return nil
+}
+
+func (t *txFake) Rollback(_ context.Context) error {
+	if t.closed {
+		return pgx.ErrTxClosed
+	}
+
+	t.closed = true
+	*t.log = append(*t.log, "rollback "+t.name)
+
+	return nil
+}
+
+type connFake struct {
+	Querier
+	log []string
+	txs int
+}
+
+func (c *connFake) BeginTx(_ context.Context, _ pgx.TxOptions) (pgx.Tx, error) {
+	c.txs++
+	c.log = append(c.log, "begin tx")
+
+	return &txFake{name: "tx", log: &c.log}, nil
+}
+
+//nolint:revive,function-length
+func TestTxManager_InTx(t *testing.T) {
+	errFailed := errors.New("failed")
+	serialization := &pgconn.PgError{Code: serializationFailure}
+
+	tests := []struct {
+		name     string
+		fn       func(m *TxManager, calls *int) func(ctx context.Context) error
+		err      error
+		expected []string
+	}{
+		{
+			name: "Commit",
+			fn: func(_ *TxManager, _ *int) func(ctx context.Context) error {
+				return func(_ context.Context) error { return nil }
+			},
+			expected: []string{"begin tx", "commit tx"},
+		},
+		{
+			name: "Rollback on error",
+			fn: func(_ *TxManager, _ *int) func(ctx context.Context) error {
+				return func(_ context.Context) error { return errFailed }
+			},
+			err:      errFailed,
+			expected: []string{"begin tx", "rollback tx"},
+		},
+		{
+			name: "Nested transaction uses savepoint",
+			fn: func(m *TxManager, _ *int) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					_ = m.InTx(ctx, func(_ context.Context) error { return errFailed })
+					return m.InTx(ctx, func(_ context.Context) error { return nil })
+				}
+			},
+			expected: []string{
+				"begin tx",
+				"begin tx/savepoint", "rollback tx/savepoint",
+				"begin tx/savepoint", "commit tx/savepoint",
+				"commit tx",
+			},
+		},
+		{
+			name: "Retry on serialization failure",
+			fn: func(_ *TxManager, calls *int) func(ctx context.Context) error {
+				return func(_ context.Context) error {
+					if *calls++; *calls == 1 {
+						return serialization
+					}
+					return nil
+				}
+			},
+			expected: []string{"begin tx", "rollback tx", "begin tx", "commit tx"},
+		},
+		{
+			name: "Give up after attempts",
+			fn: func(_ *TxManager, _ *int) func(ctx context.Context) error {
+				return func(_ context.Context) error { return serialization }
+			},
+			err: serialization,
+			expected: []string{
+				"begin tx", "rollback tx",
+				"begin tx", "rollback tx",
+				"begin tx", "rollback tx",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &connFake{}
+			m := NewTxManager(c)
+			m.backoff = 0
+
+			calls := 0
+			err := m.InTx(context.Background(), tc.fn(m, &calls))
+
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.expected, c.log)
+		})
+	}
+}
+
+func TestTxManager_Querier(t *testing.T) {
+	c := &connFake{}
+	m := NewTxManager(c)
+
+	assert.Equal(t, c, m.querier(context.Background()))
+
+	err := m.InTx(context.Background(), func(ctx context.Context) error {
+		tx, ok := m.querier(ctx).(*txFake)
+
+		assert.True(t, ok)
+		assert.Equal(t, "tx", tx.name)
+
+		assert.Equal(t, c, m.querier(WithoutTx(ctx)), "detached from the transaction")
+
+		return nil
+	})
+	assert.NoError(t, err)
+}
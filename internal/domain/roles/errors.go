@@ -0,0 +1,6 @@
+package roles
+
+import "errors"
+
+var ErrRoleNotFound = errors.New("role not found")
+var ErrPermissionNotFound = errors.New("permission not found")
@@ -0,0 +1,108 @@
+package roles
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/kiennyo/syncwatch-be/internal/db"
+)
+
+type Repository interface {
+	List(ctx context.Context) ([]*Role, error)
+	Grant(ctx context.Context, role, permission string) error
+	Revoke(ctx context.Context, role, permission string) error
+}
+
+type roleRepository struct {
+	DB db.Querier
+}
+
+var _ Repository = (*roleRepository)(nil)
+
+func NewRepository(querier db.Querier) Repository {
+	return &roleRepository{DB: querier}
+}
+
+func (r *roleRepository) List(ctx context.Context) ([]*Role, error) {
+	query := `
+		SELECT r.id, r.title, r.slug, r.description, r.created_at, r.updated_at,
+		       COALESCE(ARRAY_AGG(p.slug ORDER BY p.slug) FILTER (WHERE p.slug IS NOT NULL), '{}')
+		FROM role r
+		LEFT JOIN role_permission rp ON r.id = rp.role_id
+		LEFT JOIN permission p ON rp.permission_id = p.id
+		GROUP BY r.id
+		ORDER BY r.slug`
+
+	rows, err := r.DB.Query(ctx, query)
+	if err != nil {
+		return nil, db.MapError(err)
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*Role, error) {
+		role := Role{}
+		err := row.Scan(&role.ID, &role.Title, &role.Slug, &role.Description, &role.CreatedAt, &role.UpdatedAt,
+			&role.Permissions)
+
+		return &role, err
+	})
+}
+
+func (r *roleRepository) Grant(ctx context.Context, role, permission string) error {
+	query := `
+		INSERT INTO role_permission (role_id, permission_id)
+		SELECT r.id, p.id
+		FROM role r, permission p
+		WHERE r.slug = @role AND p.slug = @permission
+		ON CONFLICT (role_id, permission_id) DO NOTHING
+		RETURNING role_id`
+
+	return r.modify(ctx, query, role, permission)
+}
+
+func (r *roleRepository) Revoke(ctx context.Context, role, permission string) error {
+	query := `
+		DELETE FROM role_permission
+		WHERE role_id = (SELECT id FROM role WHERE slug = @role)
+		  AND permission_id = (SELECT id FROM permission WHERE slug = @permission)
+		RETURNING role_id`
+
+	return r.modify(ctx, query, role, permission)
+}
+
+// modify runs a grant or revoke, when nothing was changed it checks whether that's
+// because of an unknown role or permission, which is reported as an error.
+func (r *roleRepository) modify(ctx context.Context, query, role, permission string) error {
+	args := pgx.NamedArgs{
+		"role":       role,
+		"permission": permission,
+	}
+
+	var id any
+	err := r.DB.QueryRow(ctx, query, args).Scan(&id)
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return db.MapError(err)
+	}
+
+	var roleExists, permissionExists bool
+	err = r.DB.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM role WHERE slug = @role),
+		       EXISTS (SELECT 1 FROM permission WHERE slug = @permission)`, args).
+		Scan(&roleExists, &permissionExists)
+
+	switch {
+	case err != nil:
+		return db.MapError(err)
+	case !roleExists:
+		return ErrRoleNotFound
+	case !permissionExists:
+		return ErrPermissionNotFound
+	default:
+		return nil // already granted or revoked
+	}
+}
@@ -0,0 +1,33 @@
+package roles
+
+import "context"
+
+type Service interface {
+	List(ctx context.Context) ([]*Role, error)
+	Grant(ctx context.Context, role, permission string) error
+	Revoke(ctx context.Context, role, permission string) error
+}
+
+type roleService struct {
+	repository Repository
+}
+
+var _ Service = (*roleService)(nil)
+
+func NewService(r Repository) Service {
+	return &roleService{
+		repository: r,
+	}
+}
+
+func (s *roleService) List(ctx context.Context) ([]*Role, error) {
+	return s.repository.List(ctx)
+}
+
+func (s *roleService) Grant(ctx context.Context, role, permission string) error {
+	return s.repository.Grant(ctx, role, permission)
+}
+
+func (s *roleService) Revoke(ctx context.Context, role, permission string) error {
+	return s.repository.Revoke(ctx, role, permission)
+}
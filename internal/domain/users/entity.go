@@ -7,13 +7,15 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-type user struct {
+type User struct {
 	ID        uuid.UUID `json:"id"`
 	Name      string    `json:"name"`
 	Email     string    `json:"email"`
+	Language  string    `json:"language" doc:"Language of emails sent to the user"`
 	Password  password  `json:"-"`
 	Activated bool      `json:"activated"`
-	Scopes    []string  `json:"scopes"`
+	Role      string    `json:"role" doc:"Slug of the role"`
+	Scopes    []string  `json:"scopes" doc:"Permissions granted by the role"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -35,3 +37,7 @@ func (p *password) set(plaintextPassword string) error {
 
 	return nil
 }
+
+func (u *User) SetPassword(plaintextPassword string) error {
+	return u.Password.set(plaintextPassword)
+}
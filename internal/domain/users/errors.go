@@ -1,6 +1,18 @@
 package users
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/kiennyo/syncwatch-be/internal/db"
+)
 
 var errDuplicateEmail = errors.New("duplicate email")
 var errUserNotFound = errors.New("user not found")
+var errRoleNotFound = errors.New("role not found")
+var errUserModified = errors.New("user was modified")
+
+// constraints maps violations of the user table constraints to the errors above.
+var constraints = db.Constraints{
+	"user_email_key": errDuplicateEmail,
+	"user.role_id":   errRoleNotFound,
+}
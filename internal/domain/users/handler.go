@@ -1,101 +1,296 @@
 package users
 
 import (
+	"cmp"
 	"errors"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
 
+	"github.com/kiennyo/syncwatch-be/internal/db/keyset"
 	httperr "github.com/kiennyo/syncwatch-be/internal/http/error"
+	"github.com/kiennyo/syncwatch-be/internal/http/etag"
 	"github.com/kiennyo/syncwatch-be/internal/http/json"
+	"github.com/kiennyo/syncwatch-be/internal/http/openapi"
+	"github.com/kiennyo/syncwatch-be/internal/http/page"
+	"github.com/kiennyo/syncwatch-be/internal/mail"
+	"github.com/kiennyo/syncwatch-be/internal/ratelimit"
 	"github.com/kiennyo/syncwatch-be/internal/security"
 	"github.com/kiennyo/syncwatch-be/internal/validator"
 )
 
+const tracerName = "github.com/kiennyo/syncwatch-be/internal/domain/users"
+
+const codeDuplicateEmail = "duplicate_email"
+
+// Sign up hashes the password with bcrypt, so it is throttled per client and per
+// email to keep it from being used to exhaust the CPU.
+var (
+	signUpPolicy = ratelimit.Policy{
+		Name:  "signup",
+		Limit: ratelimit.Limit{Requests: 10, Window: time.Hour},
+		Key:   ratelimit.ByIP,
+	}
+	signUpEmailPolicy = ratelimit.Policy{
+		Name:  "signup_email",
+		Limit: ratelimit.Limit{Requests: 3, Window: time.Hour},
+		Key:   ratelimit.ByField("email"),
+	}
+)
+
+// listSpec is what clients may sort and filter the user list by.
+var listSpec = &keyset.Spec[*User]{
+	Fields: []keyset.Field[*User]{
+		{Name: "id", Column: "u.id", Type: keyset.UUID, Sortable: true, Filters: []keyset.Op{keyset.Eq, keyset.In},
+			Value: func(u *User) any { return u.ID }},
+		{Name: "created_at", Column: "u.created_at", Type: keyset.Time, Sortable: true,
+			Filters: []keyset.Op{keyset.Lt, keyset.Lte, keyset.Gt, keyset.Gte}, Value: func(u *User) any { return u.CreatedAt }},
+		{Name: "email", Column: "u.email", Type: keyset.String, Sortable: true,
+			Filters: []keyset.Op{keyset.Eq, keyset.Contains}, Value: func(u *User) any { return u.Email }},
+		{Name: "name", Column: "u.name", Type: keyset.String, Sortable: true,
+			Filters: []keyset.Op{keyset.Eq, keyset.Contains}, Value: func(u *User) any { return u.Name }},
+		{Name: "role", Column: "r.slug", Type: keyset.String, Filters: []keyset.Op{keyset.Eq, keyset.Ne, keyset.In}},
+		{Name: "activated", Column: "u.activated", Type: keyset.Bool, Filters: []keyset.Op{keyset.Eq}},
+	},
+	Key:          "id",
+	DefaultSort:  "created_at",
+	DefaultLimit: 20,
+	MaxLimit:     100,
+}
+
 type Handler struct {
 	service Service
+	limiter *ratelimit.Limiter
+	signer  *keyset.Signer
 }
 
-func NewHandler(s Service) *Handler {
+func NewHandler(s Service, l *ratelimit.Limiter, signer *keyset.Signer) *Handler {
 	return &Handler{
 		service: s,
+		limiter: l,
+		signer:  signer,
 	}
 }
 
 func (h *Handler) Handlers() chi.Router {
 	r := chi.NewRouter()
-	r.Post("/", h.signUp)
-	r.Patch("/{userID}/activated", security.Authorize(h.activate, "user:activate"))
+	r.Get("/", security.Authorize(httperr.Handle(h.list), "user:list"))
+	r.With(h.limiter.Middleware(signUpPolicy), h.limiter.Middleware(signUpEmailPolicy)).
+		Post("/", httperr.Handle(h.signUp))
+	r.Get("/{userID}", security.Authorize(httperr.Handle(h.get), "user:view"))
+	r.Delete("/{userID}", security.Authorize(httperr.Handle(h.disable), "user:delete:all"))
+	r.Patch("/{userID}/activated", security.Authorize(httperr.Handle(h.activate), "user:activate"))
 
 	return r
 }
 
-func (h *Handler) signUp(w http.ResponseWriter, r *http.Request) {
-	var input struct {
-		Name     string `json:"name"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
+// Operations documents the routes of Handlers.
+func (h *Handler) Operations() []openapi.Operation {
+	userID := openapi.Param{Name: "userID", In: openapi.InPath, Schema: &openapi.Schema{Type: "string", Format: "uuid"}}
+	ifMatch := func(required bool) openapi.Param {
+		return openapi.Param{
+			Name: "If-Match", In: openapi.InHeader, Required: required, Schema: &openapi.Schema{Type: "string"},
+			Description: "ETag of the user, the change fails with 412 once the user was modified",
+		}
+	}
+	ifNoneMatch := openapi.Param{Name: "If-None-Match", In: openapi.InHeader, Schema: &openapi.Schema{Type: "string"}}
+	user := json.Envelope{"user": &User{}}
+	message := json.Envelope{"message": ""}
+	tags := []string{"users"}
+
+	return []openapi.Operation{
+		{
+			Method: http.MethodGet, Path: "/", ID: "listUsers", Summary: "List users", Tags: tags, Scope: "user:list",
+			Params: page.Params(listSpec),
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: json.Envelope{"users": []*User{}, "page": &page.Page{}},
+					Description: "A page of users, the Link header points to the next one"},
+			},
+		},
+		{
+			Method: http.MethodPost, Path: "/", ID: "signUp", Summary: "Sign up", Tags: tags, Request: userInput{},
+			Description: "Creates an inactive user and emails the activation token.",
+			Responses: []openapi.Response{
+				{Status: http.StatusCreated, Body: user},
+				{Status: http.StatusUnprocessableEntity},
+				{Status: http.StatusTooManyRequests},
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/{userID}", ID: "getUser", Summary: "Get a user", Tags: tags, Scope: "user:view",
+			Description: "Users may view themselves, others require the user:view:all permission.",
+			Params:      []openapi.Param{userID, ifNoneMatch},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: user, Description: "The user, the ETag header carries its version"},
+				{Status: http.StatusNotModified, Description: "The user matches If-None-Match"},
+				{Status: http.StatusForbidden},
+				{Status: http.StatusNotFound},
+			},
+		},
+		{
+			Method: http.MethodDelete, Path: "/{userID}", ID: "disableUser", Summary: "Disable a user", Tags: tags,
+			Scope: "user:delete:all", Params: []openapi.Param{userID, ifMatch(true)},
+			Responses: []openapi.Response{
+				{Status: http.StatusNoContent},
+				{Status: http.StatusNotFound},
+				{Status: http.StatusPreconditionFailed},
+				{Status: http.StatusPreconditionRequired},
+			},
+		},
+		{
+			Method: http.MethodPatch, Path: "/{userID}/activated", ID: "activateUser", Summary: "Activate a user",
+			Tags: tags, Scope: "user:activate", Params: []openapi.Param{userID, ifMatch(false)},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: message},
+				{Status: http.StatusForbidden},
+				{Status: http.StatusPreconditionFailed},
+			},
+		},
 	}
+}
 
-	err := json.ReadJSON(w, r, &input)
+func (h *Handler) signUp(w http.ResponseWriter, r *http.Request) error {
+	var input userInput
+
+	err := json.Read(w, r, &input)
 	if err != nil {
-		httperr.InvalidJSON(w, r, err)
-		return
+		return err
 	}
 
 	v := validator.New()
 
-	u := &user{
-		Name:  input.Name,
-		Email: input.Email,
+	u := &User{
+		Name:     input.Name,
+		Email:    input.Email,
+		Language: cmp.Or(input.Language, mail.DefaultLocale),
 	}
 
-	err = u.Password.set(input.Password)
-	if err != nil {
-		httperr.Internal(w, r, err)
-		return
+	if err = validateUserInput(v, u, input.Password); err != nil {
+		return err
 	}
 
-	if validateUserInput(v, u); !v.Valid() {
-		httperr.Validation(w, r, v.Errors())
-		return
+	if !v.Valid() {
+		return httperr.Validation(v.Violations())
+	}
+
+	// bcrypt is slow by design, the span shows how much of the request it takes
+	_, span := otel.Tracer(tracerName).Start(r.Context(), "users.hashPassword")
+	err = u.Password.set(input.Password)
+	span.End()
+
+	if err != nil {
+		return err
 	}
 
 	if err = h.service.SignUp(r.Context(), u); err != nil {
-		switch {
-		case errors.Is(err, errDuplicateEmail):
-			// could make conflict in the future
-			v.AddError("email", "a user with this email address already exists")
-			httperr.Validation(w, r, v.Errors())
-		default:
-			httperr.Internal(w, r, err)
+		if errors.Is(err, errDuplicateEmail) {
+			// a validation failure of its own, so clients can tell it apart
+			e := httperr.New(http.StatusUnprocessableEntity, codeDuplicateEmail, "the email address is already in use")
+			return e.WithFields(map[string]validator.Violation{"email": {Code: codeDuplicateEmail}})
 		}
-		return
+
+		return err
+	}
+
+	return json.Write(w, r, http.StatusCreated, json.Envelope{"user": u}, nil)
+}
+
+func (h *Handler) activate(w http.ResponseWriter, r *http.Request) error {
+	userID := chi.URLParam(r, "userID")
+
+	principal := security.ContextGetPrincipal(r)
+
+	if userID != principal.Sub {
+		return httperr.Forbidden()
 	}
 
-	err = json.WriteJSON(w, http.StatusCreated, json.Envelope{"user": u}, nil)
+	// activation links can't send If-Match, so it's optional here
+	versions, err := etag.IfMatch(r, false)
 	if err != nil {
-		httperr.Internal(w, r, err)
+		return err
 	}
+
+	if err = h.service.Activate(r.Context(), principal.Sub, versions); err != nil {
+		return serviceError(err)
+	}
+
+	return json.Write(w, r, http.StatusOK, json.Envelope{"message": "User activated successfully"}, nil)
 }
 
-func (h *Handler) activate(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) list(w http.ResponseWriter, r *http.Request) error {
+	q, err := page.Parse(r, listSpec, h.signer)
+	if err != nil {
+		return err
+	}
+
+	list, err := h.service.Page(r.Context(), q)
+	if err != nil {
+		return err
+	}
+
+	list, p := page.Build(q, list, h.signer)
+
+	return json.Write(w, r, http.StatusOK, json.Envelope{"users": list, "page": p}, p.Header(r))
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request) error {
 	userID := chi.URLParam(r, "userID")
 
 	principal := security.ContextGetPrincipal(r)
 
-	if userID != principal.Sub {
-		httperr.Forbidden(w, r)
+	if userID != principal.Sub && !strings.Contains(principal.Scopes, "user:view:all") {
+		return httperr.Forbidden()
+	}
+
+	if uuid.Validate(userID) != nil {
+		return httperr.NotFound()
 	}
 
-	err := h.service.Activate(r.Context(), principal.Sub)
+	u, err := h.service.Get(r.Context(), userID)
 	if err != nil {
-		httperr.Internal(w, r, err)
-		return
+		return serviceError(err)
 	}
 
-	err = json.WriteJSON(w, http.StatusOK, json.Envelope{"message": "User activated successfully"}, nil)
+	if etag.NotModified(w, r, etag.Version(u.UpdatedAt)) {
+		return nil
+	}
+
+	return json.Write(w, r, http.StatusOK, json.Envelope{"user": u}, nil)
+}
+
+// disable stands in for deletion, users are kept so their history stays consistent.
+func (h *Handler) disable(w http.ResponseWriter, r *http.Request) error {
+	userID := chi.URLParam(r, "userID")
+
+	if uuid.Validate(userID) != nil {
+		return httperr.NotFound()
+	}
+
+	versions, err := etag.IfMatch(r, true)
 	if err != nil {
-		httperr.Internal(w, r, err)
+		return err
+	}
+
+	if err = h.service.Disable(r.Context(), userID, versions); err != nil {
+		return serviceError(err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func serviceError(err error) error {
+	switch {
+	case errors.Is(err, errUserNotFound):
+		return httperr.NotFound()
+	case errors.Is(err, errUserModified):
+		return httperr.PreconditionFailed()
+	default:
+		return err
 	}
 }
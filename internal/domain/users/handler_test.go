@@ -3,29 +3,70 @@ package users
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+
+	"github.com/kiennyo/syncwatch-be/internal/config"
+	"github.com/kiennyo/syncwatch-be/internal/db/keyset"
+	"github.com/kiennyo/syncwatch-be/internal/http/etag"
+	"github.com/kiennyo/syncwatch-be/internal/http/page"
+	"github.com/kiennyo/syncwatch-be/internal/security"
 )
 
 type mockService struct {
 	mock.Mock
 }
 
-func (t *mockService) Activate(ctx context.Context, id string) error {
-	//TODO implement me
-	panic("implement me")
+func (t *mockService) Activate(ctx context.Context, id string, versions []time.Time) error {
+	args := t.Called(ctx, id, versions)
+	return args.Error(0)
+}
+
+func (t *mockService) SignUp(ctx context.Context, u *User) error {
+	args := t.Called(ctx, u)
+	return args.Error(0)
 }
 
-func (t *mockService) SignUp(ctx context.Context, u *user) error {
+func (t *mockService) Create(ctx context.Context, u *User) error {
 	args := t.Called(ctx, u)
 	return args.Error(0)
 }
 
+func (t *mockService) Get(ctx context.Context, id string) (*User, error) {
+	args := t.Called(ctx, id)
+	return args.Get(0).(*User), args.Error(1)
+}
+
+func (t *mockService) List(ctx context.Context) ([]*User, error) {
+	args := t.Called(ctx)
+	return args.Get(0).([]*User), args.Error(1)
+}
+
+func (t *mockService) Page(ctx context.Context, q *keyset.Query[*User]) ([]*User, error) {
+	args := t.Called(ctx, q)
+	return args.Get(0).([]*User), args.Error(1)
+}
+
+func (t *mockService) Disable(ctx context.Context, id string, versions []time.Time) error {
+	args := t.Called(ctx, id, versions)
+	return args.Error(0)
+}
+
+func (t *mockService) SetRole(ctx context.Context, id, role string) error {
+	args := t.Called(ctx, id, role)
+	return args.Error(0)
+}
+
 func TestHandler_SignUp(t *testing.T) {
 	type mocks struct {
 		service *mockService
@@ -42,13 +83,14 @@ func TestHandler_SignUp(t *testing.T) {
 		input          string
 		setup          func(m *mocks) *Handler
 		expectedStatus int
+		expectedCode   string
 	}{
 		{
 			name:  "ValidSignUp",
 			input: `{"name":"Test","email":"test@test.com","password":"pa$sw0rd"}`,
 			setup: func(m *mocks) *Handler {
 				m.service.On("SignUp", mock.Anything, mock.Anything).Return(nil)
-				return NewHandler(m.service)
+				return NewHandler(m.service, nil, nil)
 			},
 			expectedStatus: http.StatusCreated,
 		},
@@ -57,27 +99,40 @@ func TestHandler_SignUp(t *testing.T) {
 			input: `{"name":"Test","email":"test@test.com","password":""}`,
 			setup: func(m *mocks) *Handler {
 				m.service.On("SignUp", mock.Anything, mock.Anything).Return(nil)
-				return NewHandler(m.service)
+				return NewHandler(m.service, nil, nil)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   "validation_failed",
+		},
+		{
+			// 36 characters, 78 bytes: bcrypt would refuse it
+			name:  "MultibytePasswordTooLong",
+			input: `{"name":"Test","email":"test@test.com","password":"` + strings.Repeat("ą€€€1a", 6) + `"}`,
+			setup: func(m *mocks) *Handler {
+				return NewHandler(m.service, nil, nil)
 			},
 			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   "validation_failed",
 		},
 		{
 			name:  "DuplicateEmail",
 			input: `{"name":"Test","email":"test@test.com","password":"pa$sw0rd"}`,
 			setup: func(m *mocks) *Handler {
 				m.service.On("SignUp", mock.Anything, mock.Anything).Return(errDuplicateEmail)
-				return NewHandler(m.service)
+				return NewHandler(m.service, nil, nil)
 			},
-			expectedStatus: http.StatusUnprocessableEntity, // could make conflict in the future
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   codeDuplicateEmail,
 		},
 		{
 			name:  "SignUpError",
 			input: `{"name":"Test","email":"test@test.com","password":"pa$sw0rd"}`,
 			setup: func(m *mocks) *Handler {
 				m.service.On("SignUp", mock.Anything, mock.Anything).Return(errors.New("unknown error"))
-				return NewHandler(m.service)
+				return NewHandler(m.service, nil, nil)
 			},
 			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   "internal_error",
 		},
 	}
 
@@ -92,6 +147,198 @@ func TestHandler_SignUp(t *testing.T) {
 			server.ServeHTTP(response, request)
 
 			assert.Equal(t, test.expectedStatus, response.Code)
+			if test.expectedCode != "" {
+				assert.Contains(t, response.Body.String(), `"code":"`+test.expectedCode+`"`)
+			}
+		})
+	}
+}
+
+// versions matches the versions parsed from If-Match, which are in the local zone.
+func versions(want ...time.Time) any {
+	return mock.MatchedBy(func(got []time.Time) bool {
+		return slices.EqualFunc(got, want, time.Time.Equal)
+	})
+}
+
+//nolint:revive,function-length
+func TestHandler_ConditionalRequests(t *testing.T) {
+	const userID = "6f1c2a8e-4b7d-4e35-9c1a-2d8f0b3e7a51"
+
+	updatedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	current := etag.Version(updatedAt)
+	stale := etag.Version(updatedAt.Add(-time.Minute))
+
+	tokens := security.NewTokenFactory(config.Security{JWTSecret: "secret", Iss: "syncwatch.io", Aud: "syncwatch.io"})
+	token, _ := tokens.CreateToken(userID, []string{"user:view", "user:delete:all", "user:activate"}, security.Access)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		header         map[string]string
+		setup          func(s *mockService)
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:   "Get sets the ETag",
+			method: http.MethodGet,
+			path:   "/" + userID,
+			setup: func(s *mockService) {
+				s.On("Get", mock.Anything, userID).Return(&User{ID: uuid.MustParse(userID), UpdatedAt: updatedAt}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "Get not modified",
+			method: http.MethodGet,
+			path:   "/" + userID,
+			header: map[string]string{"If-None-Match": current},
+			setup: func(s *mockService) {
+				s.On("Get", mock.Anything, userID).Return(&User{ID: uuid.MustParse(userID), UpdatedAt: updatedAt}, nil)
+			},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			name:           "Disable requires If-Match",
+			method:         http.MethodDelete,
+			path:           "/" + userID,
+			expectedStatus: http.StatusPreconditionRequired,
+			expectedCode:   "precondition_required",
+		},
+		{
+			name:   "Disable stale version",
+			method: http.MethodDelete,
+			path:   "/" + userID,
+			header: map[string]string{"If-Match": stale},
+			setup: func(s *mockService) {
+				s.On("Disable", mock.Anything, userID, versions(updatedAt.Add(-time.Minute))).Return(errUserModified)
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedCode:   "precondition_failed",
+		},
+		{
+			name:   "Disable current version",
+			method: http.MethodDelete,
+			path:   "/" + userID,
+			header: map[string]string{"If-Match": current},
+			setup: func(s *mockService) {
+				s.On("Disable", mock.Anything, userID, versions(updatedAt)).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:   "Activate stale version",
+			method: http.MethodPatch,
+			path:   "/" + userID + "/activated",
+			header: map[string]string{"If-Match": stale},
+			setup: func(s *mockService) {
+				s.On("Activate", mock.Anything, userID, versions(updatedAt.Add(-time.Minute))).Return(errUserModified)
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedCode:   "precondition_failed",
+		},
+		{
+			name:   "Activate without If-Match",
+			method: http.MethodPatch,
+			path:   "/" + userID + "/activated",
+			setup: func(s *mockService) {
+				s.On("Activate", mock.Anything, userID, []time.Time(nil)).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := new(mockService)
+			if tc.setup != nil {
+				tc.setup(service)
+			}
+
+			auth := security.NewAuthMiddleware(tokens, config.Security{})
+			server := auth.Authenticate(NewHandler(service, nil, nil).Handlers())
+
+			request := httptest.NewRequest(tc.method, tc.path, nil)
+			request.Header.Set("Authorization", "Bearer "+token)
+			for name, value := range tc.header {
+				request.Header.Set(name, value)
+			}
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, request)
+
+			assert.Equal(t, tc.expectedStatus, response.Code)
+			if tc.expectedCode != "" {
+				assert.Contains(t, response.Body.String(), `"code":"`+tc.expectedCode+`"`)
+			}
+			if tc.method == http.MethodGet {
+				assert.Equal(t, current, response.Header().Get("ETag"))
+			}
+			service.AssertExpectations(t)
 		})
 	}
 }
+
+//nolint:revive,function-length
+func TestHandler_List(t *testing.T) {
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	list := []*User{
+		{ID: uuid.MustParse("0b6c3f0e-1111-4c1e-9d7a-1c5b0f3e2a01"), Name: "a", CreatedAt: created},
+		{ID: uuid.MustParse("0b6c3f0e-2222-4c1e-9d7a-1c5b0f3e2a02"), Name: "b", CreatedAt: created.Add(time.Minute)},
+		{ID: uuid.MustParse("0b6c3f0e-3333-4c1e-9d7a-1c5b0f3e2a03"), Name: "c", CreatedAt: created.Add(2 * time.Minute)},
+	}
+
+	tokens := security.NewTokenFactory(config.Security{JWTSecret: "secret", Iss: "syncwatch.io", Aud: "syncwatch.io"})
+	token, _ := tokens.CreateToken(list[0].ID.String(), []string{"user:list"}, security.Access)
+
+	service := new(mockService)
+	auth := security.NewAuthMiddleware(tokens, config.Security{})
+	server := auth.Authenticate(NewHandler(service, nil, keyset.NewSigner("secret")).Handlers())
+
+	send := func(target string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+
+		return response
+	}
+
+	// the repository fetches one user more than the limit
+	service.On("Page", mock.Anything, mock.MatchedBy(func(q *keyset.Query[*User]) bool {
+		return q.Limit == 2 && q.After == nil
+	})).Return(list, nil).Once()
+
+	response := send("/?limit=2")
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	var body struct {
+		Users []*User    `json:"users"`
+		Page  *page.Page `json:"page"`
+	}
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+	assert.Len(t, body.Users, 2)
+	assert.Equal(t, 2, body.Page.Limit)
+	assert.True(t, body.Page.HasMore)
+	assert.NotEmpty(t, body.Page.NextCursor)
+	assert.Equal(t, []string{
+		`</?limit=2>; rel="first"`,
+		`</?cursor=` + body.Page.NextCursor + `&limit=2>; rel="next"`,
+	}, response.Header().Values("Link"))
+
+	// the cursor continues after the last user of the page
+	service.On("Page", mock.Anything, mock.MatchedBy(func(q *keyset.Query[*User]) bool {
+		return q.Limit == 2 && len(q.After) == 2 && q.After[1] == list[1].ID.String()
+	})).Return(list[2:], nil).Once()
+
+	response = send("/?limit=2&cursor=" + body.Page.NextCursor)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Empty(t, response.Header().Values("Link")[1:], "the last page has no next link")
+
+	response = send("/?limit=2&cursor=forged")
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code)
+	assert.Contains(t, response.Body.String(), `"field":"cursor"`)
+
+	service.AssertExpectations(t)
+}
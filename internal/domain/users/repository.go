@@ -5,33 +5,39 @@ import (
 	"errors"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/kiennyo/syncwatch-be/internal/db"
+	"github.com/kiennyo/syncwatch-be/internal/db/keyset"
 )
 
 const userInactiveRole = "user-inactive"
 const userActiveRole = "user-active"
+const userDisabledRole = "user-disabled"
 
 type Repository interface {
-	Create(ctx context.Context, u *user) error
-	FindById(ctx context.Context, id string) (*user, error)
-	Activate(ctx context.Context, usr *user) error
+	Create(ctx context.Context, u *User) error
+	FindById(ctx context.Context, id string) (*User, error)
+	List(ctx context.Context) ([]*User, error)
+	Page(ctx context.Context, q *keyset.Query[*User]) ([]*User, error)
+	Activate(ctx context.Context, usr *User) error
+	SetRole(ctx context.Context, usr *User) error
 }
 
 type userRepository struct {
-	DB *pgxpool.Pool
+	DB db.Querier
 }
 
 var _ Repository = (*userRepository)(nil)
 
-func NewRepository(db *pgxpool.Pool) Repository {
-	return &userRepository{DB: db}
+func NewRepository(querier db.Querier) Repository {
+	return &userRepository{DB: querier}
 }
 
-func (r *userRepository) Create(ctx context.Context, u *user) error {
+func (r *userRepository) Create(ctx context.Context, u *User) error {
 	query := `
 		WITH user_insert AS (
-			INSERT INTO "user" (name, email, password_hash, updated_at, role_id)
-				VALUES (@name, @email, @password_hash, NOW(),
+			INSERT INTO "user" (name, email, language, password_hash, activated, updated_at, role_id)
+				VALUES (@name, @email, @language, @password_hash, @activated, NOW(),
 						(SELECT id FROM role WHERE slug = @role))
 				RETURNING id, created_at, role_id, updated_at)
 		SELECT user_insert.id, user_insert.created_at, user_insert.updated_at, JSON_AGG(permission.slug)
@@ -44,54 +50,51 @@ func (r *userRepository) Create(ctx context.Context, u *user) error {
 	args := pgx.NamedArgs{
 		"name":          u.Name,
 		"email":         u.Email,
+		"language":      u.Language,
 		"password_hash": u.Password.hash,
-		"role":          userInactiveRole,
+		"activated":     u.Activated,
+		"role":          u.Role,
 	}
 
 	err := r.DB.QueryRow(ctx, query, args).
 		Scan(&u.ID, &u.CreatedAt, &u.UpdatedAt, &u.Scopes)
 	if err != nil {
-		switch {
-		case err.Error() == `ERROR: duplicate key value violates unique constraint "user_email_key" (SQLSTATE 23505)`:
-			return errDuplicateEmail
-		default:
-			return err
-		}
+		return constraints.MapError(err)
 	}
 
 	return nil
 }
 
-func (r *userRepository) FindById(ctx context.Context, id string) (*user, error) {
-	u := user{}
+func (r *userRepository) FindById(ctx context.Context, id string) (*User, error) {
+	u := User{}
 	query := `
-		SELECT u.id, u.name, u.email, u.activated, u.created_at, u.updated_at, JSON_AGG(p.slug)
+		SELECT u.id, u.name, u.email, u.language, u.activated, r.slug, u.created_at, u.updated_at, JSON_AGG(p.slug)
 		FROM "user" u
 		INNER JOIN public.role r ON r.id = u.role_id
 		INNER JOIN public.role_permission rp ON r.id = rp.role_id
 		INNER JOIN public.permission p ON rp.permission_id = p.id
 		WHERE u.id = @id
-		GROUP BY u.updated_at, u.created_at, u.activated, u.email, u.name, u.id`
+		GROUP BY u.updated_at, u.created_at, u.activated, r.slug, u.language, u.email, u.name, u.id`
 
 	args := pgx.NamedArgs{
 		"id": id,
 	}
 
 	err := r.DB.QueryRow(ctx, query, args).
-		Scan(&u.ID, &u.Name, &u.Email, &u.Activated, &u.CreatedAt, &u.UpdatedAt, &u.Scopes)
+		Scan(&u.ID, &u.Name, &u.Email, &u.Language, &u.Activated, &u.Role, &u.CreatedAt, &u.UpdatedAt, &u.Scopes)
 	if err != nil {
 		switch {
 		case errors.Is(err, pgx.ErrNoRows):
 			return nil, errUserNotFound
 		default:
-			return nil, err
+			return nil, constraints.MapError(err)
 		}
 	}
 
 	return &u, nil
 }
 
-func (r *userRepository) Activate(ctx context.Context, usr *user) error {
+func (r *userRepository) Activate(ctx context.Context, usr *User) error {
 	query := `
 		UPDATE "user"
 		SET activated = @activated, 
@@ -111,11 +114,103 @@ func (r *userRepository) Activate(ctx context.Context, usr *user) error {
 	if err != nil {
 		switch {
 		case errors.Is(err, pgx.ErrNoRows):
-			return nil // treat as success
+			return errUserModified // changed since it was read
 		default:
-			return err
+			return constraints.MapError(err)
 		}
 	}
 
 	return nil
 }
+
+func (r *userRepository) List(ctx context.Context) ([]*User, error) {
+	query := `
+		SELECT u.id, u.name, u.email, u.language, u.activated, r.slug, u.created_at, u.updated_at,
+		       COALESCE(JSON_AGG(p.slug) FILTER (WHERE p.slug IS NOT NULL), '[]')
+		FROM "user" u
+		INNER JOIN public.role r ON r.id = u.role_id
+		LEFT JOIN public.role_permission rp ON r.id = rp.role_id
+		LEFT JOIN public.permission p ON rp.permission_id = p.id
+		GROUP BY u.updated_at, u.created_at, u.activated, r.slug, u.language, u.email, u.name, u.id
+		ORDER BY u.created_at, u.id`
+
+	rows, err := r.DB.Query(ctx, query)
+	if err != nil {
+		return nil, constraints.MapError(err)
+	}
+
+	list, err := pgx.CollectRows(rows, scanUser)
+
+	return list, constraints.MapError(err)
+}
+
+// Page filters before grouping, the columns of listSpec are all grouped by.
+func (r *userRepository) Page(ctx context.Context, q *keyset.Query[*User]) ([]*User, error) {
+	args := pgx.NamedArgs{}
+	query := `
+		SELECT u.id, u.name, u.email, u.language, u.activated, r.slug, u.created_at, u.updated_at,
+		       COALESCE(JSON_AGG(p.slug) FILTER (WHERE p.slug IS NOT NULL), '[]')
+		FROM "user" u
+		INNER JOIN public.role r ON r.id = u.role_id
+		LEFT JOIN public.role_permission rp ON r.id = rp.role_id
+		LEFT JOIN public.permission p ON rp.permission_id = p.id
+		WHERE ` + q.Where(args) + `
+		GROUP BY u.updated_at, u.created_at, u.activated, r.slug, u.language, u.email, u.name, u.id
+		ORDER BY ` + q.OrderBy() + `
+		LIMIT ` + q.LimitArg(args)
+
+	rows, err := r.DB.Query(ctx, query, args)
+	if err != nil {
+		return nil, constraints.MapError(err)
+	}
+
+	list, err := pgx.CollectRows(rows, scanUser)
+
+	return list, constraints.MapError(err)
+}
+
+func scanUser(row pgx.CollectableRow) (*User, error) {
+	u := User{}
+	err := row.Scan(&u.ID, &u.Name, &u.Email, &u.Language, &u.Activated, &u.Role, &u.CreatedAt, &u.UpdatedAt,
+		&u.Scopes)
+
+	return &u, err
+}
+
+func (r *userRepository) SetRole(ctx context.Context, usr *User) error {
+	query := `
+		UPDATE "user"
+		SET role_id = role.id,
+		    activated = @activated,
+		    updated_at = NOW()
+		FROM role
+		WHERE "user".id = @id AND "user".updated_at = @updated_at AND role.slug = @role
+		RETURNING "user".updated_at`
+
+	args := pgx.NamedArgs{
+		"id":         usr.ID,
+		"activated":  usr.Activated,
+		"updated_at": usr.UpdatedAt,
+		"role":       usr.Role,
+	}
+
+	err := r.DB.QueryRow(ctx, query, args).Scan(&usr.UpdatedAt)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return constraints.MapError(err)
+	}
+
+	// the user was found before, so either the role is missing or the user changed since
+	var roleExists bool
+	err = r.DB.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM role WHERE slug = @role)`, args).Scan(&roleExists)
+	switch {
+	case err != nil:
+		return constraints.MapError(err)
+	case !roleExists:
+		return errRoleNotFound
+	default:
+		return errUserModified
+	}
+}
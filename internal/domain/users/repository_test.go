@@ -3,6 +3,7 @@ package users
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -18,9 +19,10 @@ func TestUserRepository_Create(t *testing.T) {
 	assert.NotNil(t, container.DB)
 
 	repository := NewRepository(container.DB)
-	u := &user{
+	u := &User{
 		Name:   "John",
 		Email:  "test@test.lt",
+		Role:   userInactiveRole,
 		Scopes: nil,
 	}
 	err = u.Password.set("test")
@@ -42,9 +44,10 @@ func TestUserRepository_CreateUserWithExistingEmail(t *testing.T) {
 	assert.NotNil(t, container.DB)
 
 	repository := NewRepository(container.DB)
-	u := &user{
+	u := &User{
 		Name:   "John",
 		Email:  "test@test.com",
+		Role:   userInactiveRole,
 		Scopes: nil,
 	}
 	err = u.Password.set("test")
@@ -55,5 +58,43 @@ func TestUserRepository_CreateUserWithExistingEmail(t *testing.T) {
 	assert.Equal(t, []string{"user:activate"}, u.Scopes)
 
 	err = repository.Create(ctx, u)
-	assert.Equal(t, errDuplicateEmail, err)
+	assert.ErrorIs(t, err, errDuplicateEmail)
+}
+
+func TestUserRepository_Activate(t *testing.T) {
+	ctx := context.Background()
+	container, err := testhelpers.CreateTestDB(ctx)
+
+	assert.NotNil(t, container)
+	assert.Nil(t, err)
+	assert.NotNil(t, container.DB)
+
+	repository := NewRepository(container.DB)
+	u := &User{
+		Name:  "John",
+		Email: "activate@test.com",
+		Role:  userInactiveRole,
+	}
+	err = u.Password.set("test")
+	assert.Nil(t, err)
+
+	err = repository.Create(ctx, u)
+	assert.Nil(t, err)
+
+	read := u.UpdatedAt
+	u.Activated = true
+
+	// a version the user no longer has
+	u.UpdatedAt = read.Add(-time.Second)
+	err = repository.Activate(ctx, u)
+	assert.ErrorIs(t, err, errUserModified)
+
+	u.UpdatedAt = read
+	err = repository.Activate(ctx, u)
+	assert.Nil(t, err)
+	assert.True(t, u.UpdatedAt.After(read))
+
+	// activated users can't be activated again
+	err = repository.Activate(ctx, u)
+	assert.ErrorIs(t, err, errUserModified)
 }
@@ -1,36 +1,52 @@
 package users
 
 import (
+	"cmp"
 	"context"
-	"log/slog"
+	"errors"
+	"slices"
+	"time"
 
+	"github.com/kiennyo/syncwatch-be/internal/db"
+	"github.com/kiennyo/syncwatch-be/internal/db/keyset"
+	"github.com/kiennyo/syncwatch-be/internal/logger"
 	"github.com/kiennyo/syncwatch-be/internal/mail"
 	"github.com/kiennyo/syncwatch-be/internal/security"
 	"github.com/kiennyo/syncwatch-be/internal/worker"
 )
 
 type Service interface {
-	SignUp(ctx context.Context, u *user) error
-	Activate(ctx context.Context, id string) error
+	SignUp(ctx context.Context, u *User) error
+	Activate(ctx context.Context, id string, versions []time.Time) error
+	Create(ctx context.Context, u *User) error
+	Get(ctx context.Context, id string) (*User, error)
+	List(ctx context.Context) ([]*User, error)
+	Page(ctx context.Context, q *keyset.Query[*User]) ([]*User, error)
+	Disable(ctx context.Context, id string, versions []time.Time) error
+	SetRole(ctx context.Context, id, role string) error
 }
 
 type userService struct {
 	repository   Repository
+	transactor   db.Transactor
 	tokenCreator security.TokenCreator
 	mailer       mail.Sender
 }
 
 var _ Service = (*userService)(nil)
 
-func NewService(r Repository, t security.TokenCreator, m mail.Sender) Service {
+func NewService(r Repository, tx db.Transactor, t security.TokenCreator, m mail.Sender) Service {
 	return &userService{
 		repository:   r,
+		transactor:   tx,
 		tokenCreator: t,
 		mailer:       m,
 	}
 }
 
-func (s *userService) SignUp(ctx context.Context, u *user) error {
+func (s *userService) SignUp(ctx context.Context, u *User) error {
+	u.Role = userInactiveRole
+
 	err := s.repository.Create(ctx, u)
 	if err != nil {
 		return err
@@ -41,31 +57,100 @@ func (s *userService) SignUp(ctx context.Context, u *user) error {
 		return err
 	}
 
-	worker.Background(func() {
+	worker.Background(ctx, "users.sendActivationEmail", func(ctx context.Context) {
 		activationData := map[string]any{
 			"activationToken": token,
 		}
 
-		err = s.mailer.Send(u.Email, "user_welcome.gohtml", activationData)
-		if err != nil {
-			slog.Error("Failed to send activation email", "reason", err.Error())
+		err := s.mailer.Send(ctx, u.Email, u.Language, "user_welcome.gohtml", activationData)
+
+		var suppressed *mail.SuppressedError
+		switch {
+		case errors.As(err, &suppressed):
+			logger.FromContext(ctx).Warn("Activation email not sent", "reason", err.Error())
+		case err != nil:
+			logger.FromContext(ctx).Error("Failed to send activation email", "reason", err.Error())
 		}
 	})
 
 	return err
 }
 
-func (s *userService) Activate(ctx context.Context, id string) error {
-	usr, err := s.repository.FindById(ctx, id)
-	if err != nil {
-		return err
-	}
+// Activate is idempotent, activating an active user succeeds. Versions are the ones
+// the caller expects the user at, nil accepts any.
+func (s *userService) Activate(ctx context.Context, id string, versions []time.Time) error {
+	return s.transactor.InTx(ctx, func(ctx context.Context) error {
+		usr, err := s.repository.FindById(ctx, id)
+		if err != nil {
+			return err
+		}
 
-	usr.Activated = true
+		if err = checkVersion(usr, versions); err != nil {
+			return err
+		}
 
-	err = s.repository.Activate(ctx, usr)
-	if err != nil {
-		return err
+		if usr.Activated {
+			return nil
+		}
+
+		usr.Activated = true
+
+		return s.repository.Activate(ctx, usr)
+	})
+}
+
+// Create adds a user without the sign-up flow, no activation email is sent. Users
+// created with any role other than user-inactive are activated right away.
+func (s *userService) Create(ctx context.Context, u *User) error {
+	u.Role = cmp.Or(u.Role, userActiveRole)
+	u.Activated = u.Role != userInactiveRole
+
+	return s.repository.Create(ctx, u)
+}
+
+func (s *userService) Get(ctx context.Context, id string) (*User, error) {
+	return s.repository.FindById(ctx, id)
+}
+
+func (s *userService) List(ctx context.Context) ([]*User, error) {
+	return s.repository.List(ctx)
+}
+
+func (s *userService) Page(ctx context.Context, q *keyset.Query[*User]) ([]*User, error) {
+	return s.repository.Page(ctx, q)
+}
+
+func (s *userService) Disable(ctx context.Context, id string, versions []time.Time) error {
+	return s.setRole(ctx, id, userDisabledRole, versions)
+}
+
+func (s *userService) SetRole(ctx context.Context, id, role string) error {
+	return s.setRole(ctx, id, role, nil)
+}
+
+func (s *userService) setRole(ctx context.Context, id, role string, versions []time.Time) error {
+	return s.transactor.InTx(ctx, func(ctx context.Context) error {
+		usr, err := s.repository.FindById(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err = checkVersion(usr, versions); err != nil {
+			return err
+		}
+
+		usr.Role = role
+		usr.Activated = role != userInactiveRole && role != userDisabledRole
+
+		return s.repository.SetRole(ctx, usr)
+	})
+}
+
+// checkVersion fails when usr isn't at one of versions, the repositories check it
+// didn't change since it was read.
+func checkVersion(usr *User, versions []time.Time) error {
+	if versions != nil && !slices.ContainsFunc(versions, usr.UpdatedAt.Equal) {
+		return errUserModified
 	}
 
 	return nil
@@ -4,11 +4,13 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/kiennyo/syncwatch-be/internal/db/keyset"
 	"github.com/kiennyo/syncwatch-be/internal/security"
 	"github.com/kiennyo/syncwatch-be/internal/worker"
 )
@@ -18,21 +20,42 @@ type repositoryMock struct {
 	mock.Mock
 }
 
-func (r *repositoryMock) Create(ctx context.Context, u *user) error {
+func (r *repositoryMock) Create(ctx context.Context, u *User) error {
 	args := r.Called(ctx, u)
 	return args.Error(0)
 }
 
-func (r *repositoryMock) FindById(ctx context.Context, id string) (*user, error) {
+func (r *repositoryMock) FindById(ctx context.Context, id string) (*User, error) {
 	args := r.Called(ctx, id)
-	return args.Get(0).(*user), args.Error(0)
+	return args.Get(0).(*User), args.Error(1)
 }
 
-func (r *repositoryMock) Activate(ctx context.Context, u *user) error {
+func (r *repositoryMock) List(ctx context.Context) ([]*User, error) {
+	args := r.Called(ctx)
+	return args.Get(0).([]*User), args.Error(1)
+}
+
+func (r *repositoryMock) Page(ctx context.Context, q *keyset.Query[*User]) ([]*User, error) {
+	args := r.Called(ctx, q)
+	return args.Get(0).([]*User), args.Error(1)
+}
+
+func (r *repositoryMock) Activate(ctx context.Context, u *User) error {
 	args := r.Called(ctx, u)
 	return args.Error(0)
 }
 
+func (r *repositoryMock) SetRole(ctx context.Context, u *User) error {
+	args := r.Called(ctx, u)
+	return args.Error(0)
+}
+
+type transactorFake struct{}
+
+func (transactorFake) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
 type tokenCreatorMock struct {
 	mock.Mock
 }
@@ -46,8 +69,8 @@ type mailSenderMock struct {
 	mock.Mock
 }
 
-func (m *mailSenderMock) Send(recipient, templateFile string, data any) error {
-	args := m.Called(recipient, templateFile, data)
+func (m *mailSenderMock) Send(ctx context.Context, recipient, locale, templateFile string, data any) error {
+	args := m.Called(ctx, recipient, locale, templateFile, data)
 	return args.Error(0)
 }
 
@@ -72,7 +95,7 @@ func TestUserService_SignUp(t *testing.T) {
 	tt := []struct {
 		name    string
 		setup   func(m *mocks) Service
-		user    *user
+		user    *User
 		wantErr bool
 	}{
 		{
@@ -81,25 +104,26 @@ func TestUserService_SignUp(t *testing.T) {
 				m.repo.On("Create", mock.Anything, mock.Anything).Return(nil)
 				m.tokenCreator.On("CreateToken", uuid.Nil.String(), []string(nil), security.Activation).
 					Return("token", nil)
-				m.mailSender.On("Send", "email@test.com", "user_welcome.gohtml", map[string]any{
+				m.mailSender.On("Send", mock.Anything, "email@test.com", "lt", "user_welcome.gohtml", map[string]any{
 					"activationToken": "token",
 				}).Return(nil)
 
-				return NewService(m.repo, m.tokenCreator, m.mailSender)
+				return NewService(m.repo, transactorFake{}, m.tokenCreator, m.mailSender)
 			},
-			user: &user{
-				Email: "email@test.com",
+			user: &User{
+				Email:    "email@test.com",
+				Language: "lt",
 			},
 			wantErr: false,
 		},
 		{
 			name: "RepoError",
 			setup: func(m *mocks) Service {
-				m.repo.On("Create", ctx, &user{}).Return(errors.New("some error"))
+				m.repo.On("Create", ctx, &User{Role: userInactiveRole}).Return(errors.New("some error"))
 
-				return NewService(m.repo, m.tokenCreator, m.mailSender)
+				return NewService(m.repo, transactorFake{}, m.tokenCreator, m.mailSender)
 			},
-			user:    &user{},
+			user:    &User{},
 			wantErr: true,
 		},
 		{
@@ -109,9 +133,9 @@ func TestUserService_SignUp(t *testing.T) {
 				m.tokenCreator.On("CreateToken", uuid.Nil.String(), []string{"scope"}, security.Activation).
 					Return("", errors.New("some error"))
 
-				return NewService(m.repo, m.tokenCreator, m.mailSender)
+				return NewService(m.repo, transactorFake{}, m.tokenCreator, m.mailSender)
 			},
-			user: &user{
+			user: &User{
 				ID:     uuid.Nil,
 				Scopes: []string{"scope"},
 			},
@@ -140,3 +164,126 @@ func TestUserService_SignUp(t *testing.T) {
 		})
 	}
 }
+
+func TestUserService_SetRole(t *testing.T) {
+	ctx := context.Background()
+
+	tt := []struct {
+		name          string
+		role          string
+		findErr       error
+		wantActivated bool
+		wantErr       error
+	}{
+		{
+			name:          "Admin",
+			role:          "admin",
+			wantActivated: true,
+		},
+		{
+			name:          "Disabled",
+			role:          userDisabledRole,
+			wantActivated: false,
+		},
+		{
+			name:    "UserNotFound",
+			role:    "admin",
+			findErr: errUserNotFound,
+			wantErr: errUserNotFound,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(repositoryMock)
+			usr := &User{Activated: true, Role: userActiveRole}
+
+			repo.On("FindById", ctx, "id").Return(usr, tc.findErr)
+			if tc.findErr == nil {
+				repo.On("SetRole", ctx, usr).Return(nil)
+			}
+
+			err := NewService(repo, transactorFake{}, nil, nil).SetRole(ctx, "id", tc.role)
+
+			repo.AssertExpectations(t)
+			assert.ErrorIs(t, err, tc.wantErr)
+
+			if tc.wantErr == nil {
+				assert.Equal(t, tc.role, usr.Role)
+				assert.Equal(t, tc.wantActivated, usr.Activated)
+			}
+		})
+	}
+}
+
+func TestUserService_Activate(t *testing.T) {
+	ctx := context.Background()
+	version := time.Date(2024, 5, 29, 10, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		name       string
+		activated  bool
+		versions   []time.Time
+		wantUpdate bool
+		wantErr    error
+	}{
+		{
+			name:       "Unconditional",
+			wantUpdate: true,
+		},
+		{
+			name:       "MatchingVersion",
+			versions:   []time.Time{version.Add(-time.Hour), version},
+			wantUpdate: true,
+		},
+		{
+			name:     "StaleVersion",
+			versions: []time.Time{version.Add(-time.Hour)},
+			wantErr:  errUserModified,
+		},
+		{
+			name:     "NoVersionMatches",
+			versions: []time.Time{},
+			wantErr:  errUserModified,
+		},
+		{
+			name:      "AlreadyActivated",
+			activated: true,
+			versions:  []time.Time{version},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(repositoryMock)
+			usr := &User{Activated: tc.activated, UpdatedAt: version}
+
+			repo.On("FindById", ctx, "id").Return(usr, nil)
+			if tc.wantUpdate {
+				repo.On("Activate", ctx, usr).Return(nil)
+			}
+
+			err := NewService(repo, transactorFake{}, nil, nil).Activate(ctx, "id", tc.versions)
+
+			repo.AssertExpectations(t)
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestUserService_Create(t *testing.T) {
+	ctx := context.Background()
+	repo := new(repositoryMock)
+	repo.On("Create", ctx, mock.Anything).Return(nil)
+
+	sut := NewService(repo, transactorFake{}, nil, nil)
+
+	usr := &User{}
+	assert.NoError(t, sut.Create(ctx, usr))
+	assert.Equal(t, userActiveRole, usr.Role)
+	assert.True(t, usr.Activated)
+
+	usr = &User{Role: userInactiveRole}
+	assert.NoError(t, sut.Create(ctx, usr))
+	assert.False(t, usr.Activated)
+}
@@ -1,26 +1,60 @@
 package users
 
 import (
+	"reflect"
 	"regexp"
 	"unicode"
 
 	"github.com/kiennyo/syncwatch-be/internal/validator"
 )
 
-// nolint
-var emailRX = regexp.MustCompile(".+@.+\\..+")
+var languageRX = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
 
-func validateUserInput(v *validator.Validator, u *user) {
-	v.Check(u.Name != "", "name", "must be provided")
-	v.Check(len(u.Name) <= 500, "name", "must not be more than 500 bytes long")
+func init() {
+	validator.Register("language", func(field reflect.Value, _ string) *validator.Violation {
+		if !validator.Matches(field.String(), languageRX) {
+			return &validator.Violation{Code: "language_tag"}
+		}
+		return nil
+	})
+
+	validator.Register("password", func(field reflect.Value, _ string) *validator.Violation {
+		if !isValidPasswordComposition(field.String()) {
+			return &validator.Violation{Code: "password"}
+		}
+		return nil
+	})
+
+	validator.AddMessages("en", map[string]string{
+		"language_tag":     "must be a valid language tag",
+		"password":         "must be a valid password",
+		codeDuplicateEmail: "a user with this email address already exists",
+	})
+	validator.AddMessages("lt", map[string]string{
+		"language_tag":     "turi būti galiojanti kalbos žyma",
+		"password":         "turi būti tinkamas slaptažodis",
+		codeDuplicateEmail: "naudotojas su šiuo el. pašto adresu jau egzistuoja",
+	})
+}
 
-	v.Check(u.Email != "", "email", "must be provided")
-	v.Check(validator.Matches(u.Email, emailRX), "email", "must be a valid email address")
+// userInput holds the fields clients set with their rules. The password is checked in
+// bytes as bcrypt ignores anything past 72 of them.
+type userInput struct {
+	Name     string `json:"name" validate:"required,max=500"`
+	Email    string `json:"email" validate:"required,email"`
+	Language string `json:"language" validate:"language" doc:"BCP 47 language tag of emails, en when empty"`
+	Password string `json:"password" validate:"required,min=8,max=72,password" doc:"Must mix letters, numbers and symbols"`
+}
 
-	v.Check(*u.Password.plaintext != "", "password", "must be provided")
-	v.Check(len(*u.Password.plaintext) >= 8, "password", "must be at least 8 bytes long")
-	v.Check(len(*u.Password.plaintext) <= 72, "password", "must not be more than 72 bytes long")
-	v.Check(isValidPasswordComposition(*u.Password.plaintext), "password", "must be a valid password")
+// validateUserInput checks the user with the password in plain text, before it's
+// hashed: bcrypt fails on passwords the rules reject.
+func validateUserInput(v *validator.Validator, u *User, password string) error {
+	return v.Struct(userInput{
+		Name:     u.Name,
+		Email:    u.Email,
+		Language: u.Language,
+		Password: password,
+	})
 }
 
 func isValidPasswordComposition(password string) bool {
@@ -41,3 +75,9 @@ func isValidPasswordComposition(password string) bool {
 
 	return hasNumber && hasSpecialChar && hasLetter
 }
+
+// Validate checks the user and its password in plain text the same way sign-up does,
+// for callers outside the handler. Call it before SetPassword.
+func (u *User) Validate(v *validator.Validator, password string) error {
+	return validateUserInput(v, u, password)
+}
@@ -77,13 +77,13 @@ func TestIsValidPasswordComposition(t *testing.T) {
 func TestValidateUserInput(t *testing.T) {
 	cases := []struct {
 		name             string
-		user             *user
+		user             *User
 		password         string
 		validationErrors map[string]string
 	}{
 		{
 			name:     "Empty",
-			user:     &user{},
+			user:     &User{},
 			password: "",
 			validationErrors: map[string]string{
 				"name":     "must be provided",
@@ -93,7 +93,7 @@ func TestValidateUserInput(t *testing.T) {
 		},
 		{
 			name:     "ExceedingLength",
-			user:     &user{Name: strings.Repeat("a", 501), Email: "user@example.com"},
+			user:     &User{Name: strings.Repeat("a", 501), Email: "user@example.com"},
 			password: "pa$$w0rd",
 			validationErrors: map[string]string{
 				"name": "must not be more than 500 bytes long",
@@ -101,15 +101,23 @@ func TestValidateUserInput(t *testing.T) {
 		},
 		{
 			name:     "InvalidEmailFormat",
-			user:     &user{Name: "User", Email: "user@example"},
+			user:     &User{Name: "User", Email: "user@example"},
 			password: "pa$$w0rd",
 			validationErrors: map[string]string{
 				"email": "must be a valid email address",
 			},
 		},
+		{
+			name:     "InvalidLanguage",
+			user:     &User{Name: "User", Email: "user@example.com", Language: "english please"},
+			password: "pa$$w0rd",
+			validationErrors: map[string]string{
+				"language": "must be a valid language tag",
+			},
+		},
 		{
 			name:     "ShortPassword",
-			user:     &user{Name: "User", Email: "user@example.com"},
+			user:     &User{Name: "User", Email: "user@example.com"},
 			password: "1",
 			validationErrors: map[string]string{
 				"password": "must be at least 8 bytes long",
@@ -117,7 +125,7 @@ func TestValidateUserInput(t *testing.T) {
 		},
 		{
 			name:     "TooLongPassword",
-			user:     &user{Name: "User", Email: "user@example.com"},
+			user:     &User{Name: "User", Email: "user@example.com"},
 			password: strings.Repeat("pa$$w0rd00", 10),
 			validationErrors: map[string]string{
 				"password": "must not be more than 72 bytes long",
@@ -125,7 +133,7 @@ func TestValidateUserInput(t *testing.T) {
 		},
 		{
 			name:             "ValidInput",
-			user:             &user{Name: "User", Email: "user@example.com"},
+			user:             &User{Name: "User", Email: "user@example.com"},
 			password:         "pa$$w0rd",
 			validationErrors: map[string]string{},
 		},
@@ -134,8 +142,7 @@ func TestValidateUserInput(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			v := validator.New()
-			tc.user.Password = password{plaintext: &tc.password} //nolint
-			validateUserInput(v, tc.user)
+			assert.NoError(t, validateUserInput(v, tc.user, tc.password))
 			assert.Equal(t, tc.validationErrors, v.Errors())
 		})
 	}
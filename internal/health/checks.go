@@ -0,0 +1,84 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/kiennyo/syncwatch-be/internal/db"
+	"github.com/kiennyo/syncwatch-be/internal/worker"
+)
+
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type migrationStatus interface {
+	Status(ctx context.Context) (*db.MigrationStatus, error)
+}
+
+// Postgres pings the database through the pool.
+func Postgres(pool pinger) Check {
+	return Check{
+		Name: "postgres",
+		Run:  pool.Ping,
+	}
+}
+
+// SMTP checks the mail server accepts connections, without talking SMTP to it.
+func SMTP(host string, port int) Check {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+
+	return Check{
+		Name: "smtp",
+		Run: func(ctx context.Context) error {
+			var d net.Dialer
+
+			conn, err := d.DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return err
+			}
+
+			return conn.Close()
+		},
+	}
+}
+
+// Migrations fails until the database schema is at the version this build expects,
+// e.g. while another instance is still migrating.
+func Migrations(migrator migrationStatus) Check {
+	return Check{
+		Name: "migrations",
+		Run: func(ctx context.Context) error {
+			status, err := migrator.Status(ctx)
+			if err != nil {
+				return err
+			}
+
+			if status.Dirty {
+				return fmt.Errorf("version %d is dirty", status.Version)
+			}
+
+			if status.Version != status.Latest {
+				return fmt.Errorf("version %d, expected %d", status.Version, status.Latest)
+			}
+
+			return nil
+		},
+	}
+}
+
+// Backlog fails when more than limit background tasks are waiting to finish.
+func Backlog(limit int) Check {
+	return Check{
+		Name: "backlog",
+		Run: func(_ context.Context) error {
+			if pending := worker.Pending(); pending > limit {
+				return fmt.Errorf("%d background tasks pending, limit is %d", pending, limit)
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,159 @@
+// Package health serves the liveness and readiness endpoints used by orchestrators
+// and load balancers.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kiennyo/syncwatch-be/internal/http/json"
+	"github.com/kiennyo/syncwatch-be/internal/http/openapi"
+	"github.com/kiennyo/syncwatch-be/internal/logger"
+)
+
+const (
+	statusOK       = "ok"
+	statusFail     = "fail"
+	statusDraining = "draining"
+)
+
+// Check is a readiness dependency, Timeout overrides the default one of the Checker.
+type Check struct {
+	Name    string
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+// result is public, the reason of a failure is only logged as it may tell hosts, users
+// or driver details.
+type result struct {
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+}
+
+type Checker struct {
+	timeout  time.Duration
+	checks   []Check
+	draining atomic.Bool
+}
+
+func New(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{
+		timeout: timeout,
+		checks:  checks,
+	}
+}
+
+// Drain makes readiness fail from now on, so traffic moves to other instances
+// before the server stops accepting connections.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Live only tells the process is able to serve requests, it doesn't check any
+// dependency so an outage of one doesn't get every instance restarted.
+func (c *Checker) Live(w http.ResponseWriter, r *http.Request) {
+	writeStatus(w, r, http.StatusOK, json.Envelope{"status": statusOK})
+}
+
+// Operations documents Live and Ready, served at /healthz and /readyz.
+func (c *Checker) Operations() []openapi.Operation {
+	// checks are left out while draining
+	var status struct {
+		Status string            `json:"status" validate:"oneof=ok|fail|draining"`
+		Checks map[string]result `json:"checks,omitempty"`
+	}
+
+	tags := []string{"health"}
+
+	return []openapi.Operation{
+		{
+			Method: http.MethodGet, Path: "/healthz", ID: "live", Summary: "Liveness", Tags: tags,
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: json.Envelope{"status": statusOK}}},
+		},
+		{
+			Method: http.MethodGet, Path: "/readyz", ID: "ready", Summary: "Readiness", Tags: tags,
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: status},
+				{Status: http.StatusServiceUnavailable, Body: status, Description: "A check failed or the server is draining"},
+			},
+		},
+	}
+}
+
+// Ready runs all checks and reports each result, it fails while draining.
+func (c *Checker) Ready(w http.ResponseWriter, r *http.Request) {
+	if c.draining.Load() {
+		writeStatus(w, r, http.StatusServiceUnavailable, json.Envelope{"status": statusDraining})
+		return
+	}
+
+	results, ok := c.run(r.Context())
+
+	status, code := statusOK, http.StatusOK
+	if !ok {
+		status, code = statusFail, http.StatusServiceUnavailable
+	}
+
+	writeStatus(w, r, code, json.Envelope{"status": status, "checks": results})
+}
+
+// run executes the checks concurrently, each with its own timeout.
+func (c *Checker) run(ctx context.Context) (map[string]result, bool) {
+	results := make(map[string]result, len(c.checks))
+	ok := true
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, check := range c.checks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			res := c.runCheck(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			results[check.Name] = res
+			ok = ok && res.Status == statusOK
+		}()
+	}
+
+	wg.Wait()
+
+	return results, ok
+}
+
+func (c *Checker) runCheck(ctx context.Context, check Check) result {
+	timeout := check.Timeout
+	if timeout == 0 {
+		timeout = c.timeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Run(ctx)
+	res := result{Status: statusOK, Duration: time.Since(start).Round(time.Microsecond).String()}
+
+	if err != nil {
+		res.Status = statusFail
+		logger.FromContext(ctx).Error("Readiness check failed", "check", check.Name, "reason", err)
+	}
+
+	return res
+}
+
+func writeStatus(w http.ResponseWriter, r *http.Request, status int, data json.Envelope) {
+	// probes must never see a cached answer
+	headers := http.Header{"Cache-Control": []string{"no-store"}}
+
+	_ = json.Write(w, r, status, data, headers)
+}
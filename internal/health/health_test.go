@@ -0,0 +1,129 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiennyo/syncwatch-be/internal/db"
+	"github.com/kiennyo/syncwatch-be/internal/logger"
+)
+
+type response struct {
+	Status string            `json:"status"`
+	Checks map[string]result `json:"checks"`
+}
+
+func get(t *testing.T, handler http.HandlerFunc) (int, response) {
+	return getLogged(t, handler, slog.Default())
+}
+
+func getLogged(t *testing.T, handler http.HandlerFunc, l *slog.Logger) (int, response) {
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	req = req.WithContext(logger.NewContext(req.Context(), l))
+	res := httptest.NewRecorder()
+
+	handler(res, req)
+
+	var body response
+	assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+	assert.Equal(t, "no-store", res.Header().Get("Cache-Control"))
+
+	return res.Code, body
+}
+
+func TestChecker_Ready(t *testing.T) {
+	passing := Check{Name: "passing", Run: func(_ context.Context) error { return nil }}
+	failing := Check{Name: "failing", Run: func(_ context.Context) error { return errors.New("down") }}
+	slow := Check{Name: "slow", Timeout: 10 * time.Millisecond, Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	code, body := get(t, New(time.Second, passing).Ready)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", body.Status)
+	assert.Equal(t, "ok", body.Checks["passing"].Status)
+
+	var logs bytes.Buffer
+	code, body = getLogged(t, New(time.Second, passing, failing, slow).Ready, slog.New(slog.NewTextHandler(&logs, nil)))
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "fail", body.Status)
+	assert.Equal(t, "ok", body.Checks["passing"].Status)
+	assert.Equal(t, "fail", body.Checks["failing"].Status)
+	assert.Equal(t, "fail", body.Checks["slow"].Status)
+
+	// reasons are logged, the unauthenticated body only names the checks
+	assert.Contains(t, logs.String(), "check=failing reason=down")
+	assert.Contains(t, logs.String(), "check=slow reason=\"context deadline exceeded\"")
+}
+
+func TestChecker_ReadyHidesErrors(t *testing.T) {
+	failing := Check{Name: "db", Run: func(_ context.Context) error {
+		return errors.New("dial tcp db.internal:5432: password authentication failed for user \"syncwatch\"")
+	}}
+
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	res := httptest.NewRecorder()
+	New(time.Second, failing).Ready(res, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+	assert.NotContains(t, res.Body.String(), "db.internal")
+	assert.NotContains(t, res.Body.String(), "password")
+}
+
+func TestChecker_Drain(t *testing.T) {
+	checker := New(time.Second)
+
+	code, _ := get(t, checker.Ready)
+	assert.Equal(t, http.StatusOK, code)
+
+	checker.Drain()
+
+	code, body := get(t, checker.Ready)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "draining", body.Status)
+
+	// the process is still alive while draining
+	code, _ = get(t, checker.Live)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+type statusFake db.MigrationStatus
+
+func (s *statusFake) Status(_ context.Context) (*db.MigrationStatus, error) {
+	status := db.MigrationStatus(*s)
+	return &status, nil
+}
+
+func TestMigrations(t *testing.T) {
+	tests := []struct {
+		name   string
+		status statusFake
+		err    string
+	}{
+		{name: "Up to date", status: statusFake{Version: 3, Latest: 3}},
+		{name: "Pending", status: statusFake{Version: 2, Latest: 3}, err: "version 2, expected 3"},
+		{name: "Dirty", status: statusFake{Version: 3, Latest: 3, Dirty: true}, err: "version 3 is dirty"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Migrations(&tc.status).Run(context.Background())
+
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
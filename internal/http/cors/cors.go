@@ -0,0 +1,182 @@
+// Package cors lets browser clients on the configured origins call the API.
+package cors
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kiennyo/syncwatch-be/internal/config"
+	"github.com/kiennyo/syncwatch-be/internal/http/idempotency"
+	"github.com/kiennyo/syncwatch-be/internal/http/requestid"
+	"github.com/kiennyo/syncwatch-be/internal/http/version"
+	"github.com/kiennyo/syncwatch-be/internal/security"
+)
+
+var allowedMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// allowedHeaders are the request headers the API reads.
+var allowedHeaders = []string{
+	"Authorization", "Content-Type", security.CSRFHeader, requestid.Header, idempotency.Header,
+	"If-Match", "If-None-Match", version.Header,
+}
+
+// exposedHeaders are the response headers scripts need besides the safelisted ones.
+var exposedHeaders = []string{
+	requestid.Header, idempotency.ReplayedHeader, "ETag", security.CSRFHeader,
+	version.Header, version.DeprecationHeader, version.SunsetHeader,
+	"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After",
+}
+
+// origin is an allowed origin, a wildcard one matches any subdomain of host.
+type origin struct {
+	scheme   string
+	host     string
+	wildcard bool
+}
+
+type Policy struct {
+	origins     []origin
+	any         bool
+	headers     []string
+	credentials bool
+	maxAge      string
+}
+
+// New builds the policy of the configuration, the origins are validated by it already.
+func New(cfg config.CORS) *Policy {
+	p := &Policy{
+		credentials: cfg.AllowCredentials,
+		maxAge:      strconv.Itoa(int(cfg.MaxAge.Seconds())),
+	}
+
+	for _, h := range append(slices.Clone(allowedHeaders), cfg.ExtraHeaders...) {
+		p.headers = append(p.headers, http.CanonicalHeaderKey(h))
+	}
+
+	for _, o := range cfg.AllowedOrigins {
+		if o == "*" {
+			p.any = true
+			continue
+		}
+
+		u, err := url.Parse(strings.ToLower(o))
+		if err != nil {
+			continue
+		}
+
+		host, wildcard := strings.CutPrefix(u.Host, "*.")
+		p.origins = append(p.origins, origin{scheme: u.Scheme, host: host, wildcard: wildcard})
+	}
+
+	return p
+}
+
+func (p *Policy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin == "" || !p.allowed(origin) {
+			if preflight {
+				// no CORS headers, the browser fails the actual request
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if p.any && !p.credentials {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+
+		if p.credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			h.Set("Access-Control-Expose-Headers", strings.Join(exposedHeaders, ", "))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		method := r.Header.Get("Access-Control-Request-Method")
+		requested := requestedHeaders(r)
+		if slices.Contains(allowedMethods, method) && p.headersAllowed(requested) {
+			h.Set("Access-Control-Allow-Methods", method)
+			if len(requested) > 0 {
+				h.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+			}
+			h.Set("Access-Control-Max-Age", p.maxAge)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (p *Policy) allowed(raw string) bool {
+	if p.any {
+		return true
+	}
+
+	u, err := url.Parse(strings.ToLower(raw))
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	for _, o := range p.origins {
+		if o.scheme != u.Scheme {
+			continue
+		}
+
+		if u.Host == o.host && !o.wildcard {
+			return true
+		}
+
+		// a wildcard needs at least one label in front, *.syncwatch.io doesn't match syncwatch.io
+		if o.wildcard && strings.HasSuffix(u.Host, "."+o.host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Policy) headersAllowed(requested []string) bool {
+	for _, h := range requested {
+		if !slices.Contains(p.headers, h) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func requestedHeaders(r *http.Request) []string {
+	var headers []string
+
+	for _, value := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, h := range strings.Split(value, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				headers = append(headers, http.CanonicalHeaderKey(h))
+			}
+		}
+	}
+
+	return headers
+}
@@ -0,0 +1,154 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiennyo/syncwatch-be/internal/config"
+)
+
+func TestPolicy_Middleware(t *testing.T) {
+	cfg := config.CORS{
+		AllowedOrigins:   []string{"https://player.syncwatch.io", "https://*.preview.syncwatch.io"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		origin         string
+		requestMethod  string
+		requestHeaders string
+		wantStatus     int
+		wantHeaders    map[string]string
+	}{
+		{
+			name:       "Same origin",
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			name:       "Allowed origin",
+			method:     http.MethodGet,
+			origin:     "https://player.syncwatch.io",
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://player.syncwatch.io",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers": "X-Request-ID, Idempotent-Replayed, ETag, X-CSRF-Token, " +
+					"API-Version, Deprecation, Sunset, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Retry-After",
+			},
+		},
+		{
+			name:       "Wildcard subdomain",
+			method:     http.MethodGet,
+			origin:     "https://pr-42.preview.syncwatch.io",
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "https://pr-42.preview.syncwatch.io",
+			},
+		},
+		{
+			name:       "Wildcard doesn't match the bare domain",
+			method:     http.MethodGet,
+			origin:     "https://preview.syncwatch.io",
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			name:       "Scheme must match",
+			method:     http.MethodGet,
+			origin:     "http://player.syncwatch.io",
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			name:           "Preflight",
+			method:         http.MethodOptions,
+			origin:         "https://player.syncwatch.io",
+			requestMethod:  http.MethodPost,
+			requestHeaders: "content-type, x-csrf-token",
+			wantStatus:     http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "https://player.syncwatch.io",
+				"Access-Control-Allow-Methods": "POST",
+				"Access-Control-Allow-Headers": "Content-Type, X-Csrf-Token",
+				"Access-Control-Max-Age":       "600",
+			},
+		},
+		{
+			name:           "Preflight with unknown header",
+			method:         http.MethodOptions,
+			origin:         "https://player.syncwatch.io",
+			requestMethod:  http.MethodPost,
+			requestHeaders: "X-Custom",
+			wantStatus:     http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Methods": "",
+				"Access-Control-Allow-Headers": "",
+			},
+		},
+		{
+			name:          "Preflight from unknown origin",
+			method:        http.MethodOptions,
+			origin:        "https://evil.example",
+			requestMethod: http.MethodDelete,
+			wantStatus:    http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+
+			r := httptest.NewRequest(tt.method, "/users", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.requestMethod != "" {
+				r.Header.Set("Access-Control-Request-Method", tt.requestMethod)
+			}
+			if tt.requestHeaders != "" {
+				r.Header.Set("Access-Control-Request-Headers", tt.requestHeaders)
+			}
+
+			w := httptest.NewRecorder()
+			New(cfg).Middleware(next).ServeHTTP(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Contains(t, w.Header().Values("Vary"), "Origin")
+			for header, value := range tt.wantHeaders {
+				assert.Equal(t, value, w.Header().Get(header), header)
+			}
+		})
+	}
+}
+
+func TestPolicy_AnyOrigin(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Origin", "https://anywhere.example")
+
+	w := httptest.NewRecorder()
+	New(config.CORS{AllowedOrigins: []string{"*"}}).Middleware(next).ServeHTTP(w, r)
+
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+}
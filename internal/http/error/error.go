@@ -1,43 +1,228 @@
+// Package error renders errors as RFC 9457 problem details. Handlers return an *Error,
+// or any other error which is logged and rendered as an internal error, and Handle
+// renders it, so every failure reaches clients in the same shape.
 package error
 
 import (
+	"encoding/json"
 	"errors"
-	"log/slog"
 	"net/http"
+	"sort"
 
-	"github.com/kiennyo/syncwatch-be/internal/http/json"
+	httpjson "github.com/kiennyo/syncwatch-be/internal/http/json"
+	"github.com/kiennyo/syncwatch-be/internal/http/requestid"
+	"github.com/kiennyo/syncwatch-be/internal/logger"
+	"github.com/kiennyo/syncwatch-be/internal/validator"
 )
 
-func Response(w http.ResponseWriter, r *http.Request, status int, message any) {
-	env := json.Envelope{"error": message}
+const ContentType = "application/problem+json"
 
-	err := json.WriteJSON(w, status, env, nil)
-	if err != nil {
-		slog.Error(err.Error(), "request_method", r.Method, "request_url", r.URL.String())
-		w.WriteHeader(500)
+// typeBase prefixes the code to form the problem type URI.
+const typeBase = "https://syncwatch.io/problems/"
+
+// Codes are stable, clients switch on them, so released ones are never renamed.
+const (
+	CodeInternal             = "internal_error"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeValidation           = "validation_failed"
+	CodeMalformedRequest     = "malformed_request"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeNotAcceptable        = "not_acceptable"
+	CodePayloadTooLarge      = "payload_too_large"
+	CodeUnauthenticated      = "unauthenticated"
+	CodeInvalidToken         = "invalid_token"
+	CodeForbidden            = "forbidden"
+	CodeRateLimited          = "rate_limited"
+	CodePreconditionFailed   = "precondition_failed"
+	CodePreconditionRequired = "precondition_required"
+)
+
+// FieldError is a violation of a single request field. Detail is its message in the
+// locale negotiated from Accept-Language, Code and Params let clients word it themselves.
+type FieldError struct {
+	Field  string         `json:"field" doc:"Dotted path of the field, e.g. events.0.recipient"`
+	Code   string         `json:"code" doc:"Violated rule, for clients wording the message themselves"`
+	Params map[string]any `json:"params,omitempty" doc:"Parameters of the rule, e.g. max"`
+	Detail string         `json:"detail" doc:"Message in the language negotiated from Accept-Language"`
+
+	violation validator.Violation
+}
+
+// Error is a failure meant for the client. Err is the cause, it's only logged.
+type Error struct {
+	Status int
+	Code   string
+	Title  string
+	Detail string
+	Fields []FieldError
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Err.Error()
 	}
+
+	return e.Code + ": " + e.Detail
 }
 
-func Internal(w http.ResponseWriter, r *http.Request, err error) {
-	slog.Error(err.Error(), "request_method", r.Method, "request_url", r.URL.String())
+func (e *Error) Unwrap() error {
+	return e.Err
+}
 
-	message := "the server encountered a problem and could not process your request"
-	Response(w, r, http.StatusInternalServerError, message)
+// Problem is the RFC 9457 body, with the code and field violations as extensions.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty" doc:"Request ID, quote it when reporting a problem"`
+	Code     string       `json:"code" doc:"Stable error code to switch on"`
+	Errors   []FieldError `json:"errors,omitempty" doc:"Violations of request fields"`
 }
 
-func Validation(w http.ResponseWriter, r *http.Request, errs map[string]string) {
-	Response(w, r, http.StatusUnprocessableEntity, errs)
+func New(status int, code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
 }
 
-func Forbidden(w http.ResponseWriter, r *http.Request) {
-	Response(w, r, http.StatusForbidden, "Forbidden")
+func NotFound() *Error {
+	return New(http.StatusNotFound, CodeNotFound, "the requested resource could not be found")
 }
 
-func InvalidJSON(w http.ResponseWriter, r *http.Request, err error) {
-	var mr *json.MalformedRequest
-	if errors.As(err, &mr) {
-		Response(w, r, mr.Status, mr.Msg)
-	} else {
-		Internal(w, r, err)
+func Forbidden() *Error {
+	return New(http.StatusForbidden, CodeForbidden, "you are not allowed to access this resource")
+}
+
+func RateLimited() *Error {
+	return New(http.StatusTooManyRequests, CodeRateLimited, "rate limit exceeded, retry later")
+}
+
+func PreconditionFailed() *Error {
+	return New(http.StatusPreconditionFailed, CodePreconditionFailed,
+		"the resource was modified, fetch it again and retry with its current ETag")
+}
+
+func PreconditionRequired() *Error {
+	return New(http.StatusPreconditionRequired, CodePreconditionRequired,
+		"this request must be conditional, send the ETag of the resource in If-Match")
+}
+
+// Validation reports field violations, ordered by field.
+func Validation(violations map[string]validator.Violation) *Error {
+	e := New(http.StatusUnprocessableEntity, CodeValidation, "the request contains invalid fields")
+	return e.WithFields(violations)
+}
+
+// WithFields adds field violations, ordered by field.
+func (e *Error) WithFields(violations map[string]validator.Violation) *Error {
+	for field, violation := range violations {
+		e.Fields = append(e.Fields, FieldError{
+			Field:     field,
+			Code:      violation.Code,
+			Params:    violation.Params,
+			violation: violation,
+		})
+	}
+
+	sort.Slice(e.Fields, func(i, j int) bool { return e.Fields[i].Field < e.Fields[j].Field })
+
+	return e
+}
+
+// HandlerFunc is a handler that returns its failure for Handle to render.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+func Handle(fn HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := fn(w, r); err != nil {
+			Render(w, r, err)
+		}
 	}
 }
+
+// Render writes err as a problem. Errors other than *Error and malformed request bodies
+// are logged and hidden behind an internal error.
+func Render(w http.ResponseWriter, r *http.Request, err error) {
+	var e *Error
+	var mr *httpjson.MalformedRequest
+
+	switch {
+	case errors.As(err, &e):
+		if e.Err != nil {
+			logger.FromContext(r.Context()).Warn(e.Error(), "request_method", r.Method, "request_url", r.URL.String())
+		}
+	case errors.As(err, &mr):
+		e = malformed(mr)
+	default:
+		logger.FromContext(r.Context()).Error(err.Error(), "request_method", r.Method, "request_url", r.URL.String())
+		e = New(http.StatusInternalServerError, CodeInternal,
+			"the server encountered a problem and could not process your request")
+	}
+
+	write(w, r, e)
+}
+
+func malformed(mr *httpjson.MalformedRequest) *Error {
+	switch mr.Status {
+	case http.StatusUnsupportedMediaType:
+		return New(mr.Status, CodeUnsupportedMediaType, mr.Msg)
+	case http.StatusRequestEntityTooLarge:
+		return New(mr.Status, CodePayloadTooLarge, mr.Msg)
+	case http.StatusNotAcceptable:
+		return New(mr.Status, CodeNotAcceptable, mr.Msg)
+	default:
+		return New(mr.Status, CodeMalformedRequest, mr.Msg)
+	}
+}
+
+func write(w http.ResponseWriter, r *http.Request, e *Error) {
+	p := Problem{
+		Type:     typeBase + e.Code,
+		Title:    e.Title,
+		Status:   e.Status,
+		Detail:   e.Detail,
+		Instance: requestid.FromContext(r.Context()),
+		Code:     e.Code,
+	}
+
+	if len(e.Fields) > 0 {
+		locale := validator.Locale(r.Header.Get("Accept-Language"))
+		w.Header().Set("Content-Language", locale)
+		w.Header().Add("Vary", "Accept-Language")
+
+		p.Errors = make([]FieldError, len(e.Fields))
+		for i, f := range e.Fields {
+			f.Detail = validator.Translate(locale, f.violation)
+			p.Errors[i] = f
+		}
+	}
+
+	if p.Title == "" {
+		p.Title = http.StatusText(e.Status)
+	}
+
+	js, err := json.Marshal(p)
+	if err != nil {
+		logger.FromContext(r.Context()).Error(err.Error(), "request_method", r.Method, "request_url", r.URL.String())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(e.Status)
+
+	if _, err = w.Write(js); err != nil {
+		logger.FromContext(r.Context()).Error("Write failed", "reason", err)
+	}
+}
+
+// NotFoundHandler and MethodNotAllowedHandler replace the plain text router defaults.
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	Render(w, r, NotFound())
+}
+
+func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	Render(w, r, New(http.StatusMethodNotAllowed, CodeMethodNotAllowed,
+		"the "+r.Method+" method is not supported for this resource"))
+}
@@ -0,0 +1,147 @@
+package error
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	httpjson "github.com/kiennyo/syncwatch-be/internal/http/json"
+	"github.com/kiennyo/syncwatch-be/internal/http/requestid"
+	"github.com/kiennyo/syncwatch-be/internal/validator"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Problem
+	}{
+		{
+			name: "Typed error",
+			err:  New(http.StatusConflict, "recipient_suppressed", "the recipient is suppressed"),
+			want: Problem{
+				Type:   "https://syncwatch.io/problems/recipient_suppressed",
+				Title:  "Conflict",
+				Status: http.StatusConflict,
+				Detail: "the recipient is suppressed",
+				Code:   "recipient_suppressed",
+			},
+		},
+		{
+			name: "Wrapped typed error",
+			err:  fmt.Errorf("activating: %w", NotFound()),
+			want: Problem{
+				Type:   "https://syncwatch.io/problems/not_found",
+				Title:  "Not Found",
+				Status: http.StatusNotFound,
+				Detail: "the requested resource could not be found",
+				Code:   CodeNotFound,
+			},
+		},
+		{
+			name: "Field violations",
+			err: Validation(map[string]validator.Violation{
+				"password": {Code: "min_length", Params: map[string]any{"min": 8}},
+				"email":    {Code: "required"},
+			}),
+			want: Problem{
+				Type:   "https://syncwatch.io/problems/validation_failed",
+				Title:  "Unprocessable Entity",
+				Status: http.StatusUnprocessableEntity,
+				Detail: "the request contains invalid fields",
+				Code:   CodeValidation,
+				Errors: []FieldError{
+					{Field: "email", Code: "required", Detail: "must be provided"},
+					{Field: "password", Code: "min_length", Params: map[string]any{"min": float64(8)},
+						Detail: "must be at least 8 bytes long"},
+				},
+			},
+		},
+		{
+			name: "Malformed request",
+			err:  &httpjson.MalformedRequest{Status: http.StatusUnsupportedMediaType, Msg: "not JSON"},
+			want: Problem{
+				Type:   "https://syncwatch.io/problems/unsupported_media_type",
+				Title:  "Unsupported Media Type",
+				Status: http.StatusUnsupportedMediaType,
+				Detail: "not JSON",
+				Code:   CodeUnsupportedMediaType,
+			},
+		},
+		{
+			name: "Unknown error is hidden",
+			err:  errors.New("connection refused"),
+			want: Problem{
+				Type:   "https://syncwatch.io/problems/internal_error",
+				Title:  "Internal Server Error",
+				Status: http.StatusInternalServerError,
+				Detail: "the server encountered a problem and could not process your request",
+				Code:   CodeInternal,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r *http.Request
+			requestid.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+				r = req
+			})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			w := httptest.NewRecorder()
+			Render(w, r, tt.err)
+
+			var got Problem
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+
+			tt.want.Instance = requestid.FromContext(r.Context())
+			assert.NotEmpty(t, tt.want.Instance)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want.Status, w.Code)
+			assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestRender_LocalizedFields(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("Accept-Language", "lt-LT, en;q=0.5")
+	w := httptest.NewRecorder()
+
+	Render(w, r, Validation(map[string]validator.Violation{
+		"name":  {Code: "required"},
+		"email": {Code: validator.CodeInvalid, Message: "is taken"},
+	}))
+
+	var got Problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+
+	assert.Equal(t, []FieldError{
+		{Field: "email", Code: validator.CodeInvalid, Detail: "is taken"},
+		{Field: "name", Code: "required", Detail: "privalomas laukas"},
+	}, got.Errors)
+	assert.Equal(t, "lt", w.Header().Get("Content-Language"))
+	assert.Contains(t, w.Header().Values("Vary"), "Accept-Language")
+}
+
+func TestHandle(t *testing.T) {
+	handler := Handle(func(w http.ResponseWriter, _ *http.Request) error {
+		w.Header().Set("Retry-After", "30")
+		return RateLimited()
+	})
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/", nil)
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), `"code":"rate_limited"`)
+	assert.NotContains(t, w.Body.String(), "instance", "no request ID outside of the middleware")
+}
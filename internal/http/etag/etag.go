@@ -0,0 +1,99 @@
+// Package etag implements conditional requests on top of the updated_at versions of
+// resources. The ETag of a resource is its version, so If-Match can be checked by the
+// optimistic concurrency checks of repositories rather than by comparing bodies.
+package etag
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	httperr "github.com/kiennyo/syncwatch-be/internal/http/error"
+)
+
+// Version is the strong ETag of the resource version updated at t. The updated_at
+// columns behind ETags are TIMESTAMP(6), so versions round trip in microseconds and
+// changes within the same second get tags of their own.
+func Version(t time.Time) string {
+	return `"` + strconv.FormatInt(t.UnixMicro(), 36) + `"`
+}
+
+func parseVersion(tag string) (time.Time, bool) {
+	raw, ok := strings.CutPrefix(tag, `"`)
+	if !ok {
+		return time.Time{}, false // weak tags never match If-Match
+	}
+
+	raw, ok = strings.CutSuffix(raw, `"`)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	micro, err := strconv.ParseInt(raw, 36, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.UnixMicro(micro), true
+}
+
+// NotModified sets the ETag header and, when If-None-Match of a GET or HEAD names it,
+// answers 304. Handlers return without writing a body when it reports true.
+func NotModified(w http.ResponseWriter, r *http.Request, tag string) bool {
+	w.Header().Set("ETag", tag)
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, candidate := range split(header) {
+		// weak comparison, a weak tag matches its strong counterpart
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+
+	return false
+}
+
+// IfMatch returns the versions If-Match accepts, nil when any version does. Tags which
+// aren't versions are dropped as they can't match, so the result is empty rather than
+// nil when none is. A missing header is a 428 error when required.
+func IfMatch(r *http.Request, required bool) ([]time.Time, error) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		if required {
+			return nil, httperr.PreconditionRequired()
+		}
+		return nil, nil
+	}
+
+	versions := make([]time.Time, 0, 1)
+	for _, candidate := range split(header) {
+		if candidate == "*" {
+			return nil, nil
+		}
+
+		if v, ok := parseVersion(candidate); ok {
+			versions = append(versions, v)
+		}
+	}
+
+	return versions, nil
+}
+
+func split(header string) []string {
+	parts := strings.Split(header, ",")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+
+	return parts
+}
@@ -0,0 +1,92 @@
+package etag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	httperr "github.com/kiennyo/syncwatch-be/internal/http/error"
+)
+
+var version = time.Date(2024, 5, 29, 10, 0, 0, 123456000, time.UTC)
+
+func TestNotModified(t *testing.T) {
+	tag := Version(version)
+
+	tests := []struct {
+		name        string
+		method      string
+		ifNoneMatch string
+		want        bool
+	}{
+		{name: "No header", method: http.MethodGet},
+		{name: "Same version", method: http.MethodGet, ifNoneMatch: tag, want: true},
+		{name: "Weak match", method: http.MethodHead, ifNoneMatch: `"x", W/` + tag, want: true},
+		{name: "Any", method: http.MethodGet, ifNoneMatch: "*", want: true},
+		{name: "Other version", method: http.MethodGet, ifNoneMatch: Version(version.Add(time.Second))},
+		{name: "Unsafe method", method: http.MethodPatch, ifNoneMatch: tag},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/users/1", nil)
+			if tt.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+
+			got := NotModified(w, r, tag)
+
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tag, w.Header().Get("ETag"))
+			if tt.want {
+				assert.Equal(t, http.StatusNotModified, w.Code)
+			}
+		})
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		ifMatch  string
+		required bool
+		want     []time.Time
+		wantCode string
+	}{
+		{name: "Optional"},
+		{name: "Required", required: true, wantCode: httperr.CodePreconditionRequired},
+		{name: "Any", ifMatch: "*", required: true},
+		{name: "Versions", ifMatch: Version(version) + `, "v-1"`, want: []time.Time{version}},
+		{name: "Weak tags never match", ifMatch: "W/" + Version(version), want: []time.Time{}},
+		{name: "Not a version", ifMatch: `"abc def"`, want: []time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodDelete, "/users/1", nil)
+			if tt.ifMatch != "" {
+				r.Header.Set("If-Match", tt.ifMatch)
+			}
+
+			got, err := IfMatch(r, tt.required)
+
+			if tt.wantCode != "" {
+				var e *httperr.Error
+				require.ErrorAs(t, err, &e)
+				assert.Equal(t, tt.wantCode, e.Code)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, got, len(tt.want))
+			for i := range tt.want {
+				assert.True(t, tt.want[i].Equal(got[i]))
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/kiennyo/syncwatch-be/internal/config"
+)
+
+// securityHeaders hardens browsers against the responses being framed, sniffed into
+// another content type or leaking the URL. Browsers ignore HSTS over plain HTTP, so it's
+// sent regardless of whether TLS terminates here or at a proxy.
+func securityHeaders(cfg config.HTTP) func(http.Handler) http.Handler {
+	hsts := ""
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge.Seconds()))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("X-Content-Type-Options", "nosniff")
+			h.Set("X-Frame-Options", "DENY")
+			h.Set("Referrer-Policy", "no-referrer")
+
+			if cfg.CSP != "" {
+				h.Set("Content-Security-Policy", cfg.CSP)
+			}
+
+			if hsts != "" {
+				h.Set("Strict-Transport-Security", hsts)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
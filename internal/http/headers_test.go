@@ -0,0 +1,37 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiennyo/syncwatch-be/internal/config"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+	t.Run("Configured", func(t *testing.T) {
+		cfg := config.HTTP{HSTSMaxAge: 365 * 24 * time.Hour, CSP: "default-src 'none'"}
+
+		w := httptest.NewRecorder()
+		securityHeaders(cfg)(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+		assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+		assert.Equal(t, "no-referrer", w.Header().Get("Referrer-Policy"))
+		assert.Equal(t, "default-src 'none'", w.Header().Get("Content-Security-Policy"))
+		assert.Equal(t, "max-age=31536000", w.Header().Get("Strict-Transport-Security"))
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		securityHeaders(config.HTTP{})(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Empty(t, w.Header().Get("Content-Security-Policy"))
+		assert.Empty(t, w.Header().Get("Strict-Transport-Security"))
+	})
+}
@@ -0,0 +1,270 @@
+// Package idempotency makes retries of unsafe requests safe. Clients send an
+// Idempotency-Key header, the first response to a key is stored and replayed to the
+// requests repeating it, so a retried sign up gets its 201 rather than a duplicate
+// email error.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/kiennyo/syncwatch-be/internal/config"
+	httperr "github.com/kiennyo/syncwatch-be/internal/http/error"
+	"github.com/kiennyo/syncwatch-be/internal/logger"
+	"github.com/kiennyo/syncwatch-be/internal/ratelimit"
+	"github.com/kiennyo/syncwatch-be/internal/security"
+)
+
+const (
+	Header         = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed"
+)
+
+const (
+	codeInvalidKey = "invalid_idempotency_key"
+	codeKeyReused  = "idempotency_key_reused"
+	codeInProgress = "idempotency_request_in_progress"
+)
+
+const (
+	maxKeyLength  = 255
+	maxBodySize   = 1048576
+	pollInterval  = 100 * time.Millisecond
+	retryAfterSec = 1
+)
+
+// unstored headers belong to the request they were written for.
+var unstored = []string{
+	"Set-Cookie", "X-Request-Id", "Date",
+	"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After",
+}
+
+var requests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "syncwatch",
+	Subsystem: "idempotency",
+	Name:      "requests_total",
+	Help:      "Requests with an Idempotency-Key by result: stored, replayed, conflict, mismatch or failed.",
+}, []string{"result"})
+
+type Middleware struct {
+	store       Store
+	ttl         time.Duration
+	lockTimeout time.Duration
+	wait        time.Duration
+	now         func() time.Time
+	principal   func(r *http.Request) string
+	proxies     []netip.Prefix
+}
+
+// New builds the middleware, proxies are the trusted ones telling the address of
+// anonymous clients.
+func New(cfg config.Idempotency, store Store, proxies []netip.Prefix) *Middleware {
+	return &Middleware{
+		store:       store,
+		ttl:         cfg.TTL,
+		lockTimeout: cfg.LockTimeout,
+		wait:        cfg.Wait,
+		now:         time.Now,
+		principal:   func(r *http.Request) string { return security.ContextGetPrincipal(r).Sub },
+		proxies:     proxies,
+	}
+}
+
+// Handler applies to POST and PATCH requests with the header, the other methods are
+// idempotent already. It needs the principal, so it runs after authentication.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, ok := r.Header[Header]
+		if !ok || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(raw) != 1 || raw[0] == "" || len(raw[0]) > maxKeyLength {
+			httperr.Render(w, r, httperr.New(http.StatusBadRequest, codeInvalidKey,
+				"the Idempotency-Key header must be a single value of 1 to 255 characters"))
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+		if err != nil {
+			httperr.Render(w, r, readError(err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		k := Key{Principal: m.owner(r), Route: r.Method + " " + r.URL.Path, Key: raw[0]}
+
+		if err = m.serve(w, r, next, k, fingerprint(r, body)); err != nil {
+			requests.WithLabelValues(result(err)).Inc()
+			httperr.Render(w, r, err)
+		}
+	})
+}
+
+// owner scopes the keys of a client, anonymous clients by their address so one can't
+// replay the response stored for another, e.g. the user signed up.
+func (m *Middleware) owner(r *http.Request) string {
+	if sub := m.principal(r); sub != "" {
+		return sub
+	}
+
+	return "ip:" + ratelimit.ClientIP(r, m.proxies).String()
+}
+
+func (m *Middleware) serve(w http.ResponseWriter, r *http.Request, next http.Handler, k Key, fp []byte) error {
+	deadline := m.now().Add(m.wait)
+
+	for {
+		rec, err := m.store.Lock(r.Context(), k, fp, m.now(), m.ttl, m.lockTimeout)
+		switch {
+		case err != nil:
+			return err
+		case rec == nil:
+			m.record(w, r, next, k)
+			return nil
+		case !bytes.Equal(rec.Fingerprint, fp):
+			return httperr.New(http.StatusUnprocessableEntity, codeKeyReused,
+				"the Idempotency-Key was used for a different request")
+		case rec.Response != nil:
+			requests.WithLabelValues("replayed").Inc()
+			replay(w, rec.Response)
+			return nil
+		}
+
+		if !m.now().Before(deadline) {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSec))
+			return httperr.New(http.StatusConflict, codeInProgress,
+				"a request with the same Idempotency-Key is still being processed")
+		}
+
+		select {
+		case <-r.Context().Done():
+			return r.Context().Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// record runs the request and stores its response. Server errors and rate limited
+// requests were not processed, their key is released so the client can retry, which is
+// also done when next panics.
+func (m *Middleware) record(w http.ResponseWriter, r *http.Request, next http.Handler, k Key) {
+	rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+	stored := false
+
+	defer func() {
+		if stored {
+			return
+		}
+
+		// the request may be canceled, the claim must go anyway
+		if err := m.store.Release(context.WithoutCancel(r.Context()), k); err != nil {
+			logger.FromContext(r.Context()).Error("Failed to release idempotency key", "reason", err.Error())
+		}
+	}()
+
+	next.ServeHTTP(rec, r)
+
+	if rec.status >= http.StatusInternalServerError || rec.status == http.StatusTooManyRequests {
+		requests.WithLabelValues("failed").Inc()
+		return
+	}
+
+	header := w.Header().Clone()
+	for _, name := range unstored {
+		header.Del(name)
+	}
+
+	resp := &Response{Status: rec.status, Header: header, Body: rec.body.Bytes()}
+	if err := m.store.Save(context.WithoutCancel(r.Context()), k, resp); err != nil {
+		logger.FromContext(r.Context()).Error("Failed to store idempotent response", "reason", err.Error())
+		return
+	}
+
+	stored = true
+	requests.WithLabelValues("stored").Inc()
+}
+
+func replay(w http.ResponseWriter, resp *Response) {
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write(resp.Body)
+}
+
+// fingerprint tells apart requests reusing a key, the route is part of the key already.
+func fingerprint(r *http.Request, body []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(r.Header.Get("Content-Type")))
+	h.Write([]byte{0})
+	h.Write(body)
+
+	return h.Sum(nil)
+}
+
+// readError reports a body that couldn't be read, too large or cut off by the client.
+func readError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return httperr.New(http.StatusRequestEntityTooLarge, httperr.CodePayloadTooLarge,
+			"body must not be larger than 1MB")
+	}
+
+	return httperr.New(http.StatusBadRequest, httperr.CodeMalformedRequest, "the request body could not be read")
+}
+
+func result(err error) string {
+	var e *httperr.Error
+	if errors.As(err, &e) {
+		switch e.Code {
+		case codeKeyReused:
+			return "mismatch"
+		case codeInProgress:
+			return "conflict"
+		}
+	}
+
+	return "failed"
+}
+
+// recorder copies the response as it's written.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(b)
+
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *recorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,200 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiennyo/syncwatch-be/internal/config"
+)
+
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[Key]*Record
+}
+
+func (s *memoryStore) Lock(_ context.Context, k Key, fp []byte, _ time.Time, _, _ time.Duration) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec, ok := s.records[k]; ok {
+		return rec, nil
+	}
+
+	s.records[k] = &Record{Fingerprint: fp}
+	return nil, nil
+}
+
+func (s *memoryStore) Save(_ context.Context, k Key, resp *Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[k].Response = resp
+	return nil
+}
+
+func (s *memoryStore) Release(_ context.Context, k Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, k)
+	return nil
+}
+
+func newTestMiddleware() (*Middleware, *memoryStore) {
+	store := &memoryStore{records: make(map[Key]*Record)}
+
+	m := New(config.Idempotency{TTL: time.Hour, LockTimeout: time.Minute}, store, nil)
+	m.principal = func(*http.Request) string { return "user-1" }
+
+	return m, store
+}
+
+func send(h http.Handler, method, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/users", strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(Header, key)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestMiddleware_Handler(t *testing.T) {
+	calls := 0
+	status := http.StatusCreated
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.Header().Set("Location", "/users/1")
+		w.Header().Set("X-Request-Id", "request-1")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"id":1}`))
+	})
+
+	t.Run("Replays the first response", func(t *testing.T) {
+		m, _ := newTestMiddleware()
+		h := m.Handler(next)
+		calls = 0
+
+		first := send(h, http.MethodPost, "key-1", `{"name":"a"}`)
+		replayed := send(h, http.MethodPost, "key-1", `{"name":"a"}`)
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusCreated, first.Code)
+		assert.Empty(t, first.Header().Get(ReplayedHeader))
+		assert.Equal(t, http.StatusCreated, replayed.Code)
+		assert.Equal(t, "true", replayed.Header().Get(ReplayedHeader))
+		assert.Equal(t, "/users/1", replayed.Header().Get("Location"))
+		assert.Empty(t, replayed.Header().Get("X-Request-Id"))
+		assert.Equal(t, `{"id":1}`, replayed.Body.String())
+	})
+
+	t.Run("Different body", func(t *testing.T) {
+		m, _ := newTestMiddleware()
+		h := m.Handler(next)
+
+		send(h, http.MethodPost, "key-1", `{"name":"a"}`)
+		w := send(h, http.MethodPost, "key-1", `{"name":"b"}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), `"code":"`+codeKeyReused+`"`)
+	})
+
+	t.Run("In progress", func(t *testing.T) {
+		m, store := newTestMiddleware()
+		h := m.Handler(next)
+		_, _ = store.Lock(context.Background(), Key{Principal: "user-1", Route: "POST /users", Key: "key-1"},
+			fingerprint(httptest.NewRequest(http.MethodPost, "/", nil), []byte(`{}`)), time.Now(), 0, 0)
+
+		w := send(h, http.MethodPost, "key-1", `{}`)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, "1", w.Header().Get("Retry-After"))
+		assert.Contains(t, w.Body.String(), `"code":"`+codeInProgress+`"`)
+	})
+
+	t.Run("Server errors are retried", func(t *testing.T) {
+		m, store := newTestMiddleware()
+		h := m.Handler(next)
+		calls = 0
+		status = http.StatusInternalServerError
+		defer func() { status = http.StatusCreated }()
+
+		send(h, http.MethodPost, "key-1", `{}`)
+		send(h, http.MethodPost, "key-1", `{}`)
+
+		assert.Equal(t, 2, calls)
+		assert.Empty(t, store.records)
+	})
+
+	t.Run("Ignored without key or for safe methods", func(t *testing.T) {
+		m, store := newTestMiddleware()
+		h := m.Handler(next)
+		calls = 0
+
+		send(h, http.MethodPost, "", `{}`)
+		send(h, http.MethodPost, "", `{}`)
+		send(h, http.MethodGet, "key-1", "")
+
+		assert.Equal(t, 3, calls)
+		assert.Empty(t, store.records)
+	})
+
+	t.Run("Anonymous clients", func(t *testing.T) {
+		m, _ := newTestMiddleware()
+		m.principal = func(*http.Request) string { return "" }
+		h := m.Handler(next)
+		calls = 0
+
+		var codes []int
+		for _, addr := range []string{"192.0.2.1:1234", "198.51.100.7:4321", "192.0.2.1:5678"} {
+			r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"a"}`))
+			r.RemoteAddr = addr
+			r.Header.Set(Header, "key-1")
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			codes = append(codes, w.Code)
+
+			if addr == "198.51.100.7:4321" {
+				assert.Empty(t, w.Header().Get(ReplayedHeader), "another client's response isn't replayed")
+			}
+		}
+
+		assert.Equal(t, 2, calls, "the same client is replayed")
+		assert.Equal(t, []int{http.StatusCreated, http.StatusCreated, http.StatusCreated}, codes)
+	})
+
+	t.Run("Unreadable body", func(t *testing.T) {
+		m, _ := newTestMiddleware()
+		h := m.Handler(next)
+
+		w := send(h, http.MethodPost, "key-1", strings.Repeat("a", maxBodySize+1))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+		r := httptest.NewRequest(http.MethodPost, "/users", iotest.ErrReader(errors.New("connection reset")))
+		r.Header.Set(Header, "key-1")
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Invalid key", func(t *testing.T) {
+		m, _ := newTestMiddleware()
+
+		w := send(m.Handler(next), http.MethodPost, strings.Repeat("k", maxKeyLength+1), `{}`)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `"code":"`+codeInvalidKey+`"`)
+	})
+}
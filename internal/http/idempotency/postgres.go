@@ -0,0 +1,136 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/kiennyo/syncwatch-be/internal/db"
+)
+
+// sweepEvery is the number of locks between removals of expired keys.
+const sweepEvery = 1024
+
+// PostgresStore shares the keys between instances, the primary key serializes
+// concurrent claims.
+type PostgresStore struct {
+	DB    db.Querier
+	locks atomic.Int64
+}
+
+var _ Store = (*PostgresStore)(nil)
+
+func NewPostgresStore(querier db.Querier) *PostgresStore {
+	return &PostgresStore{DB: querier}
+}
+
+func (s *PostgresStore) Lock(ctx context.Context, k Key, fingerprint []byte, now time.Time,
+	ttl, lockTimeout time.Duration) (*Record, error) {
+	if s.locks.Add(1)%sweepEvery == 0 {
+		s.sweep(ctx, now)
+	}
+
+	args := pgx.NamedArgs{
+		"principal":   k.Principal,
+		"route":       k.Route,
+		"key":         k.Key,
+		"fingerprint": fingerprint,
+		"now":         now,
+		"expires_at":  now.Add(ttl),
+		"stale":       now.Add(-lockTimeout),
+	}
+
+	claim := `
+		INSERT INTO idempotency_key (principal, route, key, fingerprint, locked_at, expires_at)
+		VALUES (@principal, @route, @key, @fingerprint, @now, @expires_at)
+		ON CONFLICT (principal, route, key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint, locked_at = EXCLUDED.locked_at, expires_at = EXCLUDED.expires_at,
+		    status = NULL, header = NULL, body = NULL
+		WHERE idempotency_key.expires_at <= @now
+		   OR (idempotency_key.status IS NULL AND idempotency_key.locked_at <= @stale)
+		RETURNING TRUE`
+
+	var claimed bool
+	err := s.DB.QueryRow(ctx, claim, args).Scan(&claimed)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, db.MapError(err)
+	}
+
+	query := `
+		SELECT fingerprint, status, header, body
+		FROM idempotency_key
+		WHERE principal = @principal AND route = @route AND key = @key`
+
+	var (
+		rec    Record
+		status *int
+		header []byte
+		body   []byte
+	)
+
+	err = s.DB.QueryRow(ctx, query, args).Scan(&rec.Fingerprint, &status, &header, &body)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		// released in between, reported as in progress so the caller tries again
+		return &Record{Fingerprint: fingerprint}, nil
+	case err != nil:
+		return nil, db.MapError(err)
+	}
+
+	if status != nil {
+		rec.Response = &Response{Status: *status, Body: body}
+		if err = json.Unmarshal(header, &rec.Response.Header); err != nil {
+			return nil, err
+		}
+	}
+
+	return &rec, nil
+}
+
+func (s *PostgresStore) Save(ctx context.Context, k Key, resp *Response) error {
+	header, err := json.Marshal(resp.Header)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE idempotency_key
+		SET status = @status, header = @header, body = @body
+		WHERE principal = @principal AND route = @route AND key = @key`
+
+	_, err = s.DB.Exec(ctx, query, pgx.NamedArgs{
+		"principal": k.Principal,
+		"route":     k.Route,
+		"key":       k.Key,
+		"status":    resp.Status,
+		"header":    header,
+		"body":      resp.Body,
+	})
+
+	return db.MapError(err)
+}
+
+func (s *PostgresStore) Release(ctx context.Context, k Key) error {
+	query := `
+		DELETE FROM idempotency_key
+		WHERE principal = @principal AND route = @route AND key = @key AND status IS NULL`
+
+	_, err := s.DB.Exec(ctx, query, pgx.NamedArgs{"principal": k.Principal, "route": k.Route, "key": k.Key})
+
+	return db.MapError(err)
+}
+
+func (s *PostgresStore) sweep(ctx context.Context, now time.Time) {
+	_, err := s.DB.Exec(ctx, `DELETE FROM idempotency_key WHERE expires_at <= @now`, pgx.NamedArgs{"now": now})
+	if err != nil {
+		slog.Error("Failed to remove expired idempotency keys", "reason", err.Error())
+	}
+}
@@ -0,0 +1,38 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Key scopes a client supplied key to its principal and route, so clients can't see
+// each other's responses and one key may be reused across endpoints.
+type Key struct {
+	Principal string
+	Route     string
+	Key       string
+}
+
+// Response is what is replayed to retries.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Record is a key claimed before. Response is nil while the first request is processed.
+type Record struct {
+	Fingerprint []byte
+	Response    *Response
+}
+
+type Store interface {
+	// Lock claims the key and returns nil, or returns the record of the request that
+	// claimed it. Expired records and locks older than lockTimeout are taken over.
+	Lock(ctx context.Context, k Key, fingerprint []byte, now time.Time, ttl, lockTimeout time.Duration) (*Record, error)
+	// Save stores the response of the claimed key.
+	Save(ctx context.Context, k Key, resp *Response) error
+	// Release drops a claim without response, so the request can be retried.
+	Release(ctx context.Context, k Key) error
+}
@@ -0,0 +1,72 @@
+package json
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+var (
+	cborEnc, _ = cbor.EncOptions{Time: cbor.TimeRFC3339Nano}.EncMode()
+	cborDec, _ = cbor.DecOptions{
+		DupMapKey:         cbor.DupMapKeyEnforcedAPF,
+		ExtraReturnErrors: cbor.ExtraDecErrorUnknownField,
+	}.DecMode()
+)
+
+// cborCodec falls back to the json struct tags, like msgpackCodec.
+type cborCodec struct{}
+
+func (cborCodec) MediaType() string {
+	return "application/cbor"
+}
+
+func (cborCodec) Marshal(v any) ([]byte, error) {
+	return cborEnc.Marshal(v)
+}
+
+func (cborCodec) Unmarshal(data []byte, dst any) error {
+	err := cborDec.Unmarshal(data, dst)
+	if err == nil {
+		return nil
+	}
+
+	var syntaxError *cbor.SyntaxError
+	var unmarshalTypeError *cbor.UnmarshalTypeError
+	var unknownFieldError *cbor.UnknownFieldError
+	var dupMapKeyError *cbor.DupMapKeyError
+	var extraneousDataError *cbor.ExtraneousDataError
+
+	switch {
+	case errors.As(err, &syntaxError), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		msg := "Request body contains badly-formed CBOR"
+		return &MalformedRequest{Status: http.StatusBadRequest, Msg: msg}
+
+	case errors.As(err, &unmarshalTypeError):
+		msg := "Request body contains an invalid value"
+		if field := unmarshalTypeError.StructFieldName; field != "" {
+			// qualified by the Go type, e.g. users.input.name
+			msg = fmt.Sprintf("Request body contains an invalid value for the %q field", field[strings.LastIndex(field, ".")+1:])
+		}
+		return &MalformedRequest{Status: http.StatusBadRequest, Msg: msg}
+
+	case errors.As(err, &unknownFieldError):
+		msg := fmt.Sprintf("Request body contains an unknown field (at map element %d)", unknownFieldError.Index)
+		return &MalformedRequest{Status: http.StatusBadRequest, Msg: msg}
+
+	case errors.As(err, &dupMapKeyError):
+		msg := fmt.Sprintf("Request body contains duplicate field \"%v\"", dupMapKeyError.Key)
+		return &MalformedRequest{Status: http.StatusBadRequest, Msg: msg}
+
+	case errors.As(err, &extraneousDataError):
+		msg := "Request body must only contain a single CBOR object"
+		return &MalformedRequest{Status: http.StatusBadRequest, Msg: msg}
+
+	default:
+		return err
+	}
+}
@@ -0,0 +1,182 @@
+package json
+
+import (
+	"cmp"
+	"context"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Codec encodes one media type.
+type Codec interface {
+	MediaType() string
+	Marshal(v any) ([]byte, error)
+	// Unmarshal decodes a single value into dst, rejecting unknown fields. Problems of
+	// the data are described by a *MalformedRequest.
+	Unmarshal(data []byte, dst any) error
+}
+
+var (
+	JSON        Codec = jsonCodec{}
+	MessagePack Codec = msgpackCodec{}
+	CBOR        Codec = cborCodec{}
+)
+
+// registry holds the supported codecs, the first is used when the client has no preference.
+var registry = []Codec{JSON, MessagePack, CBOR}
+
+// Register adds a codec, it must be called before serving requests.
+func Register(c Codec) {
+	registry = append(registry, c)
+}
+
+// Lookup returns the codec of the media type, parameters excluded.
+func Lookup(mediaType string) (Codec, bool) {
+	for _, c := range registry {
+		if strings.EqualFold(c.MediaType(), mediaType) {
+			return c, true
+		}
+	}
+
+	return nil, false
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, c Codec) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// RequestCodec is the codec of the Content-Type of the request, JSON when it has none.
+func RequestCodec(r *http.Request) (Codec, error) {
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		return registry[0], nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err == nil {
+		if c, ok := Lookup(mediaType); ok {
+			return c, nil
+		}
+	}
+
+	msg := "Content-Type header must be one of " + mediaTypes()
+	return nil, &MalformedRequest{Status: http.StatusUnsupportedMediaType, Msg: msg}
+}
+
+// ResponseCodec is the codec negotiated by the server middleware, or else the one
+// preferred by the Accept header of the request.
+func ResponseCodec(r *http.Request) (Codec, error) {
+	if c, ok := r.Context().Value(contextKey{}).(Codec); ok {
+		return c, nil
+	}
+
+	return Negotiate(r)
+}
+
+// mediaRange is an entry of the Accept header.
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func (m mediaRange) specificity() int {
+	switch {
+	case m.typ == "*":
+		return 0
+	case m.subtype == "*":
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (m mediaRange) matches(c Codec) bool {
+	typ, subtype, _ := strings.Cut(c.MediaType(), "/")
+	return (m.typ == "*" || strings.EqualFold(m.typ, typ)) && (m.subtype == "*" || strings.EqualFold(m.subtype, subtype))
+}
+
+// Negotiate picks the codec of the most preferred media range of the Accept header,
+// ties are broken by specificity and then by registration order.
+func Negotiate(r *http.Request) (Codec, error) {
+	accept := strings.Join(r.Header.Values("Accept"), ",")
+	if strings.TrimSpace(accept) == "" {
+		return registry[0], nil
+	}
+
+	ranges, excluded := parseAccept(accept)
+	for _, m := range ranges {
+		for _, c := range registry {
+			if m.matches(c) && !isExcluded(c, m, excluded) {
+				return c, nil
+			}
+		}
+	}
+
+	msg := "Accept header must allow one of " + mediaTypes()
+	return nil, &MalformedRequest{Status: http.StatusNotAcceptable, Msg: msg}
+}
+
+// isExcluded reports whether a q=0 range at least as specific as m rules out c, e.g.
+// application/json;q=0 with application/*.
+func isExcluded(c Codec, m mediaRange, excluded []mediaRange) bool {
+	for _, e := range excluded {
+		if e.specificity() >= m.specificity() && e.matches(c) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseAccept returns the acceptable ranges by preference, and the q=0 ones.
+func parseAccept(accept string) ([]mediaRange, []mediaRange) {
+	var ranges, excluded []mediaRange
+
+	for _, entry := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(entry))
+		if err != nil {
+			continue
+		}
+
+		m := mediaRange{q: 1}
+		m.typ, m.subtype, _ = strings.Cut(mediaType, "/")
+
+		if q, ok := params["q"]; ok {
+			if m.q, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+
+		switch {
+		case m.subtype == "":
+		case m.q > 0:
+			ranges = append(ranges, m)
+		default:
+			excluded = append(excluded, m)
+		}
+	}
+
+	slices.SortStableFunc(ranges, func(a, b mediaRange) int {
+		if a.q != b.q {
+			return cmp.Compare(b.q, a.q)
+		}
+
+		return cmp.Compare(b.specificity(), a.specificity())
+	})
+
+	return ranges, excluded
+}
+
+func mediaTypes() string {
+	types := make([]string, len(registry))
+	for i, c := range registry {
+		types[i] = c.MediaType()
+	}
+
+	return strings.Join(types, ", ")
+}
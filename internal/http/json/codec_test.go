@@ -0,0 +1,191 @@
+package json
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept []string
+		want   Codec
+	}{
+		{name: "No preference", accept: nil, want: JSON},
+		{name: "Anything", accept: []string{"*/*"}, want: JSON},
+		{name: "Exact", accept: []string{"application/msgpack"}, want: MessagePack},
+		{name: "Quality", accept: []string{"application/json;q=0.5, application/cbor"}, want: CBOR},
+		{name: "Specific over wildcard", accept: []string{"*/*, application/cbor"}, want: CBOR},
+		{name: "Type wildcard", accept: []string{"text/html, application/*;q=0.9"}, want: JSON},
+		{name: "Several headers", accept: []string{"text/html", "application/msgpack;q=0.8"}, want: MessagePack},
+		{name: "Excluded", accept: []string{"application/json;q=0, application/*"}, want: MessagePack},
+		{name: "Unsatisfiable", accept: []string{"text/html, application/xml"}},
+		{name: "Only excluded", accept: []string{"application/json;q=0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, accept := range tt.accept {
+				r.Header.Add("Accept", accept)
+			}
+
+			got, err := Negotiate(r)
+
+			if tt.want == nil {
+				var mr *MalformedRequest
+				assert.True(t, errors.As(err, &mr))
+				assert.Equal(t, http.StatusNotAcceptable, mr.Status)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWrite_Negotiated(t *testing.T) {
+	type payload struct {
+		Name  string `json:"name"`
+		Count int    `json:"count,omitempty"`
+	}
+	data := Envelope{"user": payload{Name: "example"}}
+
+	for _, codec := range []Codec{JSON, MessagePack, CBOR} {
+		t.Run(codec.MediaType(), func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", codec.MediaType())
+			w := httptest.NewRecorder()
+
+			assert.NoError(t, Write(w, r, http.StatusOK, data, nil))
+			assert.Equal(t, codec.MediaType(), w.Header().Get("Content-Type"))
+
+			var got map[string]map[string]any
+			assert.NoError(t, codec.Unmarshal(w.Body.Bytes(), &got))
+			assert.Equal(t, map[string]any{"name": "example"}, got["user"], "json tags are used")
+		})
+	}
+
+	t.Run("Negotiated by middleware", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", "text/html")
+		r = r.WithContext(NewContext(context.Background(), CBOR))
+		w := httptest.NewRecorder()
+
+		assert.NoError(t, Write(w, r, http.StatusOK, data, nil))
+		assert.Equal(t, "application/cbor", w.Header().Get("Content-Type"))
+	})
+}
+
+func TestRead_Binary(t *testing.T) {
+	type Payload struct {
+		Name string `json:"name"`
+	}
+
+	msgpackBody := func(v any) []byte {
+		b, err := msgpack.Marshal(v)
+		assert.NoError(t, err)
+		return b
+	}
+	cborBody := func(v any) []byte {
+		b, err := cbor.Marshal(v)
+		assert.NoError(t, err)
+		return b
+	}
+
+	cases := []struct {
+		name        string
+		contentType string
+		body        []byte
+		expectedMsg string
+	}{
+		{
+			name:        "MessagePack",
+			contentType: "application/msgpack",
+			body:        msgpackBody(map[string]any{"name": "example"}),
+		},
+		{
+			name:        "MessagePack unknown field",
+			contentType: "application/msgpack",
+			body:        msgpackBody(map[string]any{"badname": "example"}),
+			expectedMsg: `Request body contains unknown field "badname"`,
+		},
+		{
+			name:        "MessagePack invalid value",
+			contentType: "application/msgpack",
+			body:        msgpackBody(map[string]any{"name": 1}),
+			expectedMsg: "Request body contains an invalid value (invalid code=1 decoding string/bytes length)",
+		},
+		{
+			name:        "MessagePack truncated",
+			contentType: "application/msgpack",
+			body:        msgpackBody(map[string]any{"name": "example"})[:5],
+			expectedMsg: "Request body contains badly-formed MessagePack",
+		},
+		{
+			name:        "MessagePack trailing data",
+			contentType: "application/msgpack",
+			body:        append(msgpackBody(map[string]any{"name": "a"}), msgpackBody(map[string]any{"name": "b"})...),
+			expectedMsg: "Request body must only contain a single MessagePack object",
+		},
+		{
+			name:        "CBOR",
+			contentType: "application/cbor",
+			body:        cborBody(map[string]any{"name": "example"}),
+		},
+		{
+			name:        "CBOR unknown field",
+			contentType: "application/cbor",
+			body:        cborBody(map[string]any{"badname": "example"}),
+			expectedMsg: "Request body contains an unknown field (at map element 0)",
+		},
+		{
+			name:        "CBOR invalid value",
+			contentType: "application/cbor",
+			body:        cborBody(map[string]any{"name": 1}),
+			expectedMsg: `Request body contains an invalid value for the "name" field`,
+		},
+		{
+			name:        "CBOR truncated",
+			contentType: "application/cbor",
+			body:        cborBody(map[string]any{"name": "example"})[:5],
+			expectedMsg: "Request body contains badly-formed CBOR",
+		},
+		{
+			name:        "CBOR trailing data",
+			contentType: "application/cbor",
+			body:        append(cborBody(map[string]any{"name": "a"}), cborBody(map[string]any{"name": "b"})...),
+			expectedMsg: "Request body must only contain a single CBOR object",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+
+			var dst Payload
+			err := Read(httptest.NewRecorder(), req, &dst)
+
+			if tc.expectedMsg == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, Payload{Name: "example"}, dst)
+				return
+			}
+
+			var mr *MalformedRequest
+			assert.True(t, errors.As(err, &mr), err)
+			assert.Equal(t, http.StatusBadRequest, mr.Status)
+			assert.Equal(t, tc.expectedMsg, mr.Msg)
+		})
+	}
+}
@@ -1,13 +1,18 @@
+// Package json reads request bodies and writes responses in the encoding the client
+// asked for. JSON is the default, MessagePack and CBOR are registered besides it, see
+// codec.go. The package keeps its name from when JSON was the only encoding.
 package json
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log/slog"
 	"net/http"
 	"strings"
+
+	"github.com/kiennyo/syncwatch-be/internal/logger"
 )
 
 const maxPayloadSize = 1048576
@@ -23,8 +28,15 @@ func (mr *MalformedRequest) Error() string {
 	return mr.Msg
 }
 
-func WriteJSON(w http.ResponseWriter, status int, data any, headers http.Header) error {
-	js, err := json.Marshal(data)
+// Write encodes data with the codec negotiated for the request. An unsatisfiable Accept
+// header is reported as a 406 *MalformedRequest before anything is written.
+func Write(w http.ResponseWriter, r *http.Request, status int, data any, headers http.Header) error {
+	codec, err := ResponseCodec(r)
+	if err != nil {
+		return err
+	}
+
+	body, err := codec.Marshal(data)
 	if err != nil {
 		return err
 	}
@@ -33,33 +45,64 @@ func WriteJSON(w http.ResponseWriter, status int, data any, headers http.Header)
 		w.Header()[key] = value
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", codec.MediaType())
 	w.WriteHeader(status)
 
-	_, err = w.Write(js)
+	_, err = w.Write(body)
 	if err != nil {
-		slog.Error("Write failed", "reason", err)
+		logger.FromContext(r.Context()).Error("Write failed", "reason", err, "request_url", r.URL.String())
 	}
 
 	return nil
 }
 
-//nolint:revive,cyclomatic
-func ReadJSON(w http.ResponseWriter, r *http.Request, dst any) error {
-	ct := r.Header.Get("Content-Type")
-	if ct != "" {
-		mediaType := strings.ToLower(strings.TrimSpace(strings.Split(ct, ";")[0]))
-		if mediaType != "application/json" {
-			msg := "Content-Type header is not application/json"
-			return &MalformedRequest{Status: http.StatusUnsupportedMediaType, Msg: msg}
-		}
+// Read decodes the body into dst with the codec of its Content-Type, JSON when it has
+// none. Problems of the body are described by a *MalformedRequest.
+func Read(w http.ResponseWriter, r *http.Request, dst any) error {
+	codec, err := RequestCodec(r)
+	if err != nil {
+		return err
 	}
 
 	r.Body = http.MaxBytesReader(w, r.Body, maxPayloadSize)
-	dec := json.NewDecoder(r.Body)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			msg := "Request body must not be larger than 1MB"
+			return &MalformedRequest{Status: http.StatusRequestEntityTooLarge, Msg: msg}
+		}
+
+		// the client went away or cut the body off, nothing the server can fix
+		msg := "Request body could not be read"
+		return &MalformedRequest{Status: http.StatusBadRequest, Msg: msg}
+	}
+
+	if len(body) == 0 {
+		msg := "Request body must not be empty"
+		return &MalformedRequest{Status: http.StatusBadRequest, Msg: msg}
+	}
+
+	return codec.Unmarshal(body, dst)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) MediaType() string {
+	return "application/json"
+}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+//nolint:revive,cyclomatic
+func (jsonCodec) Unmarshal(data []byte, dst any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
 	dec.DisallowUnknownFields()
 
-	err := dec.Decode(&dst)
+	err := dec.Decode(dst)
 	if err != nil {
 		var syntaxError *json.SyntaxError
 		var unmarshalTypeError *json.UnmarshalTypeError
@@ -90,10 +133,6 @@ func ReadJSON(w http.ResponseWriter, r *http.Request, dst any) error {
 			msg := "Request body must not be empty"
 			return &MalformedRequest{Status: http.StatusBadRequest, Msg: msg}
 
-		case err.Error() == "http: request body too large":
-			msg := "Request body must not be larger than 1MB"
-			return &MalformedRequest{Status: http.StatusRequestEntityTooLarge, Msg: msg}
-
 		default:
 			return err
 		}
@@ -4,16 +4,20 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/kiennyo/syncwatch-be/internal/logger"
 )
 
 //nolint:revive,cognitive-complexity
-func TestReadJSON(t *testing.T) {
+func TestRead(t *testing.T) {
 	type Payload struct {
 		Name string `json:"name"`
 	}
@@ -40,7 +44,7 @@ func TestReadJSON(t *testing.T) {
 			body:        `{"name":"example"}`,
 			expectedError: &MalformedRequest{
 				Status: http.StatusUnsupportedMediaType,
-				Msg:    "Content-Type header is not application/json",
+				Msg:    "Content-Type header must be one of application/json, application/msgpack, application/cbor",
 			},
 		},
 		{
@@ -117,7 +121,7 @@ func TestReadJSON(t *testing.T) {
 
 			var dst Payload
 			var err *MalformedRequest
-			errors.As(ReadJSON(w, req, &dst), &err)
+			errors.As(Read(w, req, &dst), &err)
 
 			if tc.expectedError != nil {
 				assert.Equal(t, tc.expectedError.Status, err.Status)
@@ -129,8 +133,19 @@ func TestReadJSON(t *testing.T) {
 	}
 }
 
+func TestRead_Unreadable(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", iotest.ErrReader(errors.New("connection reset")))
+	req.Header.Set("Content-Type", "application/json")
+
+	var dst map[string]any
+	var err *MalformedRequest
+	assert.True(t, errors.As(Read(httptest.NewRecorder(), req, &dst), &err))
+	assert.Equal(t, http.StatusBadRequest, err.Status)
+	assert.Equal(t, "Request body could not be read", err.Msg)
+}
+
 //nolint:revive,cognitive-complexity
-func TestWriteJSON(t *testing.T) {
+func TestWrite(t *testing.T) {
 	data := map[string]string{"key": "value"}
 	headers := http.Header{"Test-Header": []string{"Test-Value"}}
 
@@ -166,7 +181,7 @@ func TestWriteJSON(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			recorder := httptest.NewRecorder()
-			err := WriteJSON(recorder, tt.status, tt.data, tt.headers)
+			err := Write(recorder, httptest.NewRequest(http.MethodGet, "/", nil), tt.status, tt.data, tt.headers)
 
 			if tt.err != nil {
 				assert.Error(t, err)
@@ -188,3 +203,25 @@ func TestWriteJSON(t *testing.T) {
 		})
 	}
 }
+
+// failingWriter is a client that went away.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestWrite_LogsWithRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, nil)).With("request_id", "abc")
+	r := httptest.NewRequest(http.MethodGet, "/users", nil)
+	r = r.WithContext(logger.NewContext(r.Context(), l))
+
+	err := Write(failingWriter{httptest.NewRecorder()}, r, http.StatusOK, Envelope{"key": "value"}, nil)
+
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "Write failed")
+	assert.Contains(t, buf.String(), "request_id=abc")
+}
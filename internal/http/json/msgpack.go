@@ -0,0 +1,71 @@
+package json
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// msgpackCodec reuses the json struct tags, so payloads have the same field names in
+// every encoding.
+type msgpackCodec struct{}
+
+func (msgpackCodec) MediaType() string {
+	return "application/msgpack"
+}
+
+func (msgpackCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, dst any) error {
+	r := bytes.NewReader(data)
+
+	dec := msgpack.NewDecoder(r)
+	dec.SetCustomStructTag("json")
+	dec.DisallowUnknownFields(true)
+
+	err := dec.Decode(dst)
+	if err != nil {
+		switch {
+		case errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF):
+			msg := "Request body contains badly-formed MessagePack"
+			return &MalformedRequest{Status: http.StatusBadRequest, Msg: msg}
+
+		case strings.HasPrefix(err.Error(), "msgpack: unknown field "):
+			fieldName := strings.TrimPrefix(err.Error(), "msgpack: unknown field ")
+			msg := fmt.Sprintf("Request body contains unknown field %s", fieldName)
+			return &MalformedRequest{Status: http.StatusBadRequest, Msg: msg}
+
+		case strings.HasPrefix(err.Error(), "msgpack: "):
+			// type mismatches don't name the field, e.g. "invalid code=1 decoding string/bytes length"
+			msg := fmt.Sprintf("Request body contains an invalid value (%s)", strings.TrimPrefix(err.Error(), "msgpack: "))
+			return &MalformedRequest{Status: http.StatusBadRequest, Msg: msg}
+
+		default:
+			return err
+		}
+	}
+
+	if r.Len() > 0 {
+		msg := "Request body must only contain a single MessagePack object"
+		return &MalformedRequest{Status: http.StatusBadRequest, Msg: msg}
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kiennyo/syncwatch-be/internal/http/requestid"
+	"github.com/kiennyo/syncwatch-be/internal/logger"
+)
+
+// logRequests binds a logger carrying the request and trace IDs to the request context
+// and logs a line once the request completes.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		l := slog.Default().With(slog.String("request_id", requestid.FromContext(r.Context())))
+		if span := trace.SpanContextFromContext(r.Context()); span.IsValid() {
+			l = l.With(slog.String("trace_id", span.TraceID().String()))
+		}
+
+		ctx := logger.NewContext(r.Context(), l)
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("route", chi.RouteContext(r.Context()).RoutePattern()),
+			slog.Int("status", status),
+			slog.Int("bytes", ww.BytesWritten()),
+			slog.Duration("duration", time.Since(start)),
+		}
+		attrs = append(attrs, logger.Attrs(ctx)...)
+
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		l.LogAttrs(ctx, level, "request completed", attrs...)
+	})
+}
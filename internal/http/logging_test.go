@@ -0,0 +1,54 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiennyo/syncwatch-be/internal/http/requestid"
+	"github.com/kiennyo/syncwatch-be/internal/logger"
+)
+
+func TestLogRequests(t *testing.T) {
+	out := new(bytes.Buffer)
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(out, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	r := chi.NewRouter()
+	r.Use(requestid.Middleware)
+	r.Use(logRequests)
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		ctx := logger.WithAttrs(r.Context(), slog.String("user", "42"))
+		logger.FromContext(ctx).Info("in handler")
+
+		_, _ = w.Write([]byte("hello"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set(requestid.Header, "req-1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 2)
+
+	var handlerLine, requestLine map[string]any
+	assert.NoError(t, json.Unmarshal(lines[0], &handlerLine))
+	assert.NoError(t, json.Unmarshal(lines[1], &requestLine))
+
+	assert.Equal(t, "req-1", handlerLine["request_id"])
+	assert.Equal(t, "42", handlerLine["user"])
+
+	assert.Equal(t, "request completed", requestLine["msg"])
+	assert.Equal(t, "req-1", requestLine["request_id"])
+	assert.Equal(t, "/users/{id}", requestLine["route"])
+	assert.Equal(t, 200.0, requestLine["status"])
+	assert.Equal(t, 5.0, requestLine["bytes"])
+	assert.Equal(t, "42", requestLine["user"])
+}
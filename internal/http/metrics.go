@@ -0,0 +1,63 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var requests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "syncwatch",
+	Subsystem: "http",
+	Name:      "requests_total",
+	Help:      "HTTP requests by method, route pattern and status.",
+}, []string{"method", "route", "status"})
+
+var requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "syncwatch",
+	Subsystem: "http",
+	Name:      "request_duration_seconds",
+	Help:      "HTTP request latency by method, route pattern and status.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"method", "route", "status"})
+
+// instrument records the route pattern rather than the path, so ids in URLs don't
+// create a time series each.
+func instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		defer func() {
+			route := "unmatched"
+			if pattern := chi.RouteContext(r.Context()).RoutePattern(); pattern != "" {
+				route = pattern
+			}
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK // nothing written, net/http sends 200
+			}
+
+			labels := prometheus.Labels{"method": r.Method, "route": route, "status": strconv.Itoa(status)}
+			requests.With(labels).Inc()
+			requestDuration.With(labels).Observe(time.Since(start).Seconds())
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
+
+// adminHandler serves the metrics, it's only exposed on the admin port.
+func adminHandler() http.Handler {
+	r := chi.NewRouter()
+	r.Method(http.MethodGet, "/metrics", promhttp.Handler())
+
+	return r
+}
@@ -0,0 +1,38 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrument(t *testing.T) {
+	users := chi.NewRouter()
+	users.Get("/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r := chi.NewRouter()
+	r.Use(instrument)
+	r.Mount("/metrics-test", users)
+
+	for _, target := range []string{"/metrics-test/1", "/metrics-test/2", "/unknown"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(requests.WithLabelValues(http.MethodGet, "/metrics-test/{id}", "204")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(requests.WithLabelValues(http.MethodGet, "unmatched", "404")))
+}
+
+func TestAdminHandler(t *testing.T) {
+	res := httptest.NewRecorder()
+	adminHandler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.True(t, strings.Contains(res.Body.String(), "syncwatch_worker_tasks_pending"))
+}
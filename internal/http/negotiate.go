@@ -0,0 +1,33 @@
+package http
+
+import (
+	"net/http"
+	"slices"
+
+	httperr "github.com/kiennyo/syncwatch-be/internal/http/error"
+	"github.com/kiennyo/syncwatch-be/internal/http/json"
+)
+
+// probePaths are answered whatever their Accept header says, orchestrators only look
+// at the status and some send Accept: text/html.
+var probePaths = []string{"/healthz", "/readyz"}
+
+// negotiate picks the response encoding before the handler runs, so an unsatisfiable
+// Accept header is rejected before the request has any effect. Probes get JSON instead.
+func negotiate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+
+		codec, err := json.Negotiate(r)
+		if err != nil {
+			if !slices.Contains(probePaths, r.URL.Path) {
+				httperr.Render(w, r, err)
+				return
+			}
+
+			codec = json.JSON
+		}
+
+		next.ServeHTTP(w, r.WithContext(json.NewContext(r.Context(), codec)))
+	})
+}
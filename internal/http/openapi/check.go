@@ -0,0 +1,194 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kiennyo/syncwatch-be/internal/validator"
+)
+
+// Codes of violations only the document can tell, the others are the validator's.
+const (
+	codeType         = "type"
+	codeUnknownField = "unknown_field"
+)
+
+// checker validates decoded JSON, numbers decoded as json.Number, against schemas
+// of the document. Violations are keyed by the dotted path of the value like the
+// validator does, only the first one of a value is kept.
+type checker struct {
+	schemas map[string]*Schema
+	errs    map[string]validator.Violation
+}
+
+func (c *checker) add(key string, v validator.Violation) {
+	if _, ok := c.errs[key]; !ok {
+		c.errs[key] = v
+	}
+}
+
+func (c *checker) resolve(s *Schema) *Schema {
+	for s.Ref != "" {
+		s = c.schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+
+	return s
+}
+
+func (c *checker) check(s *Schema, value any, key string) {
+	s = c.resolve(s)
+
+	if value == nil {
+		if !s.Nullable && s.Type != "" {
+			c.add(key, validator.Violation{Code: codeType, Params: map[string]any{"type": s.Type}})
+		}
+		return
+	}
+
+	ok := true
+	switch s.Type {
+	case "object":
+		var m map[string]any
+		if m, ok = value.(map[string]any); ok {
+			c.object(s, m, key)
+		}
+	case "array":
+		var items []any
+		if items, ok = value.([]any); ok {
+			c.array(s, items, key)
+		}
+	case "string":
+		var str string
+		if str, ok = value.(string); ok {
+			c.string(s, str, key)
+		}
+	case "integer", "number":
+		var n json.Number
+		if n, ok = value.(json.Number); ok {
+			ok = c.number(s, n, key)
+		}
+	case "boolean":
+		_, ok = value.(bool)
+	}
+
+	if !ok {
+		c.add(key, validator.Violation{Code: codeType, Params: map[string]any{"type": s.Type}})
+		return
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(value) }) {
+		values := make([]string, len(s.Enum))
+		for i, e := range s.Enum {
+			values[i] = fmt.Sprint(e)
+		}
+		c.add(key, validator.Violation{Code: "one_of", Params: map[string]any{"values": values}})
+	}
+}
+
+func (c *checker) object(s *Schema, m map[string]any, key string) {
+	for _, name := range s.Required {
+		if _, ok := m[name]; !ok {
+			c.add(join(key, name), validator.Violation{Code: "required"})
+		}
+	}
+
+	for name, value := range m {
+		switch prop, ok := s.Properties[name]; {
+		case ok:
+			c.check(prop, value, join(key, name))
+		case s.Additional != nil:
+			c.check(s.Additional, value, join(key, name))
+		case s.Closed:
+			c.add(join(key, name), validator.Violation{Code: codeUnknownField})
+		}
+	}
+}
+
+func (c *checker) array(s *Schema, items []any, key string) {
+	if s.MinItems != nil && len(items) < *s.MinItems {
+		c.add(key, validator.Violation{Code: "min_items", Params: map[string]any{"min": *s.MinItems}})
+	}
+	if s.MaxItems != nil && len(items) > *s.MaxItems {
+		c.add(key, validator.Violation{Code: "max_items", Params: map[string]any{"max": *s.MaxItems}})
+	}
+
+	if s.Items != nil {
+		for i, item := range items {
+			c.check(s.Items, item, join(key, strconv.Itoa(i)))
+		}
+	}
+}
+
+func (c *checker) string(s *Schema, str, key string) {
+	n := utf8.RuneCountInString(str)
+	if s.MinLength != nil && n < *s.MinLength {
+		c.add(key, validator.Violation{Code: "min_chars", Params: map[string]any{"min": *s.MinLength}})
+	}
+	if s.MaxLength != nil && n > *s.MaxLength {
+		c.add(key, validator.Violation{Code: "max_chars", Params: map[string]any{"max": *s.MaxLength}})
+	}
+
+	if s.MinBytes != nil {
+		c.apply(key, validator.Apply("min", strconv.Itoa(*s.MinBytes), str))
+	}
+	if s.MaxBytes != nil {
+		c.apply(key, validator.Apply("max", strconv.Itoa(*s.MaxBytes), str))
+	}
+
+	if s.Pattern != "" {
+		c.apply(key, validator.Apply("regex", s.Pattern, str))
+	}
+
+	switch s.Format {
+	case "email", "uuid":
+		c.apply(key, validator.Apply(s.Format, "", str))
+	case "uri":
+		c.apply(key, validator.Apply("url", "", str))
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+			c.add(key, validator.Violation{Code: "timestamp"})
+		}
+	}
+}
+
+func (c *checker) apply(key string, v *validator.Violation) {
+	if v != nil {
+		c.add(key, *v)
+	}
+}
+
+// number reports false when the value isn't of the type, e.g. 1.5 for an integer.
+func (c *checker) number(s *Schema, n json.Number, key string) bool {
+	if s.Type == "integer" {
+		if _, err := n.Int64(); err != nil {
+			return false
+		}
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return false
+	}
+
+	if s.Minimum != nil && f < *s.Minimum {
+		c.add(key, validator.Violation{Code: "min_value", Params: map[string]any{"min": *s.Minimum}})
+	}
+	if s.Maximum != nil && f > *s.Maximum {
+		c.add(key, validator.Violation{Code: "max_value", Params: map[string]any{"max": *s.Maximum}})
+	}
+
+	return true
+}
+
+func join(key, name string) string {
+	if key == "" {
+		return name
+	}
+
+	return key + "." + name
+}
@@ -0,0 +1,84 @@
+package openapi
+
+import (
+	"bytes"
+	"crypto/rand"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sync"
+
+	httperr "github.com/kiennyo/syncwatch-be/internal/http/error"
+	"github.com/kiennyo/syncwatch-be/internal/logger"
+)
+
+//go:embed docs.html
+var docsPage string
+
+var docsTemplate = template.Must(template.New("docs").Parse(docsPage))
+
+// Handler serves the document as JSON. It's encoded on the first request, the
+// document must be complete by then.
+func (d *Document) Handler() http.HandlerFunc {
+	var (
+		once sync.Once
+		body []byte
+		err  error
+	)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			body, err = json.Marshal(d)
+		})
+
+		if err != nil {
+			httperr.Render(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", jsonContentType)
+		if _, err := w.Write(body); err != nil {
+			logger.FromContext(r.Context()).Error("Write failed", "reason", err, "request_url", r.URL.String())
+		}
+	}
+}
+
+// Docs serves the docs UI, a page rendering the document at specURL. Its script and
+// style are inline, they're allowed by a per-request nonce instead of relaxing the CSP
+// of the API.
+func Docs(specURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		nonce, err := newNonce()
+		if err != nil {
+			httperr.Render(w, r, err)
+			return
+		}
+
+		var buf bytes.Buffer
+		err = docsTemplate.Execute(&buf, map[string]string{"Nonce": nonce, "SpecURL": specURL})
+		if err != nil {
+			httperr.Render(w, r, err)
+			return
+		}
+
+		h := w.Header()
+		h.Set("Content-Type", "text/html; charset=utf-8")
+		h.Set("Content-Security-Policy", "default-src 'none'; script-src 'nonce-"+nonce+"'; style-src 'nonce-"+nonce+
+			"'; connect-src 'self'; base-uri 'none'; frame-ancestors 'none'")
+
+		if _, err = buf.WriteTo(w); err != nil {
+			logger.FromContext(r.Context()).Error("Write failed", "reason", err, "request_url", r.URL.String())
+		}
+	}
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
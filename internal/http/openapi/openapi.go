@@ -0,0 +1,301 @@
+// Package openapi describes the API as an OpenAPI 3.1 document. Modules list an
+// Operation for each of their routes, with sample values of the request and response
+// bodies. Schemas are derived from the json and validate tags of those types, so the
+// document can't drift from the code that decodes and validates the bodies.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	httperr "github.com/kiennyo/syncwatch-be/internal/http/error"
+)
+
+const Version = "3.1.0"
+
+// Parameter locations.
+const (
+	InPath   = "path"
+	InQuery  = "query"
+	InHeader = "header"
+)
+
+const bearerScheme = "bearer"
+
+// Operation documents a route. Path is relative to where the module is mounted and
+// uses the chi syntax, {name} parameters are documented as strings unless listed in
+// Params. Request and the response bodies are sample values, e.g. userInput{} or
+// json.Envelope{"user": &User{}}. Scope is the permission the route requires,
+// Deprecated marks routes clients should move off.
+type Operation struct {
+	Method      string
+	Path        string
+	ID          string
+	Summary     string
+	Description string
+	Tags        []string
+	Scope       string
+	Params      []Param
+	Request     any
+	Responses   []Response
+	Deprecated  bool
+}
+
+type Param struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// Response documents a status of an operation. Errors without a Body are problems,
+// ContentType defaults to JSON. Responses of the same status list their content types.
+type Response struct {
+	Status      int
+	Description string
+	ContentType string
+	Body        any
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Document is the OpenAPI document, built with Add before it's served.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+
+	gen *generator
+}
+
+// PathItem holds the operations of a path by lower case method.
+type PathItem map[string]*operation
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat"`
+}
+
+type operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Param               `json:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Permission  string                `json:"x-permission,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+const jsonContentType = "application/json"
+
+func New(info Info) *Document {
+	d := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+		gen:     newGenerator(),
+	}
+
+	d.Components = Components{
+		Schemas: d.gen.schemas,
+		SecuritySchemes: map[string]securityScheme{
+			bearerScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+		},
+	}
+
+	return d
+}
+
+// Add documents operations of a module mounted at prefix. Operation IDs and routes
+// are unique, duplicates are programming errors and panic.
+func (d *Document) Add(prefix string, ops ...Operation) {
+	for _, op := range ops {
+		p := Path(prefix + op.Path)
+		method := strings.ToLower(op.Method)
+
+		if d.lookupID(op.ID) {
+			panic(fmt.Sprintf("openapi: duplicate operation id %q", op.ID))
+		}
+
+		item := d.Paths[p]
+		if item == nil {
+			item = make(PathItem)
+			d.Paths[p] = item
+		}
+
+		if item[method] != nil {
+			panic(fmt.Sprintf("openapi: %s %s documented twice", op.Method, p))
+		}
+
+		item[method] = d.operation(p, op)
+	}
+}
+
+func (d *Document) lookupID(id string) bool {
+	for _, item := range d.Paths {
+		for _, op := range item {
+			if op.OperationID == id {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (d *Document) operation(p string, op Operation) *operation {
+	o := &operation{
+		OperationID: op.ID,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Tags:        op.Tags,
+		Parameters:  pathParams(p, op.Params),
+		Responses:   make(map[string]*response),
+		Permission:  op.Scope,
+		Deprecated:  op.Deprecated,
+	}
+
+	if op.Scope != "" {
+		o.Security = []map[string][]string{{bearerScheme: {}}}
+	}
+
+	if op.Request != nil {
+		o.RequestBody = &requestBody{
+			Required: true,
+			Content:  map[string]mediaType{jsonContentType: {Schema: d.gen.value(op.Request, inbound)}},
+		}
+	}
+
+	for _, res := range op.Responses {
+		d.addResponse(o, res)
+	}
+
+	d.addResponse(o, Response{Description: "Error"})
+
+	return o
+}
+
+// addResponse documents res, the zero status is the default response.
+func (d *Document) addResponse(o *operation, res Response) {
+	key := "default"
+	if res.Status != 0 {
+		key = strconv.Itoa(res.Status)
+	}
+
+	r := o.Responses[key]
+	if r == nil {
+		r = &response{Description: res.Description}
+		if r.Description == "" {
+			r.Description = http.StatusText(res.Status)
+		}
+		o.Responses[key] = r
+	}
+
+	body, contentType := res.Body, res.ContentType
+	switch {
+	case body == nil && (res.Status == 0 || res.Status >= http.StatusBadRequest):
+		body, contentType = httperr.Problem{}, httperr.ContentType
+	case body == nil:
+		return
+	}
+
+	if r.Content == nil {
+		r.Content = make(map[string]mediaType)
+	}
+
+	if contentType == "" {
+		contentType = jsonContentType
+	}
+
+	r.Content[contentType] = mediaType{Schema: d.gen.value(body, outbound)}
+}
+
+var paramRX = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?}`)
+
+// Path turns a chi route pattern into an OpenAPI path: regexps are dropped from
+// parameters, as is the trailing slash of routes mounted at /.
+func Path(pattern string) string {
+	p := paramRX.ReplaceAllString(pattern, "{$1}")
+	if len(p) > 1 {
+		p = strings.TrimSuffix(p, "/")
+	}
+
+	return p
+}
+
+// pathParams lists the parameters of the path, the declared ones in place of the
+// default string schema, followed by the other declared parameters.
+func pathParams(p string, declared []Param) []Param {
+	var params []Param
+
+	for _, m := range paramRX.FindAllStringSubmatch(p, -1) {
+		i := slices.IndexFunc(declared, func(d Param) bool { return d.In == InPath && d.Name == m[1] })
+		if i < 0 {
+			params = append(params, Param{Name: m[1], In: InPath, Required: true, Schema: &Schema{Type: "string"}})
+			continue
+		}
+
+		param := declared[i]
+		param.Required = true
+		params = append(params, param)
+	}
+
+	for _, param := range declared {
+		if param.In != InPath {
+			params = append(params, param)
+		}
+	}
+
+	return params
+}
+
+// Undocumented lists the routes of r without an operation in d, as "METHOD /path".
+func (d *Document) Undocumented(r chi.Routes) []string {
+	var missing []string
+
+	_ = chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if item := d.Paths[Path(route)]; item == nil || item[strings.ToLower(method)] == nil {
+			missing = append(missing, method+" "+Path(route))
+		}
+		return nil
+	})
+
+	slices.Sort(missing)
+
+	return missing
+}
@@ -0,0 +1,163 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	ID      uuid.UUID `json:"id"`
+	Name    string    `json:"name,omitempty" doc:"Display name"`
+	Created time.Time `json:"created_at"`
+	Parent  *item     `json:"parent"`
+	secret  string
+}
+
+type itemInput struct {
+	Name  string   `json:"name" validate:"required,max=50"`
+	Nick  string   `json:"nick" validate:"maxrunes=20"`
+	Kind  string   `json:"kind" validate:"oneof=a|b"`
+	Email string   `json:"email" validate:"email"`
+	Tags  []string `json:"tags" validate:"max=3"`
+	Count int      `json:"count" validate:"min=1,max=10"`
+	Skip  string   `json:"-"`
+}
+
+func TestDocument_Schemas(t *testing.T) {
+	d := New(Info{Title: "Test", Version: "1"})
+	d.Add("/items", Operation{
+		Method: http.MethodPost, Path: "/", ID: "createItem", Request: itemInput{},
+		Responses: []Response{{Status: http.StatusCreated, Body: map[string]any{"item": &item{}}}},
+	})
+
+	input := d.Components.Schemas["ItemInput"]
+	require.NotNil(t, input)
+	assert.True(t, input.Closed)
+	assert.Equal(t, []string{"name"}, input.Required)
+	assert.NotContains(t, input.Properties, "Skip")
+	assert.Equal(t, 1, *input.Properties["name"].MinLength)
+	assert.Equal(t, 50, *input.Properties["name"].MaxBytes)
+	assert.Nil(t, input.Properties["name"].MaxLength, "max counts bytes, maxLength characters")
+	assert.Equal(t, 20, *input.Properties["nick"].MaxLength)
+	assert.Equal(t, []any{"a", "b"}, input.Properties["kind"].Enum)
+	assert.Equal(t, "email", input.Properties["email"].Format)
+	assert.Equal(t, 3, *input.Properties["tags"].MaxItems)
+	assert.Equal(t, 1.0, *input.Properties["count"].Minimum)
+	assert.Equal(t, "integer", input.Properties["count"].Type)
+
+	out := d.Components.Schemas["Item"]
+	require.NotNil(t, out)
+	assert.False(t, out.Closed)
+	assert.Equal(t, []string{"id", "created_at", "parent"}, out.Required)
+	assert.Equal(t, "uuid", out.Properties["id"].Format)
+	assert.Equal(t, "date-time", out.Properties["created_at"].Format)
+	assert.Equal(t, "Display name", out.Properties["name"].Description)
+	assert.Equal(t, "#/components/schemas/Item", out.Properties["parent"].Ref)
+	assert.NotContains(t, out.Properties, "secret")
+
+	op := d.Paths["/items"]["post"]
+	require.NotNil(t, op)
+	envelope := op.Responses["201"].Content[jsonContentType].Schema
+	assert.Equal(t, []string{"item"}, envelope.Required)
+	assert.Equal(t, "#/components/schemas/Item", envelope.Properties["item"].Ref)
+	assert.Equal(t, "#/components/schemas/Problem", op.Responses["default"].Content["application/problem+json"].Schema.Ref)
+}
+
+func TestDocument_SchemasByDirection(t *testing.T) {
+	d := New(Info{})
+	d.Add("/items", Operation{
+		Method: http.MethodPut, Path: "/{id}", ID: "replaceItem", Request: item{},
+		Responses: []Response{{Status: http.StatusOK, Body: item{}}},
+	})
+
+	in := d.Components.Schemas["ItemInput"]
+	require.NotNil(t, in)
+	assert.True(t, in.Closed)
+	assert.Empty(t, in.Required)
+	assert.Equal(t, "#/components/schemas/ItemInput", in.Properties["parent"].Ref)
+
+	out := d.Components.Schemas["Item"]
+	require.NotNil(t, out)
+	assert.False(t, out.Closed)
+	assert.Equal(t, []string{"id", "created_at", "parent"}, out.Required)
+
+	op := d.Paths["/items/{id}"]["put"]
+	assert.Equal(t, "#/components/schemas/ItemInput", op.RequestBody.Content[jsonContentType].Schema.Ref)
+	assert.Equal(t, "#/components/schemas/Item", op.Responses["200"].Content[jsonContentType].Schema.Ref)
+}
+
+func TestSchema_MarshalJSON(t *testing.T) {
+	js, err := json.Marshal(&Schema{Type: "array", Nullable: true, Items: &Schema{Type: "object", Closed: true}})
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":["array","null"],"items":{"type":"object","additionalProperties":false}}`, string(js))
+}
+
+func TestDocument_PathParams(t *testing.T) {
+	d := New(Info{})
+	d.Add("/items", Operation{
+		Method: http.MethodGet, Path: "/{itemID}/parts/{part:[a-z]+}", ID: "getPart",
+		Params: []Param{
+			{Name: "itemID", In: InPath, Schema: &Schema{Type: "string", Format: "uuid"}},
+			{Name: "verbose", In: InQuery, Schema: &Schema{Type: "boolean"}},
+		},
+	})
+
+	op := d.Paths["/items/{itemID}/parts/{part}"]["get"]
+	require.NotNil(t, op)
+	require.Len(t, op.Parameters, 3)
+	assert.Equal(t, "uuid", op.Parameters[0].Schema.Format)
+	assert.True(t, op.Parameters[0].Required)
+	assert.Equal(t, "part", op.Parameters[1].Name)
+	assert.True(t, op.Parameters[1].Required)
+	assert.Equal(t, "verbose", op.Parameters[2].Name)
+}
+
+func TestDocument_AddDuplicate(t *testing.T) {
+	d := New(Info{})
+	d.Add("", Operation{Method: http.MethodGet, Path: "/a", ID: "a"})
+
+	assert.Panics(t, func() { d.Add("", Operation{Method: http.MethodGet, Path: "/b", ID: "a"}) })
+	assert.Panics(t, func() { d.Add("", Operation{Method: http.MethodGet, Path: "/a/", ID: "b"}) })
+}
+
+func TestDocument_Undocumented(t *testing.T) {
+	sub := chi.NewRouter()
+	sub.Get("/", func(http.ResponseWriter, *http.Request) {})
+	sub.Delete("/{id}", func(http.ResponseWriter, *http.Request) {})
+
+	r := chi.NewRouter()
+	r.Get("/healthz", func(http.ResponseWriter, *http.Request) {})
+	r.Mount("/items", sub)
+
+	d := New(Info{})
+	d.Add("", Operation{Method: http.MethodGet, Path: "/healthz", ID: "live"})
+	d.Add("/items", Operation{Method: http.MethodGet, Path: "/", ID: "listItems"})
+
+	assert.Equal(t, []string{"DELETE /items/{id}"}, d.Undocumented(r))
+
+	d.Add("/items", Operation{Method: http.MethodDelete, Path: "/{id}", ID: "deleteItem"})
+	assert.Empty(t, d.Undocumented(r))
+}
+
+func TestDocs(t *testing.T) {
+	w := httptest.NewRecorder()
+	Docs("/openapi.json").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	csp := w.Header().Get("Content-Security-Policy")
+	nonce := regexp.MustCompile(`script-src 'nonce-([^']+)'`).FindStringSubmatch(csp)
+	require.NotNil(t, nonce, csp)
+	assert.Contains(t, w.Body.String(), `<script nonce="`+nonce[1]+`">`)
+	assert.Contains(t, w.Body.String(), `const specURL = "/openapi.json";`)
+}
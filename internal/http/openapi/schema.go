@@ -0,0 +1,367 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/kiennyo/syncwatch-be/internal/validator"
+)
+
+// Schema is the subset of JSON Schema 2020-12 the generated document uses. Nullable
+// adds "null" to the type, Closed rejects properties not listed. MinBytes and MaxBytes
+// bound the UTF-8 length of strings, JSON Schema only counts characters.
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"-"`
+	Nullable    bool               `json:"-"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Enum        []any              `json:"enum,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Additional  *Schema            `json:"-"`
+	Closed      bool               `json:"-"`
+	Items       *Schema            `json:"items,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	MinBytes    *int               `json:"x-minBytes,omitempty"`
+	MaxBytes    *int               `json:"x-maxBytes,omitempty"`
+	MinItems    *int               `json:"minItems,omitempty"`
+	MaxItems    *int               `json:"maxItems,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+}
+
+func (s Schema) MarshalJSON() ([]byte, error) {
+	type plain Schema
+
+	out := struct {
+		Type                 any `json:"type,omitempty"`
+		AdditionalProperties any `json:"additionalProperties,omitempty"`
+		plain
+	}{plain: plain(s)}
+
+	switch {
+	case s.Type != "" && s.Nullable:
+		out.Type = []string{s.Type, "null"}
+	case s.Type != "":
+		out.Type = s.Type
+	}
+
+	switch {
+	case s.Closed:
+		out.AdditionalProperties = false
+	case s.Additional != nil:
+		out.AdditionalProperties = s.Additional
+	}
+
+	return json.Marshal(out)
+}
+
+// direction decides which fields are required. Request bodies require the fields
+// validated as required, responses every field not omitted when empty.
+type direction int
+
+const (
+	inbound direction = iota
+	outbound
+)
+
+var (
+	timeType      = reflect.TypeFor[time.Time]()
+	rawType       = reflect.TypeFor[json.RawMessage]()
+	marshalerType = reflect.TypeFor[json.Marshaler]()
+	textType      = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// generator derives schemas from Go types the way encoding/json sees them. Named
+// structs become components, referenced wherever they are used in the same direction.
+type generator struct {
+	schemas map[string]*Schema
+	names   map[component]string
+}
+
+// component identifies the schema of a named struct. A type both read and written has
+// two, they differ in the fields required and whether unknown ones are allowed.
+type component struct {
+	t   reflect.Type
+	dir direction
+}
+
+func newGenerator() *generator {
+	return &generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[component]string),
+	}
+}
+
+// value is the schema of a sample value. Maps with string keys, like json.Envelope,
+// are described by their entries, so envelopes list what they carry.
+func (g *generator) value(v any, dir direction) *Schema {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String || rv.Len() == 0 {
+		return g.schema(reflect.TypeOf(v), dir)
+	}
+
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema, rv.Len())}
+
+	for _, key := range rv.MapKeys() {
+		s.Properties[key.String()] = g.value(rv.MapIndex(key).Interface(), dir)
+		s.Required = append(s.Required, key.String())
+	}
+
+	slices.Sort(s.Required)
+
+	return s
+}
+
+func (g *generator) schema(t reflect.Type, dir direction) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+
+	s := g.typed(t, dir)
+	if s.Ref == "" {
+		// nil slices and maps are encoded as null too
+		s.Nullable = nullable || (t.Kind() == reflect.Map || (t.Kind() == reflect.Slice && s.Type == "array"))
+	}
+
+	return s
+}
+
+func (g *generator) typed(t reflect.Type, dir direction) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawType:
+		return &Schema{}
+	case t.Implements(textType) && !t.Implements(marshalerType):
+		return &Schema{Type: "string", Format: textFormat(t)}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem(), dir)}
+	case reflect.Map:
+		s := &Schema{Type: "object"}
+		if t.Elem().Kind() != reflect.Interface {
+			s.Additional = g.schema(t.Elem(), dir)
+		}
+		return s
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t, dir)
+		}
+		return g.ref(t, dir)
+	default:
+		// interfaces hold anything
+		return &Schema{}
+	}
+}
+
+// textFormat names the format of the few text types the API uses.
+func textFormat(t reflect.Type) string {
+	if t.PkgPath() == "github.com/google/uuid" && t.Name() == "UUID" {
+		return "uuid"
+	}
+
+	return ""
+}
+
+// ref registers the component of a named struct on first use. The placeholder is in
+// place before the fields are described, so recursive types terminate.
+func (g *generator) ref(t reflect.Type, dir direction) *Schema {
+	key := component{t: t, dir: dir}
+
+	name, ok := g.names[key]
+	if !ok {
+		name = g.name(t, dir)
+		g.names[key] = name
+
+		placeholder := &Schema{}
+		g.schemas[name] = placeholder
+		*placeholder = *g.object(t, dir)
+	}
+
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// name is the exported type name, with an Input suffix in request bodies, prefixed
+// with its package when another package already took it.
+func (g *generator) name(t reflect.Type, dir direction) string {
+	name, _, _ := strings.Cut(t.Name(), "[")
+	name = capitalize(name)
+
+	if dir == inbound && !strings.HasSuffix(name, "Input") {
+		name += "Input"
+	}
+
+	if _, taken := g.schemas[name]; taken {
+		name = capitalize(path.Base(t.PkgPath())) + name
+	}
+
+	return name
+}
+
+func (g *generator) object(t reflect.Type, dir direction) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema), Closed: dir == inbound}
+	g.fields(t, dir, s)
+
+	return s
+}
+
+func (g *generator) fields(t reflect.Type, dir direction, s *Schema) {
+	for i := range t.NumField() {
+		sf := t.Field(i)
+
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		// untagged embedded structs are flattened by encoding/json
+		if sf.Anonymous && name == "" {
+			et := sf.Type
+			if et.Kind() == reflect.Pointer {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct {
+				g.fields(et, dir, s)
+				continue
+			}
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		rules := validator.ParseTag(sf.Tag.Get("validate"))
+
+		fs := g.schema(sf.Type, dir)
+		if fs.Ref == "" {
+			constrain(fs, rules)
+		}
+		fs.Description = sf.Tag.Get("doc")
+
+		s.Properties[name] = fs
+
+		if (dir == inbound && hasRule(rules, "required")) || (dir == outbound && !strings.Contains(opts, "omitempty")) {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// constrain maps validate rules to keywords. min and max count the bytes of strings,
+// they're x- keywords since minLength and maxLength count characters like minrunes
+// and maxrunes. Custom rules have no keyword and are left to the description.
+func constrain(s *Schema, rules []validator.TagRule) {
+	for _, r := range rules {
+		switch r.Name {
+		case "min", "max":
+			bound(s, r.Name == "min", r.Param)
+		case "minrunes":
+			s.MinLength = intPtr(r.Param)
+		case "maxrunes":
+			s.MaxLength = intPtr(r.Param)
+		case "email":
+			s.Format = "email"
+		case "url":
+			s.Format = "uri"
+		case "uuid":
+			s.Format = "uuid"
+		case "oneof":
+			for _, value := range strings.Split(r.Param, "|") {
+				s.Enum = append(s.Enum, value)
+			}
+		case "regex":
+			s.Pattern = r.Param
+		}
+	}
+
+	// required rejects zero values, empty strings and lists included
+	if hasRule(rules, "required") {
+		one := 1
+		switch {
+		case s.Type == "string" && s.MinLength == nil:
+			s.MinLength = &one
+		case s.Type == "array" && s.MinItems == nil:
+			s.MinItems = &one
+		}
+	}
+}
+
+func bound(s *Schema, lower bool, param string) {
+	switch s.Type {
+	case "string":
+		if lower {
+			s.MinBytes = intPtr(param)
+		} else {
+			s.MaxBytes = intPtr(param)
+		}
+	case "array":
+		if lower {
+			s.MinItems = intPtr(param)
+		} else {
+			s.MaxItems = intPtr(param)
+		}
+	case "integer", "number":
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return
+		}
+		if lower {
+			s.Minimum = &n
+		} else {
+			s.Maximum = &n
+		}
+	}
+}
+
+func hasRule(rules []validator.TagRule, name string) bool {
+	return slices.ContainsFunc(rules, func(r validator.TagRule) bool { return r.Name == name })
+}
+
+func intPtr(param string) *int {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		return nil
+	}
+
+	return &n
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+
+	return string(r)
+}
@@ -0,0 +1,333 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	httperr "github.com/kiennyo/syncwatch-be/internal/http/error"
+	"github.com/kiennyo/syncwatch-be/internal/logger"
+	"github.com/kiennyo/syncwatch-be/internal/validator"
+)
+
+// CodeInvalidResponse reports a response the document doesn't allow.
+const CodeInvalidResponse = "invalid_response"
+
+// maxBody bounds the bodies read for validation, larger ones are left to the handler,
+// which rejects them.
+const maxBody = 1_048_576
+
+// Validator checks requests against the operations of the document, before they reach
+// the handlers, and reports violations the way httperr.Validation does. Only JSON bodies
+// are checked, other encodings are left to the handlers.
+type Validator struct {
+	schemas   map[string]*Schema
+	routes    []route
+	responses bool
+}
+
+type route struct {
+	method string
+	rx     *regexp.Regexp
+	names  []string
+	op     *operation
+}
+
+// Validator matches requests to the operations added so far. With responses set,
+// responses are checked too and replaced by an error when the document doesn't allow
+// them, so contract drift fails tests. It buffers every response, which production
+// shouldn't pay for.
+func (d *Document) Validator(responses bool) *Validator {
+	v := &Validator{schemas: d.Components.Schemas, responses: responses}
+
+	for p, item := range d.Paths {
+		var names []string
+		segments := strings.Split(p, "/")
+		for i, segment := range segments {
+			if m := paramRX.FindStringSubmatch(segment); m != nil {
+				names = append(names, m[1])
+				segments[i] = "([^/]+)"
+			} else {
+				segments[i] = regexp.QuoteMeta(segment)
+			}
+		}
+
+		rx := regexp.MustCompile("^" + strings.Join(segments, "/") + "/?$")
+		for method, op := range item {
+			v.routes = append(v.routes, route{method: strings.ToUpper(method), rx: rx, names: names, op: op})
+		}
+	}
+
+	// static segments win over parameters, as they do in the router
+	sort.SliceStable(v.routes, func(i, j int) bool { return len(v.routes[i].names) < len(v.routes[j].names) })
+
+	return v
+}
+
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rt, values := v.match(r)
+		if rt == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		c := &checker{schemas: v.schemas, errs: make(map[string]validator.Violation)}
+		c.params(rt, values, r)
+
+		if err := c.body(rt.op, r); err != nil {
+			httperr.Render(w, r, err)
+			return
+		}
+
+		if len(c.errs) > 0 {
+			httperr.Render(w, r, httperr.Validation(c.errs))
+			return
+		}
+
+		if !v.responses {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w, header: w.Header().Clone(), status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		v.respond(w, r, rt.op, rec)
+	})
+}
+
+// match finds the operation of the request and the values of its path parameters.
+func (v *Validator) match(r *http.Request) (*route, []string) {
+	for i := range v.routes {
+		rt := &v.routes[i]
+		if rt.method != r.Method {
+			continue
+		}
+
+		if m := rt.rx.FindStringSubmatch(r.URL.Path); m != nil {
+			return rt, m[1:]
+		}
+	}
+
+	return nil, nil
+}
+
+func (c *checker) params(rt *route, values []string, r *http.Request) {
+	query := r.URL.Query()
+
+	for _, p := range rt.op.Parameters {
+		var raw string
+		var present bool
+
+		switch p.In {
+		case InPath:
+			if i := slices.Index(rt.names, p.Name); i >= 0 {
+				raw, present = values[i], true
+			}
+		case InQuery:
+			raw, present = query.Get(p.Name), query.Has(p.Name)
+		case InHeader:
+			raw = r.Header.Get(p.Name)
+			present = raw != ""
+		}
+
+		if !present {
+			if p.Required {
+				c.add(p.Name, validator.Violation{Code: "required"})
+			}
+			continue
+		}
+
+		if value, violation := coerce(c.resolve(p.Schema), raw); violation != nil {
+			c.add(p.Name, *violation)
+		} else {
+			c.check(p.Schema, value, p.Name)
+		}
+	}
+}
+
+// coerce types a parameter by its schema, parameters are strings on the wire.
+func coerce(s *Schema, raw string) (any, *validator.Violation) {
+	switch s.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return nil, &validator.Violation{Code: "number"}
+		}
+		return json.Number(raw), nil
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, &validator.Violation{Code: "boolean"}
+		}
+		return b, nil
+	default:
+		return raw, nil
+	}
+}
+
+// body checks a JSON body and puts it back for the handler. Bodies the handler can't
+// decode either are left to it, its errors tell what's wrong.
+func (c *checker) body(op *operation, r *http.Request) error {
+	if op.RequestBody == nil || r.Body == nil {
+		return nil
+	}
+
+	// handlers decode bodies without a content type as JSON
+	media, ok := op.RequestBody.Content[jsonContentType]
+	if contentType := r.Header.Get("Content-Type"); !ok || (contentType != "" && !isJSON(contentType)) {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBody+1))
+	if err != nil {
+		return readError(err)
+	}
+
+	r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+	if len(body) == 0 || len(body) > maxBody {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var value any
+	if err = dec.Decode(&value); err != nil {
+		return nil //nolint:nilerr // reported by the handler
+	}
+
+	c.root(media.Schema, value)
+
+	return nil
+}
+
+// readError reports a body that couldn't be read, too large or cut off by the client.
+func readError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return httperr.New(http.StatusRequestEntityTooLarge, httperr.CodePayloadTooLarge,
+			"body must not be larger than "+strconv.FormatInt(tooLarge.Limit, 10)+" bytes")
+	}
+
+	return httperr.New(http.StatusBadRequest, httperr.CodeMalformedRequest, "the request body could not be read")
+}
+
+// respond writes the recorded response when the document allows it, and an internal
+// error listing the violations otherwise.
+func (v *Validator) respond(w http.ResponseWriter, r *http.Request, op *operation, rec *recorder) {
+	c := &checker{schemas: v.schemas, errs: make(map[string]validator.Violation)}
+	c.response(op, rec)
+
+	if len(c.errs) > 0 {
+		logger.FromContext(r.Context()).Error("Response violates the OpenAPI document",
+			"request_method", r.Method, "request_url", r.URL.String(), "status", rec.status, "violations", c.errs)
+
+		e := httperr.New(http.StatusInternalServerError, CodeInvalidResponse,
+			"the response doesn't match the API document")
+		httperr.Render(w, r, e.WithFields(c.errs))
+		return
+	}
+
+	header := w.Header()
+	for name := range header {
+		header.Del(name)
+	}
+	for name, values := range rec.header {
+		header[name] = values
+	}
+
+	w.WriteHeader(rec.status)
+	if _, err := rec.body.WriteTo(w); err != nil {
+		logger.FromContext(r.Context()).Error("Write failed", "reason", err, "request_url", r.URL.String())
+	}
+}
+
+func (c *checker) response(op *operation, rec *recorder) {
+	res, ok := op.Responses[strconv.Itoa(rec.status)]
+	if !ok {
+		res = op.Responses["default"]
+	}
+
+	if res == nil {
+		c.add("status", validator.Violation{Code: CodeInvalidResponse})
+		return
+	}
+
+	contentType := rec.header.Get("Content-Type")
+	if rec.body.Len() == 0 || !isJSON(contentType) {
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	media, ok := res.Content[mediaType]
+	if !ok {
+		c.add("content_type", validator.Violation{Code: CodeInvalidResponse})
+		return
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(rec.body.Bytes()))
+	dec.UseNumber()
+
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		c.add("body", validator.Violation{Code: CodeInvalidResponse, Message: err.Error()})
+		return
+	}
+
+	c.root(media.Schema, value)
+}
+
+// root checks a body, a violation of the body itself is keyed by body.
+func (c *checker) root(s *Schema, value any) {
+	c.check(s, value, "")
+
+	if violation, ok := c.errs[""]; ok {
+		delete(c.errs, "")
+		c.add("body", violation)
+	}
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == jsonContentType || strings.HasSuffix(mediaType, "+json"))
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// recorder holds the response back until it's checked, with headers of its own so
+// an invalid response leaves nothing behind.
+type recorder struct {
+	http.ResponseWriter
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *recorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.body.Write(b)
+}
@@ -0,0 +1,212 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	httperr "github.com/kiennyo/syncwatch-be/internal/http/error"
+)
+
+type part struct {
+	Name string `json:"name" validate:"required"`
+	Size int    `json:"size" validate:"min=1"`
+}
+
+type partsInput struct {
+	Label string `json:"label" validate:"required,max=10"`
+	Parts []part `json:"parts"`
+}
+
+func testDocument() *Document {
+	d := New(Info{})
+	d.Add("/items", Operation{
+		Method: http.MethodPost, Path: "/{itemID}/parts", ID: "addParts", Request: partsInput{},
+		Params: []Param{
+			{Name: "itemID", In: InPath, Schema: &Schema{Type: "string", Format: "uuid"}},
+			{Name: "dry_run", In: InQuery, Schema: &Schema{Type: "boolean"}},
+			{Name: "X-Tenant", In: InHeader, Required: true, Schema: &Schema{Type: "string"}},
+		},
+		Responses: []Response{{Status: http.StatusCreated, Body: map[string]any{"label": ""}}},
+	})
+	d.Add("/items", Operation{Method: http.MethodGet, Path: "/{itemID}/parts/latest", ID: "latestPart"})
+
+	return d
+}
+
+func TestValidator_Requests(t *testing.T) {
+	const itemID = "4d1c3d0e-8b7a-4c39-9a56-2ff1f8a0f9b1"
+
+	tests := []struct {
+		name   string
+		target string
+		tenant string
+		body   string
+		want   map[string]string
+	}{
+		{name: "Valid", target: "/items/" + itemID + "/parts?dry_run=true", tenant: "a",
+			body: `{"label":"l","parts":[{"name":"n","size":2}]}`},
+		{name: "Trailing slash", target: "/items/" + itemID + "/parts/", tenant: "a", body: `{"label":"l"}`},
+		{name: "Path parameter", target: "/items/1/parts", tenant: "a", body: `{"label":"l"}`,
+			want: map[string]string{"itemID": "uuid"}},
+		{name: "Query parameter", target: "/items/" + itemID + "/parts?dry_run=maybe", tenant: "a", body: `{"label":"l"}`,
+			want: map[string]string{"dry_run": "boolean"}},
+		{name: "Missing header", target: "/items/" + itemID + "/parts", body: `{"label":"l"}`,
+			want: map[string]string{"X-Tenant": "required"}},
+		{name: "Body fields", target: "/items/" + itemID + "/parts", tenant: "a",
+			body: `{"label":"a label too long","parts":[{"size":0},{"name":1}],"color":"red"}`,
+			want: map[string]string{
+				"label": "max_length", "parts.0.name": "required", "parts.0.size": "min_value",
+				"parts.1.name": "type", "color": "unknown_field",
+			}},
+		{name: "Multibyte characters", target: "/items/" + itemID + "/parts", tenant: "a",
+			body: `{"label":"ąčęėįšųū"}`, want: map[string]string{"label": "max_length"}},
+		{name: "Body type", target: "/items/" + itemID + "/parts", tenant: "a", body: `["label"]`,
+			want: map[string]string{"body": "type"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				received = string(body)
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			r := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			if tt.tenant != "" {
+				r.Header.Set("X-Tenant", tt.tenant)
+			}
+			w := httptest.NewRecorder()
+
+			testDocument().Validator(false).Middleware(next).ServeHTTP(w, r)
+
+			if tt.want == nil {
+				assert.Equal(t, http.StatusNoContent, w.Code)
+				assert.Equal(t, tt.body, received, "the handler reads the body again")
+				return
+			}
+
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+			assert.Equal(t, tt.want, fieldCodes(t, w))
+		})
+	}
+}
+
+func TestValidator_SkipsUndocumented(t *testing.T) {
+	called := false
+	next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true })
+
+	// static segments aren't taken for the parameter of another route
+	r := httptest.NewRequest(http.MethodGet, "/items/1/parts/latest", nil)
+	testDocument().Validator(false).Middleware(next).ServeHTTP(httptest.NewRecorder(), r)
+	assert.True(t, called)
+
+	called = false
+	r = httptest.NewRequest(http.MethodDelete, "/items/1/parts", nil)
+	testDocument().Validator(false).Middleware(next).ServeHTTP(httptest.NewRecorder(), r)
+	assert.True(t, called)
+}
+
+func TestValidator_UnreadableBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body func(w http.ResponseWriter) io.ReadCloser
+		want int
+	}{
+		{name: "Too large", want: http.StatusRequestEntityTooLarge, body: func(w http.ResponseWriter) io.ReadCloser {
+			return http.MaxBytesReader(w, io.NopCloser(strings.NewReader(`{"label":"l"}`)), 4)
+		}},
+		{name: "Cut off", want: http.StatusBadRequest, body: func(http.ResponseWriter) io.ReadCloser {
+			return io.NopCloser(iotest.ErrReader(errors.New("connection reset")))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true })
+
+			r := httptest.NewRequest(http.MethodPost, "/items/4d1c3d0e-8b7a-4c39-9a56-2ff1f8a0f9b1/parts", nil)
+			r.Header.Set("X-Tenant", "a")
+			w := httptest.NewRecorder()
+			r.Body = tt.body(w)
+
+			testDocument().Validator(false).Middleware(next).ServeHTTP(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+			assert.False(t, called)
+		})
+	}
+}
+
+func TestValidator_Responses(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses bool
+		status    int
+		body      string
+		want      map[string]string
+	}{
+		{name: "Valid", responses: true, status: http.StatusCreated, body: `{"label":"l"}`},
+		{name: "Missing field", responses: true, status: http.StatusCreated, body: `{}`,
+			want: map[string]string{"label": "required"}},
+		{name: "Wrong type", responses: true, status: http.StatusCreated, body: `{"label":1}`,
+			want: map[string]string{"label": "type"}},
+		{name: "Undocumented status", responses: true, status: http.StatusOK, body: `{"label":"l"}`,
+			want: map[string]string{"content_type": "invalid_response"}},
+		{name: "Not checked", status: http.StatusCreated, body: `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Location", "/items/1")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			})
+
+			body := `{"label":"l"}`
+			r := httptest.NewRequest(http.MethodPost, "/items/4d1c3d0e-8b7a-4c39-9a56-2ff1f8a0f9b1/parts",
+				strings.NewReader(body))
+			r.Header.Set("X-Tenant", "a")
+			w := httptest.NewRecorder()
+
+			testDocument().Validator(tt.responses).Middleware(next).ServeHTTP(w, r)
+
+			if tt.want == nil {
+				assert.Equal(t, tt.status, w.Code)
+				assert.Equal(t, tt.body, w.Body.String())
+				assert.Equal(t, "/items/1", w.Header().Get("Location"))
+				return
+			}
+
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			assert.Empty(t, w.Header().Get("Location"), "headers of the invalid response are dropped")
+			assert.Equal(t, tt.want, fieldCodes(t, w))
+		})
+	}
+}
+
+func fieldCodes(t *testing.T, w *httptest.ResponseRecorder) map[string]string {
+	var p httperr.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+
+	codes := make(map[string]string, len(p.Errors))
+	for _, f := range p.Errors {
+		codes[f.Field] = f.Code
+		assert.NotEmpty(t, f.Detail, f.Field)
+	}
+
+	return codes
+}
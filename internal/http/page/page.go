@@ -0,0 +1,77 @@
+// Package page serves lists paginated by package keyset: it parses the
+// ?limit=&cursor=&sort=&filter[...]= parameters, documents them and describes the page
+// in the response.
+package page
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kiennyo/syncwatch-be/internal/db/keyset"
+	httperr "github.com/kiennyo/syncwatch-be/internal/http/error"
+	"github.com/kiennyo/syncwatch-be/internal/http/openapi"
+)
+
+// Parse validates the query parameters of r, violations are reported per parameter as
+// a validation error.
+func Parse[T any](r *http.Request, spec *keyset.Spec[T], signer *keyset.Signer) (*keyset.Query[T], error) {
+	q, err := spec.Parse(r.URL.Query(), signer)
+
+	var invalid *keyset.Error
+	if errors.As(err, &invalid) {
+		return nil, httperr.Validation(invalid.Violations)
+	}
+
+	return q, err
+}
+
+// Params documents the query parameters of the list, a filter per field and operator.
+// Equality filters are documented in their short form, filter[field].
+func Params[T any](spec *keyset.Spec[T]) []openapi.Param {
+	one, limit := 1.0, float64(spec.MaxLimit)
+	params := []openapi.Param{
+		{Name: "limit", In: openapi.InQuery, Schema: &openapi.Schema{Type: "integer", Minimum: &one, Maximum: &limit},
+			Description: "Items per page, " + strconv.Itoa(spec.DefaultLimit) + " by default"},
+		{Name: "cursor", In: openapi.InQuery, Description: "The next_cursor of the previous page",
+			Schema: &openapi.Schema{Type: "string"}},
+		{Name: "sort", In: openapi.InQuery, Schema: &openapi.Schema{Type: "string"},
+			Description: "Comma separated fields, descending when prefixed with -, " + spec.DefaultSort + " by default. " +
+				"Sortable: " + strings.Join(spec.Sortable(), ", ")},
+	}
+
+	for _, f := range spec.Fields {
+		for _, op := range f.Filters {
+			name := "filter[" + f.Name + "]"
+			if op != keyset.Eq {
+				name += "[" + string(op) + "]"
+			}
+
+			param := openapi.Param{Name: name, In: openapi.InQuery, Schema: schema(f.Type)}
+			if op == keyset.In {
+				param.Description = "Comma separated values"
+				param.Schema = &openapi.Schema{Type: "string"}
+			}
+
+			params = append(params, param)
+		}
+	}
+
+	return params
+}
+
+func schema(t keyset.Type) *openapi.Schema {
+	switch t {
+	case keyset.Int:
+		return &openapi.Schema{Type: "integer"}
+	case keyset.Bool:
+		return &openapi.Schema{Type: "boolean"}
+	case keyset.Time:
+		return &openapi.Schema{Type: "string", Format: "date-time"}
+	case keyset.UUID:
+		return &openapi.Schema{Type: "string", Format: "uuid"}
+	default:
+		return &openapi.Schema{Type: "string"}
+	}
+}
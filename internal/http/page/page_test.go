@@ -0,0 +1,126 @@
+package page
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kiennyo/syncwatch-be/internal/db/keyset"
+	httperr "github.com/kiennyo/syncwatch-be/internal/http/error"
+)
+
+type item struct {
+	ID      string
+	Name    string
+	Created time.Time
+}
+
+var spec = &keyset.Spec[item]{
+	Fields: []keyset.Field[item]{
+		{Name: "id", Column: "i.id", Type: keyset.UUID, Sortable: true, Value: func(i item) any { return i.ID }},
+		{Name: "name", Column: "i.name", Type: keyset.String, Sortable: true,
+			Filters: []keyset.Op{keyset.Eq, keyset.Contains}, Value: func(i item) any { return i.Name }},
+		{Name: "created_at", Column: "i.created_at", Type: keyset.Time, Sortable: true,
+			Filters: []keyset.Op{keyset.Gte}, Value: func(i item) any { return i.Created }},
+		{Name: "size", Column: "i.size", Type: keyset.Int, Filters: []keyset.Op{keyset.Eq}},
+	},
+	Key:          "id",
+	DefaultSort:  "created_at",
+	DefaultLimit: 2,
+	MaxLimit:     10,
+}
+
+var signer = keyset.NewSigner("secret")
+
+// fields renders err and returns the messages of its field violations.
+func fields(t *testing.T, err error) map[string]string {
+	w := httptest.NewRecorder()
+	httperr.Render(w, httptest.NewRequest(http.MethodGet, "/", nil), err)
+
+	var p httperr.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	require.Equal(t, httperr.CodeValidation, p.Code)
+
+	got := make(map[string]string)
+	for _, f := range p.Errors {
+		got[f.Field] = f.Detail
+	}
+
+	return got
+}
+
+func TestParse(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet,
+		"/items?limit=11&sort=size&filter[size][gt]=1&filter[created_at][gte]=yesterday&filter[x=1", nil)
+
+	_, err := Parse(r, spec, signer)
+
+	assert.Equal(t, map[string]string{
+		"limit":                   "must be a number between 1 and 10",
+		"sort":                    `can't sort by "size", use one of id, name, created_at`,
+		"filter[size][gt]":        "is not a supported filter",
+		"filter[created_at][gte]": "must be an RFC 3339 timestamp",
+		"filter[x":                "must be written filter[field] or filter[field][operator]",
+	}, fields(t, err))
+
+	r = httptest.NewRequest(http.MethodGet, "/items?cursor=eyJhIjpbXX0.c2ln", nil)
+	_, err = Parse(r, spec, signer)
+	assert.Equal(t, map[string]string{"cursor": "is invalid or doesn't match the sort and filters"}, fields(t, err))
+}
+
+func TestBuild(t *testing.T) {
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	items := []item{
+		{ID: "0b6c3f0e-1111-4c1e-9d7a-1c5b0f3e2a01", Name: "a", Created: created},
+		{ID: "0b6c3f0e-2222-4c1e-9d7a-1c5b0f3e2a02", Name: "b", Created: created.Add(time.Minute)},
+		{ID: "0b6c3f0e-3333-4c1e-9d7a-1c5b0f3e2a03", Name: "c", Created: created.Add(2 * time.Minute)},
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/items?filter[name][contains]=x", nil)
+	q, err := Parse(r, spec, signer)
+	require.NoError(t, err)
+
+	got, p := Build(q, items, signer)
+	assert.Equal(t, items[:2], got)
+	assert.True(t, p.HasMore)
+	require.NotEmpty(t, p.NextCursor)
+
+	t.Run("Last page", func(t *testing.T) {
+		got, p := Build(q, items[:2], signer)
+
+		assert.Len(t, got, 2)
+		assert.False(t, p.HasMore)
+		assert.Empty(t, p.NextCursor)
+	})
+
+	t.Run("Links", func(t *testing.T) {
+		h := p.Header(r)
+
+		assert.Equal(t, []string{
+			`</items?filter%5Bname%5D%5Bcontains%5D=x&limit=2>; rel="first"`,
+			`</items?cursor=` + p.NextCursor + `&filter%5Bname%5D%5Bcontains%5D=x&limit=2>; rel="next"`,
+		}, h.Values("Link"))
+	})
+}
+
+func TestParams(t *testing.T) {
+	names := make(map[string]string)
+	for _, p := range Params(spec) {
+		names[p.Name] = p.Schema.Type + p.Schema.Format
+	}
+
+	assert.Equal(t, map[string]string{
+		"limit":                   "integer",
+		"cursor":                  "string",
+		"sort":                    "string",
+		"filter[name]":            "string",
+		"filter[name][contains]":  "string",
+		"filter[created_at][gte]": "stringdate-time",
+		"filter[size]":            "integer",
+	}, names)
+}
@@ -0,0 +1,50 @@
+package page
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/kiennyo/syncwatch-be/internal/db/keyset"
+)
+
+// Page describes the page in the response envelope. NextCursor is empty on the last page.
+type Page struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty" doc:"Pass as cursor to fetch the next page"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// Build trims the extra item fetched by the query and describes the page, with the
+// cursor of the next one.
+func Build[T any](q *keyset.Query[T], items []T, signer *keyset.Signer) ([]T, *Page) {
+	items, next := q.Cut(items, signer)
+
+	return items, &Page{Limit: q.Limit, NextCursor: next, HasMore: next != ""}
+}
+
+// Header links the first and next pages, keeping the other query parameters of r.
+func (p *Page) Header(r *http.Request) http.Header {
+	h := http.Header{}
+
+	h.Add("Link", `<`+p.link(r, "")+`>; rel="first"`)
+	if p.HasMore {
+		h.Add("Link", `<`+p.link(r, p.NextCursor)+`>; rel="next"`)
+	}
+
+	return h
+}
+
+func (p *Page) link(r *http.Request, cursor string) string {
+	values := r.URL.Query()
+
+	values.Del("cursor")
+	if cursor != "" {
+		values.Set("cursor", cursor)
+	}
+	values.Set("limit", strconv.Itoa(p.Limit))
+
+	u := *r.URL
+	u.RawQuery = values.Encode()
+
+	return u.RequestURI()
+}
@@ -0,0 +1,38 @@
+// Package requestid assigns every request an ID, taken from the X-Request-ID header
+// when a proxy already set one, and echoes it in the response.
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+const Header = "X-Request-ID"
+
+type contextKey struct{}
+
+// validRX keeps client supplied IDs from injecting arbitrary content into logs.
+var validRX = regexp.MustCompile(`^[A-Za-z0-9._:\-]{1,128}$`)
+
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !validRX.MatchString(id) {
+			id = uuid.NewString()
+		}
+
+		w.Header().Set(Header, id)
+
+		ctx := context.WithValue(r.Context(), contextKey{}, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// FromContext returns the ID of the request, empty outside of requests.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
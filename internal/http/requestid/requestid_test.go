@@ -0,0 +1,46 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "Generated", incoming: "", keep: false},
+		{name: "Propagated", incoming: "lb-1234.abcd", keep: true},
+		{name: "Invalid replaced", incoming: "bad id\nwith newline", keep: false},
+		{name: "Too long replaced", incoming: strings.Repeat("a", 129), keep: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var seen string
+			handler := Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				seen = FromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(Header, tc.incoming)
+			res := httptest.NewRecorder()
+
+			handler.ServeHTTP(res, req)
+
+			assert.Equal(t, seen, res.Header().Get(Header))
+			if tc.keep {
+				assert.Equal(t, tc.incoming, seen)
+			} else {
+				assert.NoError(t, uuid.Validate(seen))
+			}
+		})
+	}
+}
@@ -2,36 +2,116 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 
 	"github.com/kiennyo/syncwatch-be/internal/config"
+	"github.com/kiennyo/syncwatch-be/internal/health"
+	"github.com/kiennyo/syncwatch-be/internal/http/cors"
+	httperr "github.com/kiennyo/syncwatch-be/internal/http/error"
+	"github.com/kiennyo/syncwatch-be/internal/http/idempotency"
+	"github.com/kiennyo/syncwatch-be/internal/http/openapi"
+	"github.com/kiennyo/syncwatch-be/internal/http/requestid"
+	"github.com/kiennyo/syncwatch-be/internal/http/version"
 	"github.com/kiennyo/syncwatch-be/internal/security"
 	"github.com/kiennyo/syncwatch-be/internal/worker"
 )
 
+// Routes is a module of the API, its router and the operations documenting it.
+type Routes interface {
+	Handlers() chi.Router
+	Operations() []openapi.Operation
+}
+
+// apiVersion holds the modules of a version by the path they're mounted at.
+type apiVersion struct {
+	version.Version
+	routes map[string]Routes
+}
+
 type Server struct {
-	config config.HTTP
-	routes map[string]chi.Router
-	tokens *security.TokensFactory
+	config   config.HTTP
+	versions []*apiVersion
+	auth     *security.AuthMiddleware
+	health   *health.Checker
+	cors     *cors.Policy
+	idem     *idempotency.Middleware
+
+	validateResponses bool
 }
 
 func (s *Server) Serve() error {
-	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%d", s.config.Port),
-		Handler:      s.handler(),
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  time.Minute,
+	handler := http.Handler(s.handler())
+	if s.config.H2C {
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
+
+	srv := s.newServer(handler)
+	servers := []*http.Server{srv}
+
+	listener, err := s.listen()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	if s.config.TLS() {
+		certs, err := newCertReloader(s.config.TLSCertFile, s.config.TLSKeyFile)
+		if err != nil {
+			return err
+		}
+		go certs.watch(ctx, s.config.TLSReloadInterval)
+
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
+
+		if s.config.RedirectPort != 0 {
+			redirect := s.newServer(redirectToHTTPS(s.config.Port))
+			redirect.Addr = net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.RedirectPort))
+			servers = append(servers, redirect)
+
+			go func() {
+				slog.Info("starting HTTPS redirect...", "addr", redirect.Addr)
+				if err := redirect.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+					slog.Error("HTTPS redirect stopped", "reason", err.Error())
+				}
+			}()
+		}
+	}
+
+	if s.config.AdminPort != 0 {
+		admin := s.newServer(adminHandler())
+		admin.Addr = net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.AdminPort))
+		servers = append(servers, admin)
+
+		go func() {
+			slog.Info("starting admin server...", "addr", admin.Addr)
+			if err := admin.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("Admin server stopped", "reason", err.Error())
+			}
+		}()
 	}
 
 	shutdownError := make(chan error)
@@ -42,23 +122,35 @@ func (s *Server) Serve() error {
 		sig := <-quit
 		slog.Info("caught signal: ", "sig", sig.String())
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if s.health != nil {
+			s.health.Drain()
+
+			slog.Info("draining traffic", "delay", s.config.DrainDelay.String())
+			time.Sleep(s.config.DrainDelay)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
 		defer cancel()
 
-		err := srv.Shutdown(ctx)
-		if err != nil {
-			shutdownError <- err
+		var errs []error
+		for _, server := range servers {
+			errs = append(errs, server.Shutdown(ctx))
 		}
 
 		slog.Info("completing background tasks")
 
 		worker.Wait()
-		shutdownError <- nil
+		shutdownError <- errors.Join(errs...)
 	}()
 
-	slog.Info("starting server...")
+	slog.Info("starting server...", "addr", listener.Addr().String(), "tls", s.config.TLS(), "h2c", s.config.H2C)
+
+	if s.config.TLS() {
+		err = srv.ServeTLS(listener, "", "")
+	} else {
+		err = srv.Serve(listener)
+	}
 
-	err := srv.ListenAndServe()
 	if !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
@@ -68,37 +160,261 @@ func (s *Server) Serve() error {
 		return err
 	}
 
-	slog.Info("stopped server", "addr", srv.Addr)
+	slog.Info("stopped server", "addr", listener.Addr().String())
+
+	return nil
+}
+
+func (s *Server) newServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadTimeout:       s.config.ReadTimeout,
+		ReadHeaderTimeout: s.config.HeaderTimeout,
+		WriteTimeout:      s.config.WriteTimeout,
+		IdleTimeout:       s.config.IdleTimeout,
+	}
+}
+
+func (s *Server) listen() (net.Listener, error) {
+	if s.config.UnixSocket != "" {
+		// a socket file left behind by a killed instance would make the listen fail
+		err := os.Remove(s.config.UnixSocket)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+
+		return net.Listen("unix", s.config.UnixSocket)
+	}
+
+	return net.Listen("tcp", net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port)))
+}
+
+// redirectToHTTPS permanently redirects to the same URL on the HTTPS port,
+// 308 is used so clients keep the method and body.
+func redirectToHTTPS(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+
+		if port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+
+		target := url.URL{Scheme: "https", Host: host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+		http.Redirect(w, r, target.String(), http.StatusPermanentRedirect)
+	})
+}
+
+// AddVersion adds a version of the API, served under /<name>. The first one added also
+// serves the unversioned paths of requests without an API-Version header, those clients
+// predate versioning.
+func (s *Server) AddVersion(v version.Version) *Server {
+	if s.lookupVersion(v.Name) != nil {
+		panic(fmt.Sprintf("http: version %q added twice", v.Name))
+	}
+
+	s.versions = append(s.versions, &apiVersion{Version: v, routes: make(map[string]Routes)})
+	return s
+}
+
+// AddRoutes mounts a module at path of a version added before, so the same path can
+// be served by another module in each version. Its operations are added to the
+// OpenAPI document.
+func (s *Server) AddRoutes(name, path string, routes Routes) *Server {
+	v := s.lookupVersion(name)
+	if v == nil {
+		panic(fmt.Sprintf("http: routes added to unknown version %q", name))
+	}
+
+	v.routes[path] = routes
+	return s
+}
+
+func (s *Server) lookupVersion(name string) *apiVersion {
+	for _, v := range s.versions {
+		if v.Name == name {
+			return v
+		}
+	}
 
 	return nil
 }
 
-func (s *Server) AddRoutes(path string, routes chi.Router) *Server {
-	s.routes[path] = routes
+// AddHealth serves /healthz and /readyz, readiness fails once shutdown starts.
+func (s *Server) AddHealth(checker *health.Checker) *Server {
+	s.health = checker
 	return s
 }
 
-func New(c config.HTTP, tokens *security.TokensFactory) *Server {
+// AddCORS lets browsers on the allowed origins call the API.
+func (s *Server) AddCORS(policy *cors.Policy) *Server {
+	s.cors = policy
+	return s
+}
+
+// AddIdempotency replays the stored response to retries sending the same Idempotency-Key.
+func (s *Server) AddIdempotency(m *idempotency.Middleware) *Server {
+	s.idem = m
+	return s
+}
+
+// AddResponseValidation checks responses against the OpenAPI document as well as
+// requests, see openapi.Document.Validator. It's meant for environments other than
+// production, every response is buffered.
+func (s *Server) AddResponseValidation() *Server {
+	s.validateResponses = true
+	return s
+}
+
+func New(c config.HTTP, auth *security.AuthMiddleware) *Server {
 	return &Server{
 		config: c,
-		routes: make(map[string]chi.Router),
-		tokens: tokens,
+		auth:   auth,
 	}
 }
 
 func (s *Server) handler() *chi.Mux {
-	authMiddleware := security.AuthMiddleware{
-		Tokens: s.tokens,
-	}
-
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	r.NotFound(httperr.NotFoundHandler)
+	r.MethodNotAllowed(httperr.MethodNotAllowedHandler)
+	r.Use(requestid.Middleware)
+	r.Use(traceRequests)
+	r.Use(logRequests)
+	r.Use(instrument)
 	r.Use(middleware.Recoverer)
-	r.Use(authMiddleware.Authenticate)
+	r.Use(securityHeaders(s.config))
 
-	for path, routes := range s.routes {
-		r.Mount(path, routes)
+	// preflights carry no credentials, they are answered before authentication
+	if s.cors != nil {
+		r.Use(s.cors.Middleware)
+	}
+
+	r.Use(negotiate)
+
+	// the middlewares below see the versioned path, e.g. the validator and idempotency keys
+	if len(s.versions) > 0 {
+		r.Use(version.Select(s.versions[0].Name, s.versionNames(), s.modulePaths()))
+	}
+
+	r.Use(s.auth.Authenticate)
+	r.Use(s.auth.CSRF)
+
+	// requests the document doesn't allow don't get to claim an idempotency key
+	doc := s.document()
+	r.Use(doc.Validator(s.validateResponses).Middleware)
+
+	// keys are scoped to the principal, and forged requests must not reach the store
+	if s.idem != nil {
+		r.Use(s.idem.Handler)
+	}
+
+	if s.health != nil {
+		r.Get("/healthz", s.health.Live)
+		r.Get("/readyz", s.health.Ready)
+	}
+
+	r.Get("/openapi.json", doc.Handler())
+	r.Get("/docs", openapi.Docs("/openapi.json"))
+
+	for _, v := range s.versions {
+		r.Route("/"+v.Name, func(r chi.Router) {
+			r.Use(v.Middleware)
+
+			for path, routes := range v.routes {
+				r.Mount(path, routes.Handlers())
+			}
+		})
 	}
 
 	return r
 }
+
+func (s *Server) versionNames() []string {
+	names := make([]string, len(s.versions))
+	for i, v := range s.versions {
+		names[i] = v.Name
+	}
+
+	return names
+}
+
+// modulePaths lists the paths modules are mounted at in any version.
+func (s *Server) modulePaths() []string {
+	var paths []string
+	for _, v := range s.versions {
+		for path := range v.routes {
+			if !slices.Contains(paths, path) {
+				paths = append(paths, path)
+			}
+		}
+	}
+	slices.Sort(paths)
+
+	return paths
+}
+
+var docsOperations = []openapi.Operation{
+	{
+		Method: http.MethodGet, Path: "/openapi.json", ID: "openapi", Summary: "OpenAPI document", Tags: []string{"docs"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: map[string]any{}}},
+	},
+	{
+		Method: http.MethodGet, Path: "/docs", ID: "docs", Summary: "API reference", Tags: []string{"docs"},
+		Responses: []openapi.Response{{Status: http.StatusOK, ContentType: "text/html", Body: ""}},
+	},
+}
+
+// document describes the routes of handler, modules by version and in the order of
+// their paths so schema names don't depend on map iteration. Operation IDs are prefixed
+// with the version, e.g. v1GetUser, they're unique across versions.
+func (s *Server) document() *openapi.Document {
+	info := openapi.Info{Title: "Syncwatch API", Version: "1.0.0"}
+	if len(s.versions) > 0 {
+		info.Description = "Modules are served under the version, e.g. /" + s.versions[0].Name +
+			"/users. Requests to unversioned paths are served by the version in the " + version.Header +
+			" header, " + s.versions[0].Name + " without it."
+	}
+
+	d := openapi.New(info)
+	d.Add("", docsOperations...)
+
+	if s.health != nil {
+		d.Add("", s.health.Operations()...)
+	}
+
+	for _, v := range s.versions {
+		paths := make([]string, 0, len(v.routes))
+		for path := range v.routes {
+			paths = append(paths, path)
+		}
+		slices.Sort(paths)
+
+		for _, path := range paths {
+			ops := slices.Clone(v.routes[path].Operations())
+
+			for i, op := range ops {
+				if op.ID == "" {
+					panic(fmt.Sprintf("http: operation %s %s of version %q has no id", op.Method, path+op.Path, v.Name))
+				}
+
+				ops[i].ID = v.Name + strings.ToUpper(op.ID[:1]) + op.ID[1:]
+				ops[i].Deprecated = !v.Deprecated.IsZero()
+
+				if s.idem != nil && (op.Method == http.MethodPost || op.Method == http.MethodPatch) {
+					ops[i].Params = append(slices.Clip(op.Params), idempotencyParam)
+				}
+			}
+
+			d.Add("/"+v.Name+path, ops...)
+		}
+	}
+
+	return d
+}
+
+var idempotencyParam = openapi.Param{
+	Name: idempotency.Header, In: openapi.InHeader, Schema: &openapi.Schema{Type: "string"},
+	Description: "Retries with the same key replay the first response instead of repeating the request",
+}
@@ -0,0 +1,303 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kiennyo/syncwatch-be/internal/config"
+	"github.com/kiennyo/syncwatch-be/internal/domain/users"
+	"github.com/kiennyo/syncwatch-be/internal/health"
+	"github.com/kiennyo/syncwatch-be/internal/http/cors"
+	"github.com/kiennyo/syncwatch-be/internal/http/idempotency"
+	"github.com/kiennyo/syncwatch-be/internal/http/openapi"
+	"github.com/kiennyo/syncwatch-be/internal/http/version"
+	"github.com/kiennyo/syncwatch-be/internal/mail"
+	"github.com/kiennyo/syncwatch-be/internal/ratelimit"
+	"github.com/kiennyo/syncwatch-be/internal/security"
+)
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		name     string
+		port     int
+		target   string
+		expected string
+	}{
+		{
+			name:     "Default port",
+			port:     443,
+			target:   "http://syncwatch.io/users?a=b",
+			expected: "https://syncwatch.io/users?a=b",
+		},
+		{
+			name:     "Custom port",
+			port:     8443,
+			target:   "http://syncwatch.io:8080/users",
+			expected: "https://syncwatch.io:8443/users",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.target, nil)
+			res := httptest.NewRecorder()
+
+			redirectToHTTPS(tc.port).ServeHTTP(res, req)
+
+			assert.Equal(t, http.StatusPermanentRedirect, res.Code)
+			assert.Equal(t, tc.expected, res.Header().Get("Location"))
+		})
+	}
+}
+
+func TestServer_ListenUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "api.sock")
+
+	// stale socket file from a previous run
+	assert.NoError(t, os.WriteFile(socket, nil, 0o600))
+
+	s := New(config.HTTP{UnixSocket: socket}, nil)
+	listener, err := s.listen()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "unix", listener.Addr().Network())
+	assert.NoError(t, listener.Close())
+}
+
+func testServer(t *testing.T) *Server {
+	limiter, err := ratelimit.New(config.RateLimit{}, ratelimit.NewMemoryStore())
+	require.NoError(t, err)
+
+	auth := security.NewAuthMiddleware(security.NewTokenFactory(config.Security{}), config.Security{})
+
+	return New(config.HTTP{}, auth).
+		AddIdempotency(idempotency.New(config.Idempotency{}, nil, nil)).
+		AddHealth(health.New(time.Second)).
+		AddVersion(version.Version{Name: "v1"}).
+		AddRoutes("v1", "/users", users.NewHandler(nil, limiter, nil)).
+		AddRoutes("v1", "/mail", mail.NewHandler(nil, nil, nil, nil, ""))
+}
+
+// TestServer_Documented fails when a module registers a route without an operation.
+func TestServer_Documented(t *testing.T) {
+	s := testServer(t)
+
+	assert.Empty(t, s.document().Undocumented(s.handler()))
+}
+
+func TestServer_ValidatesAgainstDocument(t *testing.T) {
+	handler := testServer(t).AddResponseValidation().handler()
+
+	r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"","email":"x","extra":1}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	for _, field := range []string{`"field":"name"`, `"field":"email"`, `"field":"extra"`, `"field":"password"`} {
+		assert.Contains(t, w.Body.String(), field)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+}
+
+// things is a module whose list differs by version.
+type things string
+
+func (t things) Handlers() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte(t)) })
+
+	return r
+}
+
+func (t things) Operations() []openapi.Operation {
+	return []openapi.Operation{{Method: http.MethodGet, Path: "/", ID: "listThings"}}
+}
+
+// unnamed is a module with an operation that lacks an ID.
+type unnamed struct{ things }
+
+func (unnamed) Operations() []openapi.Operation {
+	return []openapi.Operation{{Method: http.MethodGet, Path: "/"}}
+}
+
+func TestServer_Versions(t *testing.T) {
+	s := New(config.HTTP{}, security.NewAuthMiddleware(security.NewTokenFactory(config.Security{}), config.Security{})).
+		AddVersion(version.Version{Name: "v1", Deprecated: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}).
+		AddVersion(version.Version{Name: "v2"}).
+		AddRoutes("v1", "/things", things("one")).
+		AddRoutes("v2", "/things", things("two"))
+	handler := s.handler()
+
+	tests := []struct {
+		name       string
+		target     string
+		header     string
+		wantBody   string
+		deprecated bool
+	}{
+		{name: "Versioned path", target: "/v2/things", wantBody: "two"},
+		{name: "Deprecated version", target: "/v1/things", wantBody: "one", deprecated: true},
+		{name: "Header", target: "/things", header: "v2", wantBody: "two"},
+		{name: "Default", target: "/things", wantBody: "one", deprecated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				r.Header.Set(version.Header, tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.wantBody, w.Body.String())
+			assert.Equal(t, tt.deprecated, w.Header().Get(version.DeprecationHeader) != "")
+		})
+	}
+
+	d := s.document()
+	assert.Empty(t, d.Undocumented(handler))
+	require.NotNil(t, d.Paths["/v1/things"]["get"])
+	assert.Equal(t, "v1ListThings", d.Paths["/v1/things"]["get"].OperationID)
+	assert.True(t, d.Paths["/v1/things"]["get"].Deprecated)
+	assert.False(t, d.Paths["/v2/things"]["get"].Deprecated)
+	assert.Panics(t, func() { s.AddRoutes("v3", "/things", things("three")) })
+
+	s.AddRoutes("v2", "/unnamed", unnamed{things("none")})
+	assert.PanicsWithValue(t, `http: operation GET /unnamed/ of version "v2" has no id`, func() { s.document() })
+}
+
+// TestServer_CrossOriginCSRF plays a web player on another origin, which can't read
+// the cookies of the API host and echoes the token from the response header instead.
+func TestServer_CrossOriginCSRF(t *testing.T) {
+	const player = "https://player.syncwatch.io"
+
+	cfg := config.Security{JWTSecret: "secret", Iss: "syncwatch.io", Aud: "syncwatch.io",
+		AuthCookie: "session", CSRFCookie: "csrf_token"}
+	tokens := security.NewTokenFactory(cfg)
+	jwt, err := tokens.CreateToken("user", nil, security.Access)
+	require.NoError(t, err)
+
+	handler := New(config.HTTP{}, security.NewAuthMiddleware(tokens, cfg)).
+		AddCORS(cors.New(config.CORS{AllowedOrigins: []string{player}, AllowCredentials: true})).
+		AddVersion(version.Version{Name: "v1"}).
+		AddRoutes("v1", "/things", things("one")).
+		handler()
+
+	send := func(method string, cookies []*http.Cookie, csrf string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/things", nil)
+		r.Header.Set("Origin", player)
+		r.AddCookie(&http.Cookie{Name: "session", Value: jwt})
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		if csrf != "" {
+			r.Header.Set(security.CSRFHeader, csrf)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	w := send(http.MethodGet, nil, "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	csrf := w.Header().Get(security.CSRFHeader)
+	require.NotEmpty(t, csrf)
+	assert.Contains(t, w.Header().Get("Access-Control-Expose-Headers"), security.CSRFHeader)
+
+	// the browser sends the cookie back, the player the header it read
+	cookies := w.Result().Cookies() //nolint:bodyclose
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, cookies, "").Code)
+	assert.NotEqual(t, http.StatusForbidden, send(http.MethodPost, cookies, csrf).Code)
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	writeCert(t, certFile, keyFile, "first")
+
+	reloader, err := newCertReloader(certFile, keyFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", leafCommonName(t, reloader))
+
+	reloaded, err := reloader.reload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded)
+
+	writeCert(t, certFile, keyFile, "second")
+	future := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(certFile, future, future))
+
+	reloaded, err = reloader.reload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, "second", leafCommonName(t, reloader))
+
+	// a broken renewal keeps serving the previous certificate
+	assert.NoError(t, os.WriteFile(keyFile, []byte("broken"), 0o600))
+	future = future.Add(time.Minute)
+	assert.NoError(t, os.Chtimes(keyFile, future, future))
+
+	_, err = reloader.reload()
+	assert.Error(t, err)
+	assert.Equal(t, "second", leafCommonName(t, reloader))
+}
+
+func leafCommonName(t *testing.T, c *certReloader) string {
+	cert, err := c.GetCertificate(nil)
+	assert.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	assert.NoError(t, err)
+
+	return leaf.Subject.CommonName
+}
+
+func writeCert(t *testing.T, certFile, keyFile, commonName string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+
+	assert.NoError(t, os.WriteFile(certFile, certPem, 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, keyPem, 0o600))
+}
@@ -0,0 +1,147 @@
+// Package version lets the API change without breaking clients. Modules are mounted
+// under a prefix per version, e.g. /v1/users, and requests to the unversioned paths are
+// served by the version in the API-Version header. Deprecated versions keep working
+// until their sunset, their responses say so and their use is counted, so they can be
+// retired once clients moved on.
+package version
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	httperr "github.com/kiennyo/syncwatch-be/internal/http/error"
+)
+
+// Header selects the version of unversioned requests, and tells the version of
+// every versioned response.
+const Header = "API-Version"
+
+// Response headers of deprecated versions, RFC 9745 and RFC 8594.
+const (
+	DeprecationHeader = "Deprecation"
+	SunsetHeader      = "Sunset"
+)
+
+const (
+	codeUnsupported = "unsupported_api_version"
+	codeSunset      = "api_version_sunset"
+)
+
+var deprecatedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "syncwatch",
+	Subsystem: "http",
+	Name:      "deprecated_requests_total",
+	Help:      "Requests to deprecated API versions by version and route pattern.",
+}, []string{"version", "route"})
+
+// Version is a version of the API, mounted at /Name.
+type Version struct {
+	Name string
+	// Deprecated is when clients were, or will be, asked to move off the version, zero
+	// while it's supported.
+	Deprecated time.Time
+	// Sunset is when the version stops being served, zero until it's decided.
+	Sunset time.Time
+}
+
+// Middleware serves the routes of the version. Responses carry the version, and once
+// it's deprecated the Deprecation and Sunset headers. Past its sunset it answers 410.
+func (v Version) Middleware(next http.Handler) http.Handler {
+	var deprecation, sunset string
+	if !v.Deprecated.IsZero() {
+		deprecation = "@" + strconv.FormatInt(v.Deprecated.Unix(), 10)
+	}
+	if !v.Sunset.IsZero() {
+		sunset = v.Sunset.UTC().Format(http.TimeFormat)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set(Header, v.Name)
+
+		if deprecation != "" {
+			h.Set(DeprecationHeader, deprecation)
+
+			// the pattern is complete once the request was routed
+			defer func() {
+				route := "unmatched"
+				if pattern := chi.RouteContext(r.Context()).RoutePattern(); pattern != "" {
+					route = pattern
+				}
+				deprecatedRequests.WithLabelValues(v.Name, route).Inc()
+			}()
+		}
+
+		if sunset != "" {
+			h.Set(SunsetHeader, sunset)
+
+			if !time.Now().Before(v.Sunset) {
+				httperr.Render(w, r, httperr.New(http.StatusGone, codeSunset,
+					"API version "+v.Name+" is no longer served, see the docs for its successor"))
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Select serves requests to the unversioned paths of modules, e.g. /users, from the
+// version in the API-Version header, or from def without the header. def is meant to
+// be the version the clients that predate versioning were built against, so they
+// keep getting the responses they expect. Requests with a version in the path are
+// left alone, the path wins over the header.
+func Select(def string, versions, paths []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !matches(paths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// caches must not serve the response of one version to another
+			w.Header().Add("Vary", Header)
+
+			name := r.Header.Get(Header)
+			if name == "" {
+				name = def
+			} else if !slices.Contains(versions, name) {
+				httperr.Render(w, r, httperr.New(http.StatusBadRequest, codeUnsupported,
+					"unsupported API version "+strconv.Quote(name)+", use one of "+strings.Join(versions, ", ")))
+				return
+			}
+
+			// the same shallow copy http.StripPrefix makes
+			r2 := new(http.Request)
+			*r2 = *r
+			r2.URL = new(url.URL)
+			*r2.URL = *r.URL
+			r2.URL.Path = "/" + name + r.URL.Path
+			if r.URL.RawPath != "" {
+				r2.URL.RawPath = "/" + name + r.URL.RawPath
+			}
+
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
+
+// matches tells whether p is one of paths or below it.
+func matches(paths []string, p string) bool {
+	for _, prefix := range paths {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,104 @@
+package version
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		header     string
+		wantStatus int
+		wantPath   string
+		wantVary   bool
+	}{
+		{name: "Default", target: "/users/1", wantStatus: http.StatusOK, wantPath: "/v1/users/1", wantVary: true},
+		{name: "Header", target: "/users", header: "v2", wantStatus: http.StatusOK, wantPath: "/v2/users", wantVary: true},
+		{name: "Unsupported", target: "/users", header: "v9", wantStatus: http.StatusBadRequest, wantVary: true},
+		{name: "Versioned path", target: "/v2/users", header: "v1", wantStatus: http.StatusOK, wantPath: "/v2/users"},
+		{name: "Other module", target: "/usersettings", header: "v9", wantStatus: http.StatusOK, wantPath: "/usersettings"},
+		{name: "Unversioned route", target: "/healthz", wantStatus: http.StatusOK, wantPath: "/healthz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			next := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) { path = r.URL.Path })
+
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				r.Header.Set(Header, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			Select("v1", []string{"v1", "v2"}, []string{"/users", "/mail"})(next).ServeHTTP(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantPath, path)
+			assert.Equal(t, tt.wantVary, w.Header().Get("Vary") == Header)
+		})
+	}
+}
+
+func TestVersion_Middleware(t *testing.T) {
+	deprecated := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name            string
+		version         Version
+		wantStatus      int
+		wantDeprecation string
+		wantSunset      string
+		wantCount       float64
+	}{
+		{name: "Supported", version: Version{Name: "supported"}, wantStatus: http.StatusNoContent},
+		{
+			name:            "Deprecated",
+			version:         Version{Name: "deprecated", Deprecated: deprecated, Sunset: sunset},
+			wantStatus:      http.StatusNoContent,
+			wantDeprecation: "@1767225600",
+			wantSunset:      sunset.UTC().Format(http.TimeFormat),
+			wantCount:       1,
+		},
+		{
+			name:            "Past sunset",
+			version:         Version{Name: "sunset", Deprecated: deprecated, Sunset: deprecated.Add(time.Hour)},
+			wantStatus:      http.StatusGone,
+			wantDeprecation: "@1767225600",
+			wantSunset:      "Thu, 01 Jan 2026 01:00:00 GMT",
+			wantCount:       1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := chi.NewRouter()
+			r.Route("/"+tt.version.Name, func(r chi.Router) {
+				r.Use(tt.version.Middleware)
+				r.Get("/users/{id}", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) })
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+tt.version.Name+"/users/1", nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.version.Name, w.Header().Get(Header))
+			assert.Equal(t, tt.wantDeprecation, w.Header().Get(DeprecationHeader))
+			assert.Equal(t, tt.wantSunset, w.Header().Get(SunsetHeader))
+
+			if tt.wantStatus == http.StatusNoContent {
+				route := "/" + tt.version.Name + "/users/{id}"
+				assert.Equal(t, tt.wantCount, testutil.ToFloat64(deprecatedRequests.WithLabelValues(tt.version.Name, route)))
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Package logger configures slog and carries a request scoped logger in the context,
+// so every log line of a request can be correlated by its request ID.
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/kiennyo/syncwatch-be/internal/config"
+)
+
+type contextKey struct{}
+
+// fields collects the attributes added while the request is handled, for the line
+// logged once it completes.
+type fields struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+type scope struct {
+	logger *slog.Logger
+	fields *fields
+}
+
+func New(w io.Writer, cfg config.Log) *slog.Logger {
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(cfg.Level)) // validated by config
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	if strings.EqualFold(cfg.Format, "text") {
+		return slog.New(slog.NewTextHandler(w, opts))
+	}
+
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
+
+// FromContext returns the logger of the request, or the default one outside of requests.
+func FromContext(ctx context.Context) *slog.Logger {
+	if s, ok := ctx.Value(contextKey{}).(*scope); ok {
+		return s.logger
+	}
+
+	return slog.Default()
+}
+
+// NewContext starts a request scope logging with l.
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, &scope{logger: l, fields: &fields{}})
+}
+
+// WithAttrs adds attributes to the logger of ctx and to the request completion line,
+// e.g. the user once authenticated.
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	s, ok := ctx.Value(contextKey{}).(*scope)
+	if !ok {
+		s = &scope{logger: slog.Default(), fields: &fields{}}
+	}
+
+	s.fields.mu.Lock()
+	s.fields.attrs = append(s.fields.attrs, attrs...)
+	s.fields.mu.Unlock()
+
+	args := make([]any, len(attrs))
+	for i, attr := range attrs {
+		args[i] = attr
+	}
+
+	return context.WithValue(ctx, contextKey{}, &scope{logger: s.logger.With(args...), fields: s.fields})
+}
+
+// Attrs returns the attributes added by WithAttrs within the request scope.
+func Attrs(ctx context.Context) []slog.Attr {
+	s, ok := ctx.Value(contextKey{}).(*scope)
+	if !ok {
+		return nil
+	}
+
+	s.fields.mu.Lock()
+	defer s.fields.mu.Unlock()
+
+	return append([]slog.Attr(nil), s.fields.attrs...)
+}
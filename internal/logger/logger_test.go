@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiennyo/syncwatch-be/internal/config"
+)
+
+func TestNew(t *testing.T) {
+	out := new(bytes.Buffer)
+	l := New(out, config.Log{Level: "warn", Format: "json"})
+
+	l.Info("hidden")
+	l.Warn("shown", "key", "value")
+
+	var line map[string]any
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &line))
+	assert.Equal(t, "shown", line["msg"])
+	assert.Equal(t, "value", line["key"])
+
+	out.Reset()
+	New(out, config.Log{Level: "debug", Format: "text"}).Debug("text line")
+	assert.Contains(t, out.String(), `level=DEBUG msg="text line"`)
+}
+
+func TestWithAttrs(t *testing.T) {
+	out := new(bytes.Buffer)
+	base := slog.New(slog.NewTextHandler(out, nil))
+
+	assert.Equal(t, slog.Default(), FromContext(context.Background()))
+
+	ctx := NewContext(context.Background(), base.With("request_id", "abc"))
+	child := WithAttrs(ctx, slog.String("user", "42"))
+
+	FromContext(child).Info("handled")
+	assert.Contains(t, out.String(), "request_id=abc user=42")
+
+	// attributes added deeper in the chain are visible to the request scope
+	assert.Equal(t, []slog.Attr{slog.String("user", "42")}, Attrs(ctx))
+	assert.Nil(t, Attrs(context.Background()))
+}
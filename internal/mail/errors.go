@@ -0,0 +1,14 @@
+package mail
+
+import "fmt"
+
+// SuppressedError is returned by Send when the recipient is on the suppression list,
+// e.g. after a hard bounce or a spam complaint.
+type SuppressedError struct {
+	Recipient string
+	Reason    string
+}
+
+func (e *SuppressedError) Error() string {
+	return fmt.Sprintf("recipient %s is suppressed: %s", e.Recipient, e.Reason)
+}
@@ -0,0 +1,321 @@
+package mail
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/kiennyo/syncwatch-be/internal/db"
+	httperr "github.com/kiennyo/syncwatch-be/internal/http/error"
+	"github.com/kiennyo/syncwatch-be/internal/http/json"
+	"github.com/kiennyo/syncwatch-be/internal/http/openapi"
+	"github.com/kiennyo/syncwatch-be/internal/logger"
+	"github.com/kiennyo/syncwatch-be/internal/security"
+	"github.com/kiennyo/syncwatch-be/internal/validator"
+)
+
+const webhookSecretHeader = "X-Webhook-Secret"
+
+const manageScope = "mail:manage"
+
+const (
+	codeInvalidWebhookSecret = "invalid_webhook_secret"
+	codeRecipientSuppressed  = "recipient_suppressed"
+)
+
+const (
+	eventBounce    = "bounce"
+	eventComplaint = "complaint"
+	bounceHard     = "hard"
+	bounceSoft     = "soft"
+)
+
+// event is the provider agnostic delivery notification accepted by the webhook.
+type event struct {
+	Type       string `json:"type" validate:"required,oneof=bounce|complaint"`
+	BounceType string `json:"bounce_type" doc:"hard or soft, required for bounces"`
+	Recipient  string `json:"recipient" validate:"required"`
+	Detail     string `json:"detail"`
+}
+
+type eventsInput struct {
+	Events []event `json:"events" validate:"required"`
+}
+
+type previewInput struct {
+	Locale string         `json:"locale" doc:"The default locale when empty"`
+	Data   map[string]any `json:"data" doc:"Template data"`
+}
+
+type testInput struct {
+	Recipient string         `json:"recipient" validate:"required,email"`
+	Locale    string         `json:"locale" doc:"The default locale when empty"`
+	Data      map[string]any `json:"data" doc:"Template data"`
+}
+
+type Handler struct {
+	repository Repository
+	transactor db.Transactor
+	templates  Renderer
+	sender     Sender
+	secret     string
+}
+
+func NewHandler(r Repository, tx db.Transactor, t Renderer, s Sender, webhookSecret string) *Handler {
+	return &Handler{
+		repository: r,
+		transactor: tx,
+		templates:  t,
+		sender:     s,
+		secret:     webhookSecret,
+	}
+}
+
+func (h *Handler) Handlers() chi.Router {
+	r := chi.NewRouter()
+	r.Post("/webhooks/events", httperr.Handle(h.events))
+	r.Get("/templates", security.Authorize(httperr.Handle(h.listTemplates), manageScope))
+	r.Post("/templates/{template}/preview", security.Authorize(httperr.Handle(h.preview), manageScope))
+	r.Post("/templates/{template}/test", security.Authorize(httperr.Handle(h.sendTest), manageScope))
+
+	return r
+}
+
+// Operations documents the routes of Handlers.
+func (h *Handler) Operations() []openapi.Operation {
+	template := openapi.Param{Name: "template", In: openapi.InPath, Description: "Template file, e.g. user_welcome.gohtml",
+		Schema: &openapi.Schema{Type: "string"}}
+	tags := []string{"mail"}
+
+	return []openapi.Operation{
+		{
+			Method: http.MethodPost, Path: "/webhooks/events", ID: "receiveMailEvents", Summary: "Receive delivery events",
+			Description: "Called by the mail provider, hard bounces and complaints suppress the recipient.",
+			Tags:        tags, Request: eventsInput{},
+			Params: []openapi.Param{
+				{Name: webhookSecretHeader, In: openapi.InHeader, Required: true, Schema: &openapi.Schema{Type: "string"}},
+			},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: json.Envelope{"processed": 0}},
+				{Status: http.StatusUnauthorized},
+				{Status: http.StatusUnprocessableEntity},
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/templates", ID: "listMailTemplates", Summary: "List templates", Tags: tags,
+			Scope: manageScope,
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: json.Envelope{"templates": []string{}, "locales": []string{}, "default_locale": ""}},
+			},
+		},
+		{
+			Method: http.MethodPost, Path: "/templates/{template}/preview", ID: "previewMailTemplate",
+			Summary: "Preview a template", Tags: tags, Scope: manageScope, Request: previewInput{},
+			Params: []openapi.Param{template, {Name: "part", In: openapi.InQuery,
+				Description: "Returns the raw body of the part instead of JSON",
+				Schema:      &openapi.Schema{Type: "string", Enum: []any{"html", "plain"}}}},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: json.Envelope{"content": &Content{}}},
+				{Status: http.StatusOK, ContentType: "text/html", Body: ""},
+				{Status: http.StatusOK, ContentType: "text/plain", Body: ""},
+				{Status: http.StatusNotFound},
+				{Status: http.StatusUnprocessableEntity},
+			},
+		},
+		{
+			Method: http.MethodPost, Path: "/templates/{template}/test", ID: "sendTestMail", Summary: "Send a test email",
+			Tags: tags, Scope: manageScope, Request: testInput{}, Params: []openapi.Param{template},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: json.Envelope{"message": ""}},
+				{Status: http.StatusNotFound},
+				{Status: http.StatusConflict, Description: "The recipient is suppressed"},
+				{Status: http.StatusUnprocessableEntity},
+			},
+		},
+	}
+}
+
+func (h *Handler) events(w http.ResponseWriter, r *http.Request) error {
+	secret := r.Header.Get(webhookSecretHeader)
+	if h.secret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(h.secret)) != 1 {
+		return httperr.New(http.StatusUnauthorized, codeInvalidWebhookSecret, "invalid or missing webhook secret")
+	}
+
+	var input eventsInput
+
+	if err := json.Read(w, r, &input); err != nil {
+		return err
+	}
+
+	v := validator.New()
+	if err := v.Struct(input); err != nil {
+		return err
+	}
+
+	if validateBounceTypes(v, input.Events); !v.Valid() {
+		return httperr.Validation(v.Violations())
+	}
+
+	// all or nothing, the provider redelivers the whole batch when the request fails
+	err := h.transactor.InTx(r.Context(), func(ctx context.Context) error {
+		for _, e := range input.Events {
+			if err := h.process(ctx, e); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return json.Write(w, r, http.StatusOK, json.Envelope{"processed": len(input.Events)}, nil)
+}
+
+// process records the notification in the send log and suppresses the recipient on
+// hard bounces and complaints. Soft bounces are only logged.
+func (h *Handler) process(ctx context.Context, e event) error {
+	entry := &sendLog{
+		Recipient: e.Recipient,
+		Status:    statusBounced,
+		Detail:    e.Detail,
+	}
+
+	var reason string
+
+	switch {
+	case e.Type == eventComplaint:
+		entry.Status = statusComplained
+		reason = eventComplaint
+	case e.BounceType == bounceHard:
+		reason = "hard_bounce"
+	}
+
+	if reason != "" {
+		err := h.repository.Suppress(ctx, &suppression{
+			Email:  e.Recipient,
+			Reason: reason,
+			Detail: e.Detail,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return h.repository.Log(ctx, entry)
+}
+
+func (h *Handler) listTemplates(w http.ResponseWriter, r *http.Request) error {
+	env := json.Envelope{
+		"templates":      h.templates.Templates(),
+		"locales":        h.templates.Locales(),
+		"default_locale": DefaultLocale,
+	}
+
+	return json.Write(w, r, http.StatusOK, env, nil)
+}
+
+// preview renders a template with the supplied data. The ?part=html and ?part=plain
+// query parameters return the raw body, so it can be opened directly in a browser.
+func (h *Handler) preview(w http.ResponseWriter, r *http.Request) error {
+	var input previewInput
+
+	if err := json.Read(w, r, &input); err != nil {
+		return err
+	}
+
+	content, err := h.render(r, input.Locale, input.Data)
+	if err != nil {
+		return err
+	}
+
+	switch r.URL.Query().Get("part") {
+	case "html":
+		writeRaw(w, r, "text/html; charset=utf-8", content.HTMLBody)
+	case "plain":
+		writeRaw(w, r, "text/plain; charset=utf-8", content.PlainBody)
+	default:
+		return json.Write(w, r, http.StatusOK, json.Envelope{"content": content}, nil)
+	}
+
+	return nil
+}
+
+func (h *Handler) sendTest(w http.ResponseWriter, r *http.Request) error {
+	var input testInput
+
+	if err := json.Read(w, r, &input); err != nil {
+		return err
+	}
+
+	v := validator.New()
+	if err := v.Struct(input); err != nil {
+		return err
+	}
+
+	if !v.Valid() {
+		return httperr.Validation(v.Violations())
+	}
+
+	// render first, so template problems are reported as such instead of a failed send
+	if _, err := h.render(r, input.Locale, input.Data); err != nil {
+		return err
+	}
+
+	templateFile := chi.URLParam(r, "template")
+
+	err := h.sender.Send(r.Context(), input.Recipient, input.Locale, templateFile, input.Data)
+	if err != nil {
+		var suppressed *SuppressedError
+		if errors.As(err, &suppressed) {
+			return httperr.New(http.StatusConflict, codeRecipientSuppressed, err.Error())
+		}
+
+		return err
+	}
+
+	return json.Write(w, r, http.StatusOK, json.Envelope{"message": "Test email sent successfully"}, nil)
+}
+
+// render maps template problems to client errors.
+func (h *Handler) render(r *http.Request, locale string, data any) (*Content, error) {
+	content, err := h.templates.Render(locale, chi.URLParam(r, "template"), data)
+	if err != nil {
+		if errors.Is(err, ErrTemplateNotFound) {
+			return nil, httperr.NotFound()
+		}
+
+		// template errors name the missing data, there's no code for them
+		return nil, httperr.Validation(map[string]validator.Violation{
+			"data": {Code: validator.CodeInvalid, Message: err.Error()},
+		})
+	}
+
+	return content, nil
+}
+
+func writeRaw(w http.ResponseWriter, r *http.Request, contentType, body string) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+
+	_, err := w.Write([]byte(body))
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Write failed", "reason", err, "request_url", r.URL.String())
+	}
+}
+
+// validateBounceTypes checks what tags can't express, bounce types only go with bounces.
+func validateBounceTypes(v *validator.Validator, events []event) {
+	for i, e := range events {
+		v.CheckCode(
+			e.Type != eventBounce || e.BounceType == bounceHard || e.BounceType == bounceSoft,
+			fmt.Sprintf("events.%d.bounce_type", i),
+			"one_of", map[string]any{"values": []string{bounceHard, bounceSoft}},
+		)
+	}
+}
@@ -0,0 +1,253 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/kiennyo/syncwatch-be/internal/config"
+	"github.com/kiennyo/syncwatch-be/internal/security"
+)
+
+type senderMock struct {
+	mock.Mock
+}
+
+func (s *senderMock) Send(ctx context.Context, recipient, locale, templateFile string, data any) error {
+	args := s.Called(ctx, recipient, locale, templateFile, data)
+	return args.Error(0)
+}
+
+// transactorSpy runs fn in place and keeps what the transaction ended with.
+type transactorSpy struct {
+	calls int
+	err   error
+}
+
+func (tx *transactorSpy) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx.calls++
+	tx.err = fn(ctx)
+
+	return tx.err
+}
+
+//nolint:revive,function-length
+func TestHandler_Events(t *testing.T) {
+	tests := []struct {
+		name           string
+		secret         string
+		input          string
+		setup          func(r *repositoryMock)
+		expectedStatus int
+		expectedTx     int
+	}{
+		{
+			name:           "Missing secret",
+			input:          `{"events":[{"type":"complaint","recipient":"test@test.com"}]}`,
+			setup:          func(_ *repositoryMock) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Invalid event",
+			secret:         "secret",
+			input:          `{"events":[{"type":"bounce","bounce_type":"maybe","recipient":"test@test.com"}]}`,
+			setup:          func(_ *repositoryMock) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "Hard bounce suppresses recipient",
+			secret: "secret",
+			input:  `{"events":[{"type":"bounce","bounce_type":"hard","recipient":"test@test.com"}]}`,
+			setup: func(r *repositoryMock) {
+				r.On("Suppress", mock.Anything, &suppression{Email: "test@test.com", Reason: "hard_bounce"}).
+					Return(nil)
+				r.On("Log", mock.Anything, &sendLog{Recipient: "test@test.com", Status: statusBounced}).
+					Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedTx:     1,
+		},
+		{
+			name:   "Soft bounce is only logged",
+			secret: "secret",
+			input:  `{"events":[{"type":"bounce","bounce_type":"soft","recipient":"test@test.com"}]}`,
+			setup: func(r *repositoryMock) {
+				r.On("Log", mock.Anything, &sendLog{Recipient: "test@test.com", Status: statusBounced}).
+					Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedTx:     1,
+		},
+		{
+			name:   "Complaint suppresses recipient",
+			secret: "secret",
+			input:  `{"events":[{"type":"complaint","recipient":"test@test.com"}]}`,
+			setup: func(r *repositoryMock) {
+				r.On("Suppress", mock.Anything, &suppression{Email: "test@test.com", Reason: "complaint"}).
+					Return(nil)
+				r.On("Log", mock.Anything, &sendLog{Recipient: "test@test.com", Status: statusComplained}).
+					Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedTx:     1,
+		},
+		{
+			name:   "Failed event rolls back the batch",
+			secret: "secret",
+			input: `{"events":[{"type":"complaint","recipient":"test@test.com"},` +
+				`{"type":"bounce","bounce_type":"soft","recipient":"other@test.com"}]}`,
+			setup: func(r *repositoryMock) {
+				r.On("Suppress", mock.Anything, &suppression{Email: "test@test.com", Reason: "complaint"}).
+					Return(nil)
+				r.On("Log", mock.Anything, &sendLog{Recipient: "test@test.com", Status: statusComplained}).
+					Return(nil)
+				r.On("Log", mock.Anything, &sendLog{Recipient: "other@test.com", Status: statusBounced}).
+					Return(errors.New("connection reset"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedTx:     1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(repositoryMock)
+			tc.setup(repo)
+			tx := new(transactorSpy)
+			server := NewHandler(repo, tx, nil, nil, "secret").Handlers()
+
+			request, _ := http.NewRequest(http.MethodPost, "/webhooks/events", bytes.NewBufferString(tc.input))
+			request.Header.Set(webhookSecretHeader, tc.secret)
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, request)
+
+			assert.Equal(t, tc.expectedStatus, response.Code)
+			assert.Equal(t, tc.expectedTx, tx.calls)
+			assert.Equal(t, tc.expectedStatus == http.StatusInternalServerError, tx.err != nil, "rolled back")
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+//nolint:revive,function-length
+func TestHandler_Templates(t *testing.T) {
+	tmpls, err := loadTemplates(templateFS)
+	assert.NoError(t, err)
+
+	tokens := security.NewTokenFactory(config.Security{JWTSecret: "secret", Iss: "syncwatch.io", Aud: "syncwatch.io"})
+	admin, _ := tokens.CreateToken("admin", []string{manageScope}, security.Access)
+	user, _ := tokens.CreateToken("user", []string{"user:view"}, security.Access)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		input          string
+		setup          func(s *senderMock)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "List requires permission",
+			method:         http.MethodGet,
+			path:           "/templates",
+			token:          user,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "List",
+			method:         http.MethodGet,
+			path:           "/templates",
+			token:          admin,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "user_welcome.gohtml",
+		},
+		{
+			name:           "Preview",
+			method:         http.MethodPost,
+			path:           "/templates/user_welcome.gohtml/preview",
+			token:          admin,
+			input:          `{"locale":"lt","data":{"activationToken":"abc"}}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "Sveiki",
+		},
+		{
+			name:           "Preview HTML part",
+			method:         http.MethodPost,
+			path:           "/templates/user_welcome.gohtml/preview?part=html",
+			token:          admin,
+			input:          `{"data":{"activationToken":"abc"}}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "<!doctype html>",
+		},
+		{
+			name:           "Preview unknown template",
+			method:         http.MethodPost,
+			path:           "/templates/missing.gohtml/preview",
+			token:          admin,
+			input:          `{}`,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Test send invalid recipient",
+			method:         http.MethodPost,
+			path:           "/templates/user_welcome.gohtml/test",
+			token:          admin,
+			input:          `{"recipient":"nope"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "Test send",
+			method: http.MethodPost,
+			path:   "/templates/user_welcome.gohtml/test",
+			token:  admin,
+			input:  `{"recipient":"test@test.com","locale":"en","data":{"activationToken":"abc"}}`,
+			setup: func(s *senderMock) {
+				s.On("Send", mock.Anything, "test@test.com", "en", "user_welcome.gohtml",
+					map[string]any{"activationToken": "abc"}).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "Test send to suppressed recipient",
+			method: http.MethodPost,
+			path:   "/templates/user_welcome.gohtml/test",
+			token:  admin,
+			input:  `{"recipient":"test@test.com"}`,
+			setup: func(s *senderMock) {
+				s.On("Send", mock.Anything, "test@test.com", "", "user_welcome.gohtml", mock.Anything).
+					Return(&SuppressedError{Recipient: "test@test.com", Reason: "complaint"})
+			},
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sender := new(senderMock)
+			if tc.setup != nil {
+				tc.setup(sender)
+			}
+
+			am := &security.AuthMiddleware{Tokens: tokens}
+			server := am.Authenticate(NewHandler(new(repositoryMock), nil, tmpls, sender, "secret").Handlers())
+
+			request, _ := http.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.input))
+			request.Header.Set("Authorization", "Bearer "+tc.token)
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, request)
+
+			assert.Equal(t, tc.expectedStatus, response.Code)
+			assert.True(t, strings.Contains(response.Body.String(), tc.expectedBody))
+			sender.AssertExpectations(t)
+		})
+	}
+}
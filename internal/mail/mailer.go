@@ -1,50 +1,95 @@
 package mail
 
 import (
-	"bytes"
+	"context"
 	"embed"
-	"html/template"
+	"errors"
+	"net/textproto"
 	"time"
 
 	"github.com/go-mail/mail/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/kiennyo/syncwatch-be/internal/config"
+	"github.com/kiennyo/syncwatch-be/internal/logger"
+)
+
+const tracerName = "github.com/kiennyo/syncwatch-be/internal/mail"
+
+const (
+	maxAttempts    = 3
+	initialBackoff = 500 * time.Millisecond
 )
 
 type Sender interface {
-	Send(recipient, templateFile string, data any) error
+	Send(ctx context.Context, recipient, locale, templateFile string, data any) error
 }
 
 var _ Sender = (*mailer)(nil)
 
-//go:embed "templates"
+// all: is needed so the "_" prefixed partials get embedded too.
+//
+//go:embed all:templates
 var templateFS embed.FS
 
+var sends = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "syncwatch",
+	Subsystem: "mail",
+	Name:      "sends_total",
+	Help:      "Emails by send result: sent, failed or suppressed.",
+}, []string{"status"})
+
+type dialer interface {
+	DialAndSend(m ...*mail.Message) error
+}
+
 type mailer struct {
-	dialer *mail.Dialer
-	sender string
+	dialer     dialer
+	sender     string
+	templates  Renderer
+	repository Repository
+	backoff    time.Duration
 }
 
-func (m *mailer) Send(recipient, templateFile string, data any) error {
-	tmpl, err := template.New("email").ParseFS(templateFS, "templates/"+templateFile)
+func (m *mailer) Send(ctx context.Context, recipient, locale, templateFile string, data any) error {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "mail.Send", trace.WithAttributes(
+		attribute.String("mail.template", templateFile),
+		attribute.String("mail.locale", locale),
+	))
+	defer span.End()
+
+	err := m.send(ctx, recipient, locale, templateFile, data)
 	if err != nil {
-		return err
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 	}
 
-	subject := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(subject, "subject", data)
+	return err
+}
+
+func (m *mailer) send(ctx context.Context, recipient, locale, templateFile string, data any) error {
+	suppressed, err := m.repository.FindSuppression(ctx, recipient)
 	if err != nil {
 		return err
 	}
 
-	plainBody := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(plainBody, "plainBody", data)
-	if err != nil {
-		return err
+	if suppressed != nil {
+		m.log(ctx, &sendLog{
+			Recipient: recipient,
+			Template:  templateFile,
+			Status:    statusSuppressed,
+			Detail:    suppressed.Reason,
+		})
+
+		return &SuppressedError{Recipient: recipient, Reason: suppressed.Reason}
 	}
 
-	htmlBody := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(htmlBody, "htmlBody", data)
+	content, err := m.templates.Render(locale, templateFile, data)
 	if err != nil {
 		return err
 	}
@@ -52,28 +97,88 @@ func (m *mailer) Send(recipient, templateFile string, data any) error {
 	msg := mail.NewMessage()
 	msg.SetHeader("To", recipient)
 	msg.SetHeader("From", m.sender)
-	msg.SetHeader("Subject", subject.String())
-	msg.SetBody("text/plain", plainBody.String())
-	msg.AddAlternative("text/html", htmlBody.String())
+	msg.SetHeader("Subject", content.Subject)
+	msg.SetBody("text/plain", content.PlainBody)
+	msg.AddAlternative("text/html", content.HTMLBody)
+
+	attempts, err := m.dialAndSend(ctx, msg)
 
-	for i := 1; i <= 3; i++ {
+	entry := &sendLog{
+		Recipient: recipient,
+		Template:  templateFile,
+		Status:    statusSent,
+		Attempts:  attempts,
+	}
+	if err != nil {
+		entry.Status = statusFailed
+		entry.Detail = err.Error()
+	}
+	m.log(ctx, entry)
+
+	return err
+}
+
+// dialAndSend retries transient failures with an exponential backoff. Permanent (5xx)
+// SMTP replies are not retried as the server would reject the message again.
+func (m *mailer) dialAndSend(ctx context.Context, msg *mail.Message) (int, error) {
+	var err error
+
+	backoff := m.backoff
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
 		err = m.dialer.DialAndSend(msg)
-		if nil == err {
-			return nil
+		if err == nil {
+			return attempt, nil
+		}
+
+		if isPermanent(err) || attempt == maxAttempts {
+			return attempt, err
 		}
 
-		time.Sleep(500 * time.Millisecond)
+		logger.FromContext(ctx).Warn("Failed to send email, retrying", "attempt", attempt, "reason", err.Error())
+
+		select {
+		case <-ctx.Done():
+			return attempt, errors.Join(err, ctx.Err())
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
 	}
 
-	return err
+	return maxAttempts, err
+}
+
+func (m *mailer) log(ctx context.Context, entry *sendLog) {
+	sends.WithLabelValues(string(entry.Status)).Inc()
+
+	if err := m.repository.Log(ctx, entry); err != nil {
+		logger.FromContext(ctx).Error("Failed to write mail send log", "reason", err.Error())
+	}
+}
+
+func isPermanent(err error) bool {
+	var sendErr *mail.SendError
+	if errors.As(err, &sendErr) {
+		err = sendErr.Cause
+	}
+
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code >= 500
+	}
+
+	return false
 }
 
-func New(cfg config.SMPT) Sender {
-	dialer := mail.NewDialer(cfg.Host, cfg.Port, cfg.Username, cfg.Password)
-	dialer.Timeout = 5 * time.Second
+func New(cfg config.SMTP, repository Repository, templates Renderer) Sender {
+	d := mail.NewDialer(cfg.Host, cfg.Port, cfg.Username, cfg.Password)
+	d.Timeout = 5 * time.Second
 
 	return &mailer{
-		dialer: dialer,
-		sender: cfg.Sender,
+		dialer:     d,
+		sender:     cfg.Sender,
+		templates:  templates,
+		repository: repository,
+		backoff:    initialBackoff,
 	}
 }
@@ -0,0 +1,127 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"testing"
+
+	"github.com/go-mail/mail/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type repositoryMock struct {
+	mock.Mock
+}
+
+func (r *repositoryMock) FindSuppression(ctx context.Context, email string) (*suppression, error) {
+	args := r.Called(ctx, email)
+	s, _ := args.Get(0).(*suppression)
+	return s, args.Error(1)
+}
+
+func (r *repositoryMock) Suppress(ctx context.Context, s *suppression) error {
+	args := r.Called(ctx, s)
+	return args.Error(0)
+}
+
+func (r *repositoryMock) Log(ctx context.Context, l *sendLog) error {
+	args := r.Called(ctx, l)
+	return args.Error(0)
+}
+
+type dialerMock struct {
+	errs  []error
+	calls int
+}
+
+func (d *dialerMock) DialAndSend(_ ...*mail.Message) error {
+	d.calls++
+	if len(d.errs) == 0 {
+		return nil
+	}
+
+	err := d.errs[0]
+	d.errs = d.errs[1:]
+
+	return err
+}
+
+//nolint:revive,function-length
+func TestMailer_Send(t *testing.T) {
+	tmpls, err := loadTemplates(templateFS)
+	assert.NoError(t, err)
+
+	transient := errors.New("connection reset")
+	permanent := &mail.SendError{Cause: &textproto.Error{Code: 550, Msg: "mailbox unavailable"}}
+
+	tests := []struct {
+		name          string
+		suppression   *suppression
+		dialErrs      []error
+		expectedCalls int
+		expectedLog   status
+		wantErr       bool
+	}{
+		{
+			name:          "Sent",
+			expectedCalls: 1,
+			expectedLog:   statusSent,
+		},
+		{
+			name:          "Sent after retry",
+			dialErrs:      []error{transient},
+			expectedCalls: 2,
+			expectedLog:   statusSent,
+		},
+		{
+			name:          "Failed after all attempts",
+			dialErrs:      []error{transient, transient, transient},
+			expectedCalls: maxAttempts,
+			expectedLog:   statusFailed,
+			wantErr:       true,
+		},
+		{
+			name:          "Permanent failure is not retried",
+			dialErrs:      []error{permanent},
+			expectedCalls: 1,
+			expectedLog:   statusFailed,
+			wantErr:       true,
+		},
+		{
+			name:          "Suppressed recipient",
+			suppression:   &suppression{Email: "test@test.com", Reason: "hard_bounce"},
+			expectedCalls: 0,
+			expectedLog:   statusSuppressed,
+			wantErr:       true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(repositoryMock)
+			repo.On("FindSuppression", mock.Anything, "test@test.com").Return(tc.suppression, nil)
+			repo.On("Log", mock.Anything, mock.MatchedBy(func(l *sendLog) bool {
+				return l.Status == tc.expectedLog && l.Attempts == tc.expectedCalls
+			})).Return(nil)
+
+			d := &dialerMock{errs: tc.dialErrs}
+			m := &mailer{dialer: d, templates: tmpls, repository: repo}
+
+			err := m.Send(context.Background(), "test@test.com", "en", "user_welcome.gohtml", nil)
+
+			assert.Equal(t, tc.expectedCalls, d.calls)
+			repo.AssertExpectations(t)
+
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			var suppressed *SuppressedError
+			assert.Equal(t, tc.suppression != nil, errors.As(err, &suppressed))
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package mail
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/kiennyo/syncwatch-be/internal/db"
+)
+
+type status string
+
+const (
+	statusSent       status = "sent"
+	statusFailed     status = "failed"
+	statusSuppressed status = "suppressed"
+	statusBounced    status = "bounced"
+	statusComplained status = "complained"
+)
+
+type sendLog struct {
+	Recipient string
+	Template  string
+	Status    status
+	Attempts  int
+	Detail    string
+}
+
+type suppression struct {
+	Email  string
+	Reason string
+	Detail string
+}
+
+type Repository interface {
+	FindSuppression(ctx context.Context, email string) (*suppression, error)
+	Suppress(ctx context.Context, s *suppression) error
+	Log(ctx context.Context, l *sendLog) error
+}
+
+type mailRepository struct {
+	DB db.Querier
+}
+
+var _ Repository = (*mailRepository)(nil)
+
+func NewRepository(querier db.Querier) Repository {
+	return &mailRepository{DB: querier}
+}
+
+// FindSuppression returns nil without an error when the address is not suppressed.
+func (r *mailRepository) FindSuppression(ctx context.Context, email string) (*suppression, error) {
+	s := suppression{}
+	query := `
+		SELECT email, reason, detail
+		FROM mail_suppression
+		WHERE email = @email`
+
+	args := pgx.NamedArgs{
+		"email": email,
+	}
+
+	err := r.DB.QueryRow(ctx, query, args).Scan(&s.Email, &s.Reason, &s.Detail)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, nil //nolint:nilnil
+		default:
+			return nil, err
+		}
+	}
+
+	return &s, nil
+}
+
+func (r *mailRepository) Suppress(ctx context.Context, s *suppression) error {
+	query := `
+		INSERT INTO mail_suppression (email, reason, detail)
+		VALUES (@email, @reason, @detail)
+		ON CONFLICT (email) DO UPDATE
+			SET reason = EXCLUDED.reason,
+			    detail = EXCLUDED.detail,
+			    updated_at = NOW()`
+
+	args := pgx.NamedArgs{
+		"email":  s.Email,
+		"reason": s.Reason,
+		"detail": s.Detail,
+	}
+
+	_, err := r.DB.Exec(ctx, query, args)
+
+	return err
+}
+
+func (r *mailRepository) Log(ctx context.Context, l *sendLog) error {
+	query := `
+		INSERT INTO mail_send_log (recipient, template, status, attempts, detail)
+		VALUES (@recipient, @template, @status, @attempts, @detail)`
+
+	args := pgx.NamedArgs{
+		"recipient": l.Recipient,
+		"template":  l.Template,
+		"status":    string(l.Status),
+		"attempts":  l.Attempts,
+		"detail":    l.Detail,
+	}
+
+	_, err := r.DB.Exec(ctx, query, args)
+
+	return err
+}
@@ -0,0 +1,230 @@
+package mail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// DefaultLocale is used whenever a recipient's preferred language has no templates.
+const DefaultLocale = "en"
+
+const (
+	templatesDir = "templates"
+	layoutsDir   = "layouts"
+	partialsGlob = "_*.gohtml"
+)
+
+// requiredParts are the blocks every message template has to define.
+var requiredParts = []string{"subject", "plainBody", "htmlBody"}
+
+var ErrTemplateNotFound = errors.New("template not found")
+
+// Renderer executes the embedded email templates.
+type Renderer interface {
+	Render(locale, templateFile string, data any) (*Content, error)
+	Templates() []string
+	Locales() []string
+}
+
+// Content is a rendered email template.
+type Content struct {
+	Subject   string `json:"subject"`
+	PlainBody string `json:"plain_body"`
+	HTMLBody  string `json:"html_body"`
+}
+
+var _ Renderer = (*templates)(nil)
+
+// templates holds every message template pre-parsed together with the shared layouts
+// and the partials of its locale, keyed by locale and then by template file name.
+type templates struct {
+	byLocale map[string]map[string]*template.Template
+}
+
+// LoadTemplates parses and checks all embedded templates up front, so a broken or
+// incomplete template fails the startup instead of the first email using it.
+func LoadTemplates() (Renderer, error) {
+	return loadTemplates(templateFS)
+}
+
+func loadTemplates(fsys fs.FS) (*templates, error) {
+	entries, err := fs.ReadDir(fsys, templatesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &templates{byLocale: make(map[string]map[string]*template.Template)}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == layoutsDir {
+			continue
+		}
+
+		locale := entry.Name()
+
+		parsed, err := parseLocale(fsys, locale)
+		if err != nil {
+			return nil, err
+		}
+
+		t.byLocale[locale] = parsed
+	}
+
+	if err = t.check(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func parseLocale(fsys fs.FS, locale string) (map[string]*template.Template, error) {
+	files, err := fs.Glob(fsys, path.Join(templatesDir, locale, "*.gohtml"))
+	if err != nil {
+		return nil, err
+	}
+
+	funcs := template.FuncMap{
+		"locale": func() string { return locale },
+	}
+
+	parsed := make(map[string]*template.Template)
+
+	for _, file := range files {
+		name := path.Base(file)
+		if strings.HasPrefix(name, "_") {
+			continue
+		}
+
+		tmpl, err := template.New(name).Funcs(funcs).ParseFS(
+			fsys,
+			path.Join(templatesDir, layoutsDir, "*.gohtml"),
+			path.Join(templatesDir, locale, partialsGlob),
+			file,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", file, err)
+		}
+
+		parsed[name] = tmpl
+	}
+
+	return parsed, nil
+}
+
+// check makes sure every supported locale provides every template and that each of
+// them defines all required parts, reporting all problems at once.
+func (t *templates) check() error {
+	if _, ok := t.byLocale[DefaultLocale]; !ok {
+		return fmt.Errorf("default locale %q has no templates", DefaultLocale)
+	}
+
+	var errs []error
+
+	names := t.Templates()
+	for _, locale := range t.Locales() {
+		for _, name := range names {
+			tmpl, ok := t.byLocale[locale][name]
+			if !ok {
+				errs = append(errs, fmt.Errorf("template %s is missing for locale %q", name, locale))
+				continue
+			}
+
+			for _, part := range requiredParts {
+				if tmpl.Lookup(part) == nil {
+					errs = append(errs, fmt.Errorf("template %s/%s does not define %q", locale, name, part))
+				}
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (t *templates) Render(locale, templateFile string, data any) (*Content, error) {
+	tmpl, err := t.lookup(locale, templateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := new(bytes.Buffer)
+	err = tmpl.ExecuteTemplate(subject, "subject", data)
+	if err != nil {
+		return nil, err
+	}
+
+	plainBody := new(bytes.Buffer)
+	err = tmpl.ExecuteTemplate(plainBody, "plainBody", data)
+	if err != nil {
+		return nil, err
+	}
+
+	htmlBody := new(bytes.Buffer)
+	err = tmpl.ExecuteTemplate(htmlBody, "htmlBody", data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Content{
+		Subject:   subject.String(),
+		PlainBody: plainBody.String(),
+		HTMLBody:  htmlBody.String(),
+	}, nil
+}
+
+// lookup returns the template for the closest supported locale, trying the exact
+// locale, then its base language (e.g. "lt" for "lt-LT") and finally DefaultLocale.
+func (t *templates) lookup(locale, name string) (*template.Template, error) {
+	tmpl, ok := t.byLocale[t.resolve(locale)][name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	return tmpl, nil
+}
+
+func (t *templates) resolve(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+
+	if _, ok := t.byLocale[locale]; ok {
+		return locale
+	}
+
+	base, _, _ := strings.Cut(locale, "-")
+	if _, ok := t.byLocale[base]; ok {
+		return base
+	}
+
+	return DefaultLocale
+}
+
+// Locales returns the supported locales, every one of them provides all Templates.
+func (t *templates) Locales() []string {
+	locales := make([]string, 0, len(t.byLocale))
+	for locale := range t.byLocale {
+		locales = append(locales, locale)
+	}
+	slices.Sort(locales)
+
+	return locales
+}
+
+// Templates returns the file names of all message templates.
+func (t *templates) Templates() []string {
+	var names []string
+	for _, byName := range t.byLocale {
+		for name := range byName {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+
+	return names
+}
@@ -0,0 +1,123 @@
+package mail
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testLayout   = `{{define "htmlHeader"}}<html lang="{{locale}}">{{end}}{{define "htmlFooter"}}</html>{{end}}`
+	testPartials = `{{define "signature"}}Team{{end}}`
+	testTemplate = `{{define "subject"}}Hi {{.name}}{{end}}` +
+		`{{define "plainBody"}}Hi{{end}}` +
+		`{{define "htmlBody"}}{{template "htmlHeader" .}}{{template "signature" .}}{{template "htmlFooter" .}}{{end}}`
+)
+
+func TestLoadTemplates_Embedded(t *testing.T) {
+	tmpls, err := loadTemplates(templateFS)
+
+	assert.NoError(t, err)
+	assert.Contains(t, tmpls.Locales(), DefaultLocale)
+	assert.Contains(t, tmpls.Templates(), "user_welcome.gohtml")
+}
+
+func TestLoadTemplates_Check(t *testing.T) {
+	tests := []struct {
+		name   string
+		fs     fstest.MapFS
+		errMsg string
+	}{
+		{
+			name: "Complete",
+			fs: fstest.MapFS{
+				"templates/layouts/base.gohtml": {Data: []byte(testLayout)},
+				"templates/en/_partials.gohtml": {Data: []byte(testPartials)},
+				"templates/en/welcome.gohtml":   {Data: []byte(testTemplate)},
+				"templates/lt/_partials.gohtml": {Data: []byte(testPartials)},
+				"templates/lt/welcome.gohtml":   {Data: []byte(testTemplate)},
+			},
+		},
+		{
+			name: "Missing default locale",
+			fs: fstest.MapFS{
+				"templates/layouts/base.gohtml": {Data: []byte(testLayout)},
+				"templates/lt/_partials.gohtml": {Data: []byte(testPartials)},
+				"templates/lt/welcome.gohtml":   {Data: []byte(testTemplate)},
+			},
+			errMsg: `default locale "en" has no templates`,
+		},
+		{
+			name: "Template missing for locale",
+			fs: fstest.MapFS{
+				"templates/layouts/base.gohtml": {Data: []byte(testLayout)},
+				"templates/en/_partials.gohtml": {Data: []byte(testPartials)},
+				"templates/en/welcome.gohtml":   {Data: []byte(testTemplate)},
+				"templates/lt/_partials.gohtml": {Data: []byte(testPartials)},
+				"templates/lt/other.gohtml":     {Data: []byte(testTemplate)},
+			},
+			errMsg: `template welcome.gohtml is missing for locale "lt"`,
+		},
+		{
+			name: "Missing part",
+			fs: fstest.MapFS{
+				"templates/layouts/base.gohtml": {Data: []byte(testLayout)},
+				"templates/en/_partials.gohtml": {Data: []byte(testPartials)},
+				"templates/en/welcome.gohtml":   {Data: []byte(`{{define "subject"}}Hi{{end}}`)},
+			},
+			errMsg: `template en/welcome.gohtml does not define "plainBody"`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := loadTemplates(tc.fs)
+
+			if tc.errMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.errMsg)
+			}
+		})
+	}
+}
+
+func TestTemplates_Lookup(t *testing.T) {
+	tmpls, err := loadTemplates(templateFS)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		locale   string
+		expected string
+	}{
+		{locale: "en", expected: "Welcome to Syncwatch!"},
+		{locale: "lt", expected: "Sveiki atvykę į Syncwatch!"},
+		{locale: "lt-LT", expected: "Sveiki atvykę į Syncwatch!"},
+		{locale: "LT", expected: "Sveiki atvykę į Syncwatch!"},
+		{locale: "de", expected: "Welcome to Syncwatch!"},
+		{locale: "", expected: "Welcome to Syncwatch!"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.locale, func(t *testing.T) {
+			tmpl, err := tmpls.lookup(tc.locale, "user_welcome.gohtml")
+			assert.NoError(t, err)
+
+			subject := new(bytes.Buffer)
+			err = tmpl.ExecuteTemplate(subject, "subject", nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, subject.String())
+
+			html := new(bytes.Buffer)
+			err = tmpl.ExecuteTemplate(html, "htmlBody", map[string]any{"activationToken": "token"})
+			assert.NoError(t, err)
+			assert.True(t, strings.Contains(html.String(), `lang="`+tmpls.resolve(tc.locale)+`"`))
+		})
+	}
+
+	_, err = tmpls.lookup("en", "missing.gohtml")
+	assert.Error(t, err)
+}
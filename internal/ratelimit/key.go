@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/kiennyo/syncwatch-be/internal/http/json"
+	"github.com/kiennyo/syncwatch-be/internal/security"
+)
+
+// maxFieldBody bounds the body read by ByField, larger bodies are not keyed.
+const maxFieldBody = 1 << 20
+
+// KeyFunc returns the bucket key of the request within a policy, or false when the
+// policy doesn't apply to it.
+type KeyFunc func(r *http.Request, clientIP netip.Addr) (string, bool)
+
+// ByIP keys requests by client IP.
+func ByIP(_ *http.Request, clientIP netip.Addr) (string, bool) {
+	return "ip:" + clientIP.String(), true
+}
+
+// BySubject keys requests by the authenticated user, anonymous requests by client IP.
+func BySubject(r *http.Request, clientIP netip.Addr) (string, bool) {
+	if principal := security.ContextGetPrincipal(r); principal.Sub != "" {
+		return "sub:" + principal.Sub, true
+	}
+
+	return ByIP(r, clientIP)
+}
+
+// ByField keys requests by a top level string field of the body, in any of the
+// supported encodings, compared case insensitively. The body is restored for the
+// handler. Requests without the field are not limited by the policy, the handler
+// rejects them anyway.
+func ByField(name string) KeyFunc {
+	return func(r *http.Request, _ netip.Addr) (string, bool) {
+		if r.Body == nil {
+			return "", false
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxFieldBody))
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+		if err != nil {
+			return "", false
+		}
+
+		codec, err := json.RequestCodec(r)
+		if err != nil {
+			return "", false
+		}
+
+		var fields map[string]any
+		if codec.Unmarshal(body, &fields) != nil {
+			return "", false
+		}
+
+		value, _ := fields[name].(string)
+		if strings.TrimSpace(value) == "" {
+			return "", false
+		}
+
+		return name + ":" + strings.ToLower(strings.TrimSpace(value)), true
+	}
+}
+
+// ClientIP is the address of the peer, or when the peer is a trusted proxy the right
+// most X-Forwarded-For entry that isn't one. Entries left of it are set by the client
+// and can't be trusted.
+func ClientIP(r *http.Request, trusted []netip.Prefix) netip.Addr {
+	addr := remoteAddr(r)
+	if !isTrusted(addr, trusted) {
+		return addr
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			break
+		}
+
+		addr = hop.Unmap()
+		if !isTrusted(addr, trusted) {
+			break
+		}
+	}
+
+	return addr
+}
+
+func remoteAddr(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	// unix socket peers have no address, they all share one bucket
+	addr, _ := netip.ParseAddr(host)
+
+	return addr.Unmap()
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"io"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{
+			name:       "Direct",
+			remoteAddr: "203.0.113.7:5000",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "Untrusted peer can't forward",
+			remoteAddr: "203.0.113.7:5000",
+			forwarded:  []string{"198.51.100.1"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "Trusted proxy",
+			remoteAddr: "10.0.0.2:5000",
+			forwarded:  []string{"198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "Spoofed entries left of the client are ignored",
+			remoteAddr: "10.0.0.2:5000",
+			forwarded:  []string{"1.1.1.1, 198.51.100.1", "10.0.0.3"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "Invalid entry stops the walk",
+			remoteAddr: "10.0.0.2:5000",
+			forwarded:  []string{"198.51.100.1, garbage"},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "IPv4 mapped IPv6",
+			remoteAddr: "[::ffff:203.0.113.7]:5000",
+			want:       "203.0.113.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			assert.Equal(t, tt.want, ClientIP(r, trusted).String())
+		})
+	}
+}
+
+func TestByField(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantKey string
+		wantOk  bool
+	}{
+		{
+			name:    "Field",
+			body:    `{"name": "test", "email": " Test@Example.com"}`,
+			wantKey: "email:test@example.com",
+			wantOk:  true,
+		},
+		{
+			name: "Missing field",
+			body: `{"name": "test"}`,
+		},
+		{
+			name: "Not a string",
+			body: `{"email": 1}`,
+		},
+		{
+			name: "Invalid JSON",
+			body: `{"email": `,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+
+			key, ok := ByField("email")(r, netip.Addr{})
+			assert.Equal(t, tt.wantKey, key)
+			assert.Equal(t, tt.wantOk, ok)
+
+			body, _ := io.ReadAll(r.Body)
+			assert.Equal(t, tt.body, string(body), "the body is restored")
+		})
+	}
+}
@@ -0,0 +1,122 @@
+// Package ratelimit throttles requests with token buckets, keyed by client IP, by the
+// authenticated subject or by a request field such as the email.
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests in a burst, refilled evenly over Window.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Window)
+}
+
+// ParseLimit parses limits written as requests/window, e.g. 5/1h.
+func ParseLimit(s string) (Limit, error) {
+	requests, window, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid limit %q, expected requests/window", s)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("invalid limit %q, requests must be a positive number", s)
+	}
+
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q, window must be a positive duration", s)
+	}
+
+	return Limit{Requests: n, Window: d}, nil
+}
+
+// ParsePolicies parses name=requests/window entries.
+func ParsePolicies(entries []string) (map[string]Limit, error) {
+	policies := make(map[string]Limit, len(entries))
+
+	var errs []error
+	for _, entry := range entries {
+		name, limit, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			errs = append(errs, fmt.Errorf("invalid policy %q, expected name=requests/window", entry))
+			continue
+		}
+
+		l, err := ParseLimit(limit)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		policies[name] = l
+	}
+
+	return policies, errors.Join(errs...)
+}
+
+// perSecond is the refill rate of the bucket.
+func (l Limit) perSecond() float64 {
+	return float64(l.Requests) / l.Window.Seconds()
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      Limit
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next request is allowed, when denied
+}
+
+// bucket is the state stores keep per key.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func newBucket(limit Limit, now time.Time) bucket {
+	return bucket{tokens: float64(limit.Requests), updated: now}
+}
+
+// take refills the bucket for the time passed since its last update and takes a token.
+func (b *bucket) take(limit Limit, now time.Time) Result {
+	rate := limit.perSecond()
+
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Requests), b.tokens+elapsed*rate)
+	}
+	b.updated = now
+
+	res := Result{Limit: limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((float64(limit.Requests) - b.tokens) / rate)
+
+	return res
+}
+
+// fullAt is when the bucket is full again, it can be forgotten from then on.
+func (b *bucket) fullAt(limit Limit) time.Time {
+	return b.updated.Add(seconds((float64(limit.Requests) - b.tokens) / limit.perSecond()))
+}
+
+// seconds rounds to microseconds, dropping the float error of the refill math.
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Round(s*1e6)) * time.Microsecond
+}
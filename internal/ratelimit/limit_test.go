@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    map[string]Limit
+		wantErr string
+	}{
+		{
+			name:    "Valid",
+			entries: []string{"signup=5/1h", "login=10/30s"},
+			want: map[string]Limit{
+				"signup": {Requests: 5, Window: time.Hour},
+				"login":  {Requests: 10, Window: 30 * time.Second},
+			},
+		},
+		{
+			name:    "Missing name",
+			entries: []string{"5/1h"},
+			wantErr: `invalid policy "5/1h", expected name=requests/window`,
+		},
+		{
+			name:    "Zero requests",
+			entries: []string{"signup=0/1h"},
+			wantErr: `invalid limit "0/1h", requests must be a positive number`,
+		},
+		{
+			name:    "Invalid window",
+			entries: []string{"signup=5/hour"},
+			wantErr: `invalid limit "5/hour", window must be a positive duration`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePolicies(tt.entries)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMemoryStore_Take(t *testing.T) {
+	ctx := context.Background()
+	limit := Limit{Requests: 2, Window: time.Minute}
+	now := time.Date(2024, 5, 27, 10, 0, 0, 0, time.UTC)
+
+	s := NewMemoryStore()
+
+	res, _ := s.Take(ctx, "key", limit, now)
+	assert.Equal(t, Result{Allowed: true, Limit: limit, Remaining: 1, Reset: 30 * time.Second}, res)
+
+	res, _ = s.Take(ctx, "key", limit, now)
+	assert.Equal(t, Result{Allowed: true, Limit: limit, Remaining: 0, Reset: time.Minute}, res)
+
+	res, _ = s.Take(ctx, "key", limit, now.Add(10*time.Second))
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 20*time.Second, res.RetryAfter)
+
+	res, _ = s.Take(ctx, "other", limit, now)
+	assert.True(t, res.Allowed, "keys have their own bucket")
+
+	res, _ = s.Take(ctx, "key", limit, now.Add(30*time.Second))
+	assert.True(t, res.Allowed, "a token is refilled every 30s")
+	assert.Equal(t, 0, res.Remaining)
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	limit := Limit{Requests: 1, Window: time.Minute}
+	now := time.Now()
+
+	s := NewMemoryStore()
+	_, _ = s.Take(context.Background(), "key", limit, now)
+
+	s.sweep(now.Add(59 * time.Second))
+	assert.Len(t, s.buckets, 1)
+
+	s.sweep(now.Add(time.Minute))
+	assert.Empty(t, s.buckets)
+}
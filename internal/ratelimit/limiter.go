@@ -0,0 +1,153 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/kiennyo/syncwatch-be/internal/config"
+	httperr "github.com/kiennyo/syncwatch-be/internal/http/error"
+	"github.com/kiennyo/syncwatch-be/internal/logger"
+)
+
+var decisions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "syncwatch",
+	Subsystem: "ratelimit",
+	Name:      "decisions_total",
+	Help:      "Rate limited requests by policy and decision: allowed, denied or failed.",
+}, []string{"policy", "decision"})
+
+// Policy limits a route. Limit is the default, the configuration overrides it by name.
+type Policy struct {
+	Name  string
+	Limit Limit
+	Key   KeyFunc
+}
+
+type Limiter struct {
+	store     Store
+	overrides map[string]Limit
+	proxies   []netip.Prefix
+	now       func() time.Time
+}
+
+// New builds the limiter of the configuration, a nil one when rate limiting is disabled.
+func New(cfg config.RateLimit, store Store) (*Limiter, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	proxies, proxiesErr := ParseProxies(cfg.TrustedProxies)
+	if proxiesErr != nil {
+		proxiesErr = fmt.Errorf("RATE_LIMIT_TRUSTED_PROXIES: %w", proxiesErr)
+	}
+
+	overrides, policiesErr := ParsePolicies(cfg.Policies)
+	if policiesErr != nil {
+		policiesErr = fmt.Errorf("RATE_LIMIT_POLICIES: %w", policiesErr)
+	}
+
+	if err := errors.Join(proxiesErr, policiesErr); err != nil {
+		return nil, err
+	}
+
+	return &Limiter{
+		store:     store,
+		overrides: overrides,
+		proxies:   proxies,
+		now:       time.Now,
+	}, nil
+}
+
+// ParseProxies parses the CIDRs of trusted proxies, single addresses are taken as /32
+// or /128.
+func ParseProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+
+	var errs []error
+	for _, proxy := range proxies {
+		if addr, err := netip.ParseAddr(proxy); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid CIDR %q", proxy))
+			continue
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, errors.Join(errs...)
+}
+
+// Middleware enforces the policy. A nil Limiter disables rate limiting. Store failures
+// let the request through, an unavailable store shouldn't take the API down with it.
+func (l *Limiter) Middleware(p Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l == nil {
+			return next
+		}
+
+		limit := p.Limit
+		if override, ok := l.overrides[p.Name]; ok {
+			limit = override
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := p.Key(r, ClientIP(r, l.proxies))
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res, err := l.store.Take(r.Context(), p.Name+"|"+key, limit, l.now())
+			if err != nil {
+				decisions.WithLabelValues(p.Name, "failed").Inc()
+				logger.FromContext(r.Context()).Error("Failed to apply rate limit",
+					"policy", p.Name, "reason", err.Error())
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			setHeaders(w.Header(), res)
+
+			if !res.Allowed {
+				decisions.WithLabelValues(p.Name, "denied").Inc()
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+				httperr.Render(w, r, httperr.RateLimited())
+				return
+			}
+
+			decisions.WithLabelValues(p.Name, "allowed").Inc()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setHeaders reports the policy closest to its limit when several apply to a route.
+func setHeaders(h http.Header, res Result) {
+	if current := h.Get("RateLimit-Remaining"); current != "" {
+		if remaining, err := strconv.Atoi(current); err == nil && remaining <= res.Remaining {
+			return
+		}
+	}
+
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit.Requests))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", res.Limit.Requests, ceilSeconds(res.Limit.Window)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiennyo/syncwatch-be/internal/config"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit, time.Time) (Result, error) {
+	return Result{}, errors.New("store unavailable")
+}
+
+func newTestLimiter(t *testing.T, store Store, cfg config.RateLimit) *Limiter {
+	cfg.Enabled = true
+
+	l, err := New(cfg, store)
+	assert.NoError(t, err)
+
+	now := time.Date(2024, 5, 27, 10, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	return l
+}
+
+func serve(h http.Handler, remoteAddr string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/", nil)
+	r.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestLimiter_Middleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	policy := Policy{Name: "test", Limit: Limit{Requests: 1, Window: time.Minute}, Key: ByIP}
+
+	t.Run("Headers and 429", func(t *testing.T) {
+		h := newTestLimiter(t, NewMemoryStore(), config.RateLimit{}).Middleware(policy)(ok)
+
+		w := serve(h, "203.0.113.7:5000")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+		assert.Equal(t, "1;w=60", w.Header().Get("RateLimit-Policy"))
+
+		w = serve(h, "203.0.113.7:5000")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+		w = serve(h, "203.0.113.8:5000")
+		assert.Equal(t, http.StatusNoContent, w.Code, "other clients aren't limited")
+	})
+
+	t.Run("Configured override", func(t *testing.T) {
+		l := newTestLimiter(t, NewMemoryStore(), config.RateLimit{Policies: []string{"test=2/1m"}})
+		h := l.Middleware(policy)(ok)
+
+		assert.Equal(t, http.StatusNoContent, serve(h, "203.0.113.7:5000").Code)
+		assert.Equal(t, http.StatusNoContent, serve(h, "203.0.113.7:5000").Code)
+		assert.Equal(t, http.StatusTooManyRequests, serve(h, "203.0.113.7:5000").Code)
+	})
+
+	t.Run("Most restrictive policy is reported", func(t *testing.T) {
+		l := newTestLimiter(t, NewMemoryStore(), config.RateLimit{})
+		loose := Policy{Name: "loose", Limit: Limit{Requests: 10, Window: time.Hour}, Key: ByIP}
+		h := l.Middleware(policy)(l.Middleware(loose)(ok))
+
+		w := serve(h, "203.0.113.7:5000")
+		assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	})
+
+	t.Run("Store failure lets requests through", func(t *testing.T) {
+		h := newTestLimiter(t, failingStore{}, config.RateLimit{}).Middleware(policy)(ok)
+
+		assert.Equal(t, http.StatusNoContent, serve(h, "203.0.113.7:5000").Code)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		l, err := New(config.RateLimit{Enabled: false}, NewMemoryStore())
+		assert.NoError(t, err)
+
+		h := l.Middleware(policy)(ok)
+		for range 3 {
+			assert.Equal(t, http.StatusNoContent, serve(h, "203.0.113.7:5000").Code)
+		}
+	})
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	_, err := New(config.RateLimit{
+		Enabled:        true,
+		TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1", "proxy"},
+		Policies:       []string{"signup"},
+	}, NewMemoryStore())
+
+	assert.EqualError(t, err, "RATE_LIMIT_TRUSTED_PROXIES: invalid CIDR \"proxy\"\n"+
+		"RATE_LIMIT_POLICIES: invalid policy \"signup\", expected name=requests/window")
+}
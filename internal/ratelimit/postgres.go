@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/kiennyo/syncwatch-be/internal/db"
+)
+
+type txQuerier interface {
+	db.Querier
+	db.Transactor
+}
+
+// PostgresStore shares the buckets between instances. Each take locks the row of its
+// key, so it costs a transaction but is exact under concurrency.
+type PostgresStore struct {
+	DB    txQuerier
+	takes atomic.Int64
+}
+
+var _ Store = (*PostgresStore)(nil)
+
+func NewPostgresStore(tx txQuerier) *PostgresStore {
+	return &PostgresStore{DB: tx}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	if s.takes.Add(1)%sweepEvery == 0 {
+		s.sweep(ctx, now)
+	}
+
+	var res Result
+
+	err := s.DB.InTx(ctx, func(ctx context.Context) error {
+		b := newBucket(limit, now)
+		args := pgx.NamedArgs{
+			"key":     key,
+			"tokens":  b.tokens,
+			"updated": b.updated,
+			"full_at": b.updated,
+		}
+
+		insert := `
+			INSERT INTO rate_limit_bucket (key, tokens, updated_at, full_at)
+			VALUES (@key, @tokens, @updated, @full_at)
+			ON CONFLICT (key) DO NOTHING`
+		if _, err := s.DB.Exec(ctx, insert, args); err != nil {
+			return db.MapError(err)
+		}
+
+		query := `SELECT tokens, updated_at FROM rate_limit_bucket WHERE key = @key FOR UPDATE`
+		if err := s.DB.QueryRow(ctx, query, args).Scan(&b.tokens, &b.updated); err != nil {
+			return db.MapError(err)
+		}
+
+		res = b.take(limit, now)
+
+		args["tokens"], args["updated"], args["full_at"] = b.tokens, b.updated, b.fullAt(limit)
+
+		update := `
+			UPDATE rate_limit_bucket
+			SET tokens = @tokens, updated_at = @updated, full_at = @full_at
+			WHERE key = @key`
+		_, err := s.DB.Exec(ctx, update, args)
+
+		return db.MapError(err)
+	})
+
+	return res, err
+}
+
+// sweep removes full buckets, they behave the same as missing ones.
+func (s *PostgresStore) sweep(ctx context.Context, now time.Time) {
+	_, err := s.DB.Exec(ctx, `DELETE FROM rate_limit_bucket WHERE full_at <= @now`, pgx.NamedArgs{"now": now})
+	if err != nil {
+		slog.Error("Failed to remove full rate limit buckets", "reason", err.Error())
+	}
+}
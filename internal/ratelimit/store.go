@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is the number of takes between removals of full buckets.
+const sweepEvery = 1024
+
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+type memoryEntry struct {
+	bucket bucket
+	fullAt time.Time
+}
+
+// MemoryStore keeps the buckets of a single instance.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryEntry
+	takes   int
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*memoryEntry)}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.takes++
+	if s.takes%sweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	entry, ok := s.buckets[key]
+	if !ok {
+		entry = &memoryEntry{bucket: newBucket(limit, now)}
+		s.buckets[key] = entry
+	}
+
+	res := entry.bucket.take(limit, now)
+	entry.fullAt = entry.bucket.fullAt(limit)
+
+	return res, nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, entry := range s.buckets {
+		if !entry.fullAt.After(now) {
+			delete(s.buckets, key)
+		}
+	}
+}
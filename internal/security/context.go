@@ -10,6 +10,8 @@ type contextKey string
 type ContextValue struct {
 	Sub    string
 	Scopes string
+	// Cookie is set when the token came from the auth cookie, such requests need a CSRF token.
+	Cookie bool
 }
 
 const principalContext = contextKey("principal")
@@ -0,0 +1,100 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+)
+
+// CSRFHeader must echo the CSRF cookie on unsafe requests authenticated by cookie.
+// Responses to those requests carry the token in it too.
+const CSRFHeader = "X-CSRF-Token"
+
+const csrfTokenBytes = 32
+
+// CSRF implements double submit protection. Browsers authenticated by cookie get a
+// random token cookie, and the token in the response header, as scripts of a web player
+// on another origin can't read cookies of the API host. CORS only exposes the header to
+// allowed origins, so a cross site form can't learn the token and can't send it back.
+// Requests authenticated by the Authorization header are left alone, browsers never
+// attach that header by themselves.
+func (a *AuthMiddleware) CSRF(next http.Handler) http.Handler {
+	if a.Cookie == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := ContextGetPrincipal(r)
+
+		token := ""
+		if cookie, err := r.Cookie(a.CSRFCookie); err == nil && validCSRFToken(cookie.Value) {
+			token = cookie.Value
+		}
+
+		// probes and API clients don't need one, they'd get a new cookie every time
+		if principal.Cookie {
+			current := token
+			if current == "" {
+				current = a.issueCSRFToken(w, r)
+			}
+			w.Header().Set(CSRFHeader, current)
+		}
+
+		if safeMethod(r.Method) || !principal.Cookie {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := r.Header.Get(CSRFHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(header), []byte(token)) != 1 {
+			invalidCSRFTokenResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// issueCSRFToken sets a new token cookie and returns the token, empty when none could
+// be made.
+func (a *AuthMiddleware) issueCSRFToken(w http.ResponseWriter, r *http.Request) string {
+	token, err := newCSRFToken()
+	if err != nil {
+		return "" // the request fails the check without a token, the next one gets it
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     a.CSRFCookie,
+		Value:    token,
+		Path:     "/",
+		Secure:   a.SecureCookie || r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return token
+}
+
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+func newCSRFToken() (string, error) {
+	b := make([]byte, csrfTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// validCSRFToken tells whether the cookie holds a token issued by newCSRFToken.
+func validCSRFToken(token string) bool {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	return err == nil && len(b) == csrfTokenBytes
+}
@@ -0,0 +1,181 @@
+package security
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// token is a well formed token, 32 bytes encoded.
+const token = "q2Yh8uV1f0Jm3X9kzW4bN7cR5tL6pE2sA8dG1hK0jQw"
+
+func TestAuthMiddleware_CSRF(t *testing.T) {
+	am := &AuthMiddleware{Cookie: "session", CSRFCookie: "csrf_token"}
+
+	tests := []struct {
+		name       string
+		method     string
+		principal  *ContextValue
+		cookie     string
+		header     string
+		wantStatus int
+		wantIssued bool
+	}{
+		{
+			name:       "Safe method issues a token",
+			method:     http.MethodGet,
+			principal:  &ContextValue{Sub: "user", Cookie: true},
+			wantStatus: http.StatusOK,
+			wantIssued: true,
+		},
+		{
+			name:       "Matching token",
+			method:     http.MethodPost,
+			principal:  &ContextValue{Sub: "user", Cookie: true},
+			cookie:     token,
+			header:     token,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Missing header",
+			method:     http.MethodPost,
+			principal:  &ContextValue{Sub: "user", Cookie: true},
+			cookie:     token,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Mismatching token",
+			method:     http.MethodDelete,
+			principal:  &ContextValue{Sub: "user", Cookie: true},
+			cookie:     token,
+			header:     "other",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Missing cookie",
+			method:     http.MethodPatch,
+			principal:  &ContextValue{Sub: "user", Cookie: true},
+			header:     token,
+			wantStatus: http.StatusForbidden,
+			wantIssued: true,
+		},
+		{
+			name:       "Malformed cookie",
+			method:     http.MethodPost,
+			principal:  &ContextValue{Sub: "user", Cookie: true},
+			cookie:     "token",
+			header:     "token",
+			wantStatus: http.StatusForbidden,
+			wantIssued: true,
+		},
+		{
+			name:       "Valid cookie isn't reissued",
+			method:     http.MethodGet,
+			principal:  &ContextValue{Sub: "user", Cookie: true},
+			cookie:     token,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Probe without cookies",
+			method:     http.MethodGet,
+			principal:  &ContextValue{},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Authorization header",
+			method:     http.MethodPost,
+			principal:  &ContextValue{Sub: "user"},
+			cookie:     token,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Anonymous",
+			method:     http.MethodPost,
+			principal:  &ContextValue{},
+			cookie:     token,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), principalContext, tt.principal)
+			req, _ := http.NewRequestWithContext(ctx, tt.method, "/", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "csrf_token", Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set(CSRFHeader, tt.header)
+			}
+
+			res := httptest.NewRecorder()
+			am.CSRF(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(res, req)
+
+			assert.Equal(t, tt.wantStatus, res.Code)
+
+			cookies := res.Result().Cookies() //nolint:bodyclose
+			if tt.wantIssued {
+				assert.Len(t, cookies, 1)
+				assert.Equal(t, "csrf_token", cookies[0].Name)
+				assert.Len(t, cookies[0].Value, 43)
+				assert.False(t, cookies[0].HttpOnly, "scripts must read the token")
+				assert.False(t, cookies[0].Secure, "plain HTTP without SecureCookie")
+				assert.Equal(t, cookies[0].Value, res.Header().Get(CSRFHeader), "players elsewhere read the header")
+			} else {
+				assert.Empty(t, cookies)
+			}
+
+			switch {
+			case !tt.principal.Cookie:
+				assert.Empty(t, res.Header().Get(CSRFHeader))
+			case !tt.wantIssued:
+				assert.Equal(t, tt.cookie, res.Header().Get(CSRFHeader))
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_CSRFSecure(t *testing.T) {
+	tests := []struct {
+		name   string
+		secure bool
+		tls    bool
+	}{
+		{name: "Configured", secure: true},
+		{name: "TLS", tls: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			am := &AuthMiddleware{Cookie: "session", CSRFCookie: "csrf_token", SecureCookie: tt.secure}
+
+			ctx := context.WithValue(context.Background(), principalContext, &ContextValue{Sub: "user", Cookie: true})
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+
+			res := httptest.NewRecorder()
+			am.CSRF(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(res, req)
+
+			cookies := res.Result().Cookies() //nolint:bodyclose
+			assert.Len(t, cookies, 1)
+			assert.True(t, cookies[0].Secure)
+		})
+	}
+}
+
+func TestAuthMiddleware_CSRFDisabled(t *testing.T) {
+	ctx := context.WithValue(context.Background(), principalContext, &ContextValue{})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "/", nil)
+	res := httptest.NewRecorder()
+
+	(&AuthMiddleware{}).CSRF(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Empty(t, res.Header().Get("Set-Cookie"), "no token without cookie authentication")
+}
@@ -3,21 +3,42 @@ package security
 import (
 	"net/http"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
 	"github.com/kiennyo/syncwatch-be/internal/http/error"
 )
 
+var authFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "syncwatch",
+	Subsystem: "auth",
+	Name:      "failures_total",
+	Help:      "Rejected requests by reason: invalid_token, invalid_csrf_token, unauthenticated or not_permitted.",
+}, []string{"reason"})
+
+const codeInvalidCSRFToken = "invalid_csrf_token"
+
 func invalidAuthenticationTokenResponse(w http.ResponseWriter, r *http.Request) {
+	authFailures.WithLabelValues("invalid_token").Inc()
 	w.Header().Set("WWW-Authenticate", "Bearer")
 	message := "invalid or missing authentication token"
-	error.Response(w, r, http.StatusUnauthorized, message)
+	error.Render(w, r, error.New(http.StatusUnauthorized, error.CodeInvalidToken, message))
 }
 
 func authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	authFailures.WithLabelValues("unauthenticated").Inc()
 	message := "you must be authenticated to access this resource"
-	error.Response(w, r, http.StatusUnauthorized, message)
+	error.Render(w, r, error.New(http.StatusUnauthorized, error.CodeUnauthenticated, message))
 }
 
 func notPermittedResponse(w http.ResponseWriter, r *http.Request) {
+	authFailures.WithLabelValues("not_permitted").Inc()
 	message := "your user account doesn't have the necessary permissions to access this resource"
-	error.Response(w, r, http.StatusForbidden, message)
+	error.Render(w, r, error.New(http.StatusForbidden, error.CodeForbidden, message))
+}
+
+func invalidCSRFTokenResponse(w http.ResponseWriter, r *http.Request) {
+	authFailures.WithLabelValues("invalid_csrf_token").Inc()
+	message := "invalid or missing CSRF token"
+	error.Render(w, r, error.New(http.StatusForbidden, codeInvalidCSRFToken, message))
 }
@@ -1,12 +1,32 @@
 package security
 
 import (
+	"log/slog"
 	"net/http"
 	"strings"
+
+	"github.com/kiennyo/syncwatch-be/internal/config"
+	"github.com/kiennyo/syncwatch-be/internal/logger"
 )
 
 type AuthMiddleware struct {
 	Tokens TokenVerifier
+	// Cookie is the name of the cookie browser clients send the token in, empty when
+	// only the Authorization header is accepted.
+	Cookie     string
+	CSRFCookie string
+	// SecureCookie marks the CSRF cookie Secure on plain HTTP too, e.g. behind a proxy
+	// terminating TLS.
+	SecureCookie bool
+}
+
+func NewAuthMiddleware(tokens TokenVerifier, cfg config.Security) *AuthMiddleware {
+	return &AuthMiddleware{
+		Tokens:       tokens,
+		Cookie:       cfg.AuthCookie,
+		CSRFCookie:   cfg.CSRFCookie,
+		SecureCookie: cfg.CookieSecure,
+	}
 }
 
 func Authorize(next http.HandlerFunc, requiredScopes string) http.HandlerFunc {
@@ -31,13 +51,14 @@ func Authorize(next http.HandlerFunc, requiredScopes string) http.HandlerFunc {
 func (a *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Vary", "Authorization")
+		if a.Cookie != "" {
+			w.Header().Add("Vary", "Cookie")
+		}
 
 		authorizationHeader := r.Header.Get("Authorization")
 
-		// anonymous request
 		if authorizationHeader == "" {
-			r = contextSetPrincipal(r, &ContextValue{})
-			next.ServeHTTP(w, r)
+			a.authenticateCookie(next, w, r)
 			return
 		}
 
@@ -56,7 +77,34 @@ func (a *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 		}
 
 		r = contextSetPrincipal(r, contextValue)
+		r = r.WithContext(logger.WithAttrs(r.Context(), slog.String("user", contextValue.Sub)))
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// authenticateCookie authenticates browser clients, the header takes precedence so API
+// clients are never subject to CSRF checks.
+func (a *AuthMiddleware) authenticateCookie(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(a.Cookie)
+
+	// anonymous request
+	if a.Cookie == "" || err != nil {
+		r = contextSetPrincipal(r, &ContextValue{})
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	contextValue, err := a.Tokens.VerifyToken(cookie.Value)
+	if err != nil {
+		invalidAuthenticationTokenResponse(w, r)
+		return
+	}
+
+	contextValue.Cookie = true
+
+	r = contextSetPrincipal(r, contextValue)
+	r = r.WithContext(logger.WithAttrs(r.Context(), slog.String("user", contextValue.Sub)))
+
+	next.ServeHTTP(w, r)
+}
@@ -32,6 +32,7 @@ func TestAuthMiddleware_Authenticate(t *testing.T) {
 	testCases := []struct {
 		name               string
 		authorizationToken string
+		cookie             string
 		expectedStatusCode int
 		context            *ContextValue
 	}{
@@ -62,6 +63,32 @@ func TestAuthMiddleware_Authenticate(t *testing.T) {
 				Scopes: scopesJoined,
 			},
 		},
+		{
+			name:               "Valid cookie",
+			cookie:             token,
+			expectedStatusCode: http.StatusOK,
+			context: &ContextValue{
+				Sub:    subject,
+				Scopes: scopesJoined,
+				Cookie: true,
+			},
+		},
+		{
+			name:               "Invalid cookie",
+			cookie:             "Random.Invalid.Token",
+			expectedStatusCode: http.StatusUnauthorized,
+			context:            nil,
+		},
+		{
+			name:               "Header takes precedence over cookie",
+			authorizationToken: fmt.Sprintf("Bearer %s", token),
+			cookie:             "Random.Invalid.Token",
+			expectedStatusCode: http.StatusOK,
+			context: &ContextValue{
+				Sub:    subject,
+				Scopes: scopesJoined,
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -72,9 +99,13 @@ func TestAuthMiddleware_Authenticate(t *testing.T) {
 			if tc.authorizationToken != "" {
 				req.Header.Set("Authorization", tc.authorizationToken)
 			}
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "session", Value: tc.cookie})
+			}
 
 			am := &AuthMiddleware{
 				Tokens: tokenFactory,
+				Cookie: "session",
 			}
 
 			nextHandler := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
@@ -82,6 +113,7 @@ func TestAuthMiddleware_Authenticate(t *testing.T) {
 					principal := ContextGetPrincipal(r)
 					assert.Equal(t, tc.context.Scopes, principal.Scopes)
 					assert.Equal(t, tc.context.Sub, principal.Sub)
+					assert.Equal(t, tc.context.Cookie, principal.Cookie)
 				}
 			})
 
@@ -1,13 +1,9 @@
 package testhelpers
 
 import (
-	"bufio"
 	"context"
 	"fmt"
-	"log"
 	"log/slog"
-	"os"
-	"os/exec"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,6 +14,7 @@ import (
 
 	"github.com/kiennyo/syncwatch-be/internal/config"
 	"github.com/kiennyo/syncwatch-be/internal/db"
+	"github.com/kiennyo/syncwatch-be/migrations"
 )
 
 type TestingDB struct {
@@ -26,7 +23,6 @@ type TestingDB struct {
 
 var cached *TestingDB
 
-//nolint:revive,cognitive-complexity
 func CreateTestDB(ctx context.Context) (*TestingDB, error) {
 	if cached != nil {
 		slog.Info("Getting cached instance")
@@ -59,36 +55,6 @@ func CreateTestDB(ctx context.Context) (*TestingDB, error) {
 		return nil, err
 	}
 
-	// set DB_URL for migrations
-	ip, _ := container.ContainerIP(ctx)
-	connectionString := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", user, password, ip, name)
-
-	err = os.Setenv("DB_URL", connectionString)
-	if err != nil {
-		return nil, err
-	}
-
-	// run migrations
-	cmd := exec.Command("task", "db:run-migration")
-	stderr, _ := cmd.StderrPipe()
-
-	if err = cmd.Start(); err != nil {
-		log.Printf("cmd.Start: %v", err)
-	}
-
-	scanner := bufio.NewScanner(stderr)
-	scanner.Split(bufio.ScanLines)
-
-	// for debugging
-	// for scanner.Scan() {
-	//	m := scanner.Text()
-	//	fmt.Println(m)
-	// }
-
-	if err = cmd.Wait(); err != nil {
-		return nil, err
-	}
-
 	s, err := container.ConnectionString(ctx, "sslmode=disable")
 	if err != nil {
 		slog.Error("Failed getting connection string")
@@ -99,13 +65,24 @@ func CreateTestDB(ctx context.Context) (*TestingDB, error) {
 		URL:         s,
 		MaxOpenConn: 1,
 		MaxIdleConn: 1,
-		MaxIdleTime: "1m",
+		MaxIdleTime: time.Minute,
 	})
 	if err != nil {
 		slog.Error("Failed connecting to database")
 		return nil, err
 	}
 
+	// same runner as the application, no external migrate tool needed
+	migrator, err := db.NewMigrator(pool, migrations.FS)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = migrator.Up(ctx); err != nil {
+		slog.Error("Failed running migrations")
+		return nil, err
+	}
+
 	cached = &TestingDB{DB: pool}
 
 	return cached, nil
@@ -0,0 +1,143 @@
+package validator
+
+import (
+	"cmp"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is used when the client accepts none of the catalog locales, and for
+// codes a locale has no message for.
+const DefaultLocale = "en"
+
+// messages holds a <locale>.json file per locale, mapping codes to messages with
+// {param} placeholders.
+//
+//go:embed messages/*.json
+var messagesFS embed.FS
+
+var (
+	catalogMu sync.RWMutex
+	catalog   = loadCatalog()
+)
+
+func loadCatalog() map[string]map[string]string {
+	entries, err := messagesFS.ReadDir("messages")
+	if err != nil {
+		panic(err)
+	}
+
+	c := make(map[string]map[string]string, len(entries))
+	for _, entry := range entries {
+		raw, err := messagesFS.ReadFile(path.Join("messages", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+
+		messages := make(map[string]string)
+		if err = json.Unmarshal(raw, &messages); err != nil {
+			panic(fmt.Sprintf("validator: messages/%s: %s", entry.Name(), err))
+		}
+
+		c[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+
+	return c
+}
+
+// AddMessages adds messages of a locale to the catalog, for codes of registered rules.
+// Like Register it's meant to be called from init functions.
+func AddMessages(locale string, messages map[string]string) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	if catalog[locale] == nil {
+		catalog[locale] = make(map[string]string, len(messages))
+	}
+
+	for code, message := range messages {
+		catalog[locale][code] = message
+	}
+}
+
+// Translate returns the message of violation in locale, falling back to DefaultLocale
+// and then to the code itself.
+func Translate(locale string, violation Violation) string {
+	if violation.Message != "" {
+		return violation.Message
+	}
+
+	catalogMu.RLock()
+	message, ok := catalog[locale][violation.Code]
+	if !ok {
+		message, ok = catalog[DefaultLocale][violation.Code]
+	}
+	catalogMu.RUnlock()
+
+	if !ok {
+		return violation.Code
+	}
+
+	for name, value := range violation.Params {
+		message = strings.ReplaceAll(message, "{"+name+"}", formatParam(value))
+	}
+
+	return message
+}
+
+func formatParam(value any) string {
+	if values, ok := value.([]string); ok {
+		return strings.Join(values, ", ")
+	}
+
+	return fmt.Sprint(value)
+}
+
+// Locale picks the catalog locale best matching an Accept-Language header. A region
+// falls back to its language, e.g. lt-LT to lt.
+func Locale(acceptLanguage string) string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+
+		q := 1.0
+		if raw, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(raw, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		if tag != "" && q > 0 {
+			tags = append(tags, weighted{tag: strings.ToLower(tag), q: q})
+		}
+	}
+
+	slices.SortStableFunc(tags, func(a, b weighted) int { return cmp.Compare(b.q, a.q) })
+
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	for _, t := range tags {
+		if _, ok := catalog[t.tag]; ok {
+			return t.tag
+		}
+
+		base, _, _ := strings.Cut(t.tag, "-")
+		if _, ok := catalog[base]; ok {
+			return base
+		}
+	}
+
+	return DefaultLocale
+}
@@ -0,0 +1,50 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocale(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: "en"},
+		{header: "lt", want: "lt"},
+		{header: "lt-LT,lt;q=0.9,en;q=0.8", want: "lt"},
+		{header: "de-DE, en;q=0.5, lt;q=0.7", want: "lt"},
+		{header: "lt;q=0, en", want: "en"},
+		{header: "fr, *;q=0.1", want: "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			assert.Equal(t, tt.want, Locale(tt.header))
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	minLength := Violation{Code: "min_length", Params: map[string]any{"min": 8}}
+	oneOf := Violation{Code: "one_of", Params: map[string]any{"values": []string{"hard", "soft"}}}
+
+	assert.Equal(t, "must be at least 8 bytes long", Translate("en", minLength))
+	assert.Equal(t, "turi būti bent 8 baitų ilgio", Translate("lt", minLength))
+	assert.Equal(t, "must be one of hard, soft", Translate("en", oneOf))
+	assert.Equal(t, "must be at least 8 bytes long", Translate("fr", minLength), "falls back to the default locale")
+	assert.Equal(t, "is taken", Translate("lt", Violation{Code: CodeInvalid, Message: "is taken"}))
+	assert.Equal(t, "unknown_code", Translate("en", Violation{Code: "unknown_code"}))
+}
+
+func TestCatalog_Complete(t *testing.T) {
+	for locale, messages := range loadCatalog() {
+		for code := range catalog[DefaultLocale] {
+			if code == "even" {
+				continue // registered by the tests
+			}
+			assert.Contains(t, messages, code, "locale %s", locale)
+		}
+	}
+}
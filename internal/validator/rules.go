@@ -0,0 +1,212 @@
+package validator
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Rule checks a field against the parameter of its tag, e.g. "500" for max=500, and
+// returns the violation or nil. Codes of custom rules need messages, see AddMessages.
+type Rule func(field reflect.Value, param string) *Violation
+
+var (
+	rulesMu sync.RWMutex
+	rules   = map[string]Rule{
+		"required": required,
+		"min":      minimum,
+		"max":      maximum,
+		"minrunes": minRunes,
+		"maxrunes": maxRunes,
+		"email":    email,
+		"url":      absoluteURL,
+		"uuid":     validUUID,
+		"oneof":    oneOf,
+		"regex":    matchesRegex,
+	}
+)
+
+// Register adds a rule, or replaces the one of the same name. It's meant to be called
+// from init functions, before any validation.
+func Register(name string, rule Rule) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+
+	rules[name] = rule
+}
+
+// Apply checks a value outside of a struct against a rule, e.g. Apply("email", "", s).
+// Like in tags, every rule but required passes on zero values. Unknown rules panic.
+func Apply(name, param string, value any) *Violation {
+	rule, ok := lookup(name)
+	if !ok {
+		panic(fmt.Sprintf("validator: unknown rule %q", name))
+	}
+
+	return boundRule{name: name, param: param, rule: rule}.check(reflect.ValueOf(value))
+}
+
+func lookup(name string) (Rule, bool) {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+
+	rule, ok := rules[name]
+	return rule, ok
+}
+
+func required(field reflect.Value, _ string) *Violation {
+	if field.IsZero() || ((field.Kind() == reflect.Slice || field.Kind() == reflect.Map) && field.Len() == 0) {
+		return &Violation{Code: "required"}
+	}
+
+	return nil
+}
+
+// minimum bounds the bytes of strings, the items of collections and the value of numbers.
+func minimum(field reflect.Value, param string) *Violation {
+	switch field.Kind() {
+	case reflect.String:
+		if n := intParam("min", param); field.Len() < n {
+			return &Violation{Code: "min_length", Params: map[string]any{"min": n}}
+		}
+	case reflect.Slice, reflect.Map, reflect.Array:
+		if n := intParam("min", param); field.Len() < n {
+			return &Violation{Code: "min_items", Params: map[string]any{"min": n}}
+		}
+	default:
+		if n := floatParam("min", param); number(field) < n {
+			return &Violation{Code: "min_value", Params: map[string]any{"min": n}}
+		}
+	}
+
+	return nil
+}
+
+func maximum(field reflect.Value, param string) *Violation {
+	switch field.Kind() {
+	case reflect.String:
+		if n := intParam("max", param); field.Len() > n {
+			return &Violation{Code: "max_length", Params: map[string]any{"max": n}}
+		}
+	case reflect.Slice, reflect.Map, reflect.Array:
+		if n := intParam("max", param); field.Len() > n {
+			return &Violation{Code: "max_items", Params: map[string]any{"max": n}}
+		}
+	default:
+		if n := floatParam("max", param); number(field) > n {
+			return &Violation{Code: "max_value", Params: map[string]any{"max": n}}
+		}
+	}
+
+	return nil
+}
+
+func minRunes(field reflect.Value, param string) *Violation {
+	if n := intParam("minrunes", param); utf8.RuneCountInString(field.String()) < n {
+		return &Violation{Code: "min_chars", Params: map[string]any{"min": n}}
+	}
+
+	return nil
+}
+
+func maxRunes(field reflect.Value, param string) *Violation {
+	if n := intParam("maxrunes", param); utf8.RuneCountInString(field.String()) > n {
+		return &Violation{Code: "max_chars", Params: map[string]any{"max": n}}
+	}
+
+	return nil
+}
+
+// email accepts bare addresses with a dotted domain, display names aren't addresses.
+func email(field reflect.Value, _ string) *Violation {
+	value := field.String()
+
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value || !strings.Contains(value[strings.LastIndex(value, "@"):], ".") {
+		return &Violation{Code: "email"}
+	}
+
+	return nil
+}
+
+func absoluteURL(field reflect.Value, _ string) *Violation {
+	u, err := url.Parse(field.String())
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &Violation{Code: "url"}
+	}
+
+	return nil
+}
+
+func validUUID(field reflect.Value, _ string) *Violation {
+	if uuid.Validate(field.String()) != nil {
+		return &Violation{Code: "uuid"}
+	}
+
+	return nil
+}
+
+// oneOf takes the values separated by |, as the config tags do.
+func oneOf(field reflect.Value, param string) *Violation {
+	values := strings.Split(param, "|")
+	if !slices.Contains(values, fmt.Sprint(field.Interface())) {
+		return &Violation{Code: "one_of", Params: map[string]any{"values": values}}
+	}
+
+	return nil
+}
+
+var patterns sync.Map
+
+func matchesRegex(field reflect.Value, param string) *Violation {
+	rx, ok := patterns.Load(param)
+	if !ok {
+		rx, _ = patterns.LoadOrStore(param, regexp.MustCompile(param))
+	}
+
+	if !Matches(field.String(), rx.(*regexp.Regexp)) {
+		return &Violation{Code: "pattern"}
+	}
+
+	return nil
+}
+
+func number(field reflect.Value) float64 {
+	switch {
+	case field.CanInt():
+		return float64(field.Int())
+	case field.CanUint():
+		return float64(field.Uint())
+	case field.CanFloat():
+		return field.Float()
+	default:
+		panic(fmt.Sprintf("validator: can't compare a %s by value", field.Type()))
+	}
+}
+
+func intParam(rule, param string) int {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		panic(fmt.Sprintf("validator: %s needs a number, got %q", rule, param))
+	}
+
+	return n
+}
+
+func floatParam(rule, param string) float64 {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validator: %s needs a number, got %q", rule, param))
+	}
+
+	return n
+}
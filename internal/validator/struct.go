@@ -0,0 +1,168 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrNotStruct is returned by Struct for arguments that aren't a struct or a non-nil
+// pointer to one.
+var ErrNotStruct = errors.New("validator: not a struct")
+
+// Struct checks the fields of s, a struct or a pointer to one, against their validate
+// tags, e.g.
+//
+//	Name string `json:"name" validate:"required,max=500"`
+//
+// Rules are comma separated, a parameter follows = and commas in it are escaped as \,.
+// Every rule but required passes on zero values, so optional fields only need to be
+// valid when set. Nested structs and slices of them are checked too; violations are
+// keyed by the dotted path of json names, e.g. events.0.recipient. Only the first
+// violation of a field is kept. Unknown rules are programming errors and panic.
+func (v *Validator) Struct(s any) error {
+	sv := reflect.Indirect(reflect.ValueOf(s))
+	if sv.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %T", ErrNotStruct, s)
+	}
+
+	v.structValue(sv, "")
+
+	return nil
+}
+
+func (v *Validator) structValue(sv reflect.Value, prefix string) {
+	st := sv.Type()
+
+	for i := range st.NumField() {
+		sf := st.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		key := prefix + fieldName(sf)
+		fv := sv.Field(i)
+
+		for _, rule := range parseTag(sf.Tag.Get("validate")) {
+			if violation := rule.check(fv); violation != nil {
+				v.Add(key, *violation)
+				break
+			}
+		}
+
+		v.nested(fv, key)
+	}
+}
+
+// nested descends into struct fields and the elements of slices of structs.
+func (v *Validator) nested(fv reflect.Value, key string) {
+	if fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			return
+		}
+		fv = fv.Elem()
+	}
+
+	switch fv.Kind() {
+	case reflect.Struct:
+		v.structValue(fv, key+".")
+	case reflect.Slice, reflect.Array:
+		elem := fv.Type().Elem()
+		if elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct {
+			return
+		}
+
+		for i := range fv.Len() {
+			v.nested(fv.Index(i), key+"."+strconv.Itoa(i))
+		}
+	default:
+	}
+}
+
+// fieldName is the json name of the field, the one clients know it by.
+func fieldName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return sf.Name
+	}
+
+	return name
+}
+
+type boundRule struct {
+	name  string
+	param string
+	rule  Rule
+}
+
+func (b boundRule) check(fv reflect.Value) *Violation {
+	if b.name != "required" && fv.IsZero() {
+		return nil
+	}
+
+	return b.rule(fv, b.param)
+}
+
+func parseTag(tag string) []boundRule {
+	var bound []boundRule
+	for _, t := range ParseTag(tag) {
+		rule, ok := lookup(t.Name)
+		if !ok {
+			panic(fmt.Sprintf("validator: unknown rule %q in tag %q", t.Name, tag))
+		}
+
+		bound = append(bound, boundRule{name: t.Name, param: t.Param, rule: rule})
+	}
+
+	return bound
+}
+
+// TagRule is a rule named in a validate tag, e.g. max with the parameter 500.
+type TagRule struct {
+	Name  string
+	Param string
+}
+
+// ParseTag splits a validate tag into its rules, for code describing the constraints
+// a tag puts on a field. Rules aren't looked up, so unknown ones are returned too.
+func ParseTag(tag string) []TagRule {
+	if tag == "" {
+		return nil
+	}
+
+	var parsed []TagRule
+	for _, entry := range splitEscaped(tag) {
+		name, param, _ := strings.Cut(entry, "=")
+		parsed = append(parsed, TagRule{Name: name, Param: param})
+	}
+
+	return parsed
+}
+
+// splitEscaped splits on commas not escaped as \,.
+func splitEscaped(tag string) []string {
+	var (
+		parts []string
+		b     strings.Builder
+	)
+
+	for i := 0; i < len(tag); i++ {
+		switch {
+		case tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',':
+			b.WriteByte(',')
+			i++
+		case tag[i] == ',':
+			parts = append(parts, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(tag[i])
+		}
+	}
+
+	return append(parts, b.String())
+}
@@ -0,0 +1,150 @@
+package validator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type address struct {
+	City    string `json:"city" validate:"required,maxrunes=5"`
+	Country string `json:"country" validate:"oneof=LT|LV|EE"`
+}
+
+type profile struct {
+	Name      string    `json:"name" validate:"required,max=10"`
+	Email     string    `json:"email" validate:"email"`
+	Website   string    `json:"website" validate:"url"`
+	ID        string    `json:"id" validate:"uuid"`
+	Age       int       `json:"age" validate:"min=18,max=130"`
+	Tags      []string  `json:"tags" validate:"max=2"`
+	Code      string    `json:"code" validate:"regex=^[a-z]{2\\,3}$"`
+	Nickname  string    `validate:"even"`
+	Home      *address  `json:"home"`
+	Addresses []address `json:"addresses" validate:"required"`
+	internal  string
+}
+
+func init() {
+	Register("even", func(field reflect.Value, _ string) *Violation {
+		if len(field.String())%2 != 0 {
+			return &Violation{Code: "even"}
+		}
+		return nil
+	})
+	AddMessages(DefaultLocale, map[string]string{"even": "must have an even length"})
+}
+
+func TestValidator_Struct(t *testing.T) {
+	tests := []struct {
+		name  string
+		input any
+		want  map[string]string
+	}{
+		{
+			name: "Valid",
+			input: &profile{
+				Name:      "Jonas",
+				Email:     "jonas@example.com",
+				Website:   "https://example.com",
+				ID:        "0b6c3f0e-1111-4c1e-9d7a-1c5b0f3e2a01",
+				Age:       30,
+				Code:      "lt",
+				Nickname:  "jj",
+				Addresses: []address{{City: "Riga", Country: "LV"}},
+			},
+			want: map[string]string{},
+		},
+		{
+			name:  "Empty slice is missing",
+			input: profile{Name: "Jonas", Addresses: []address{}},
+			want:  map[string]string{"addresses": "must be provided"},
+		},
+		{
+			name:  "Zero values only fail required",
+			input: profile{Addresses: []address{{}}},
+			want:  map[string]string{"name": "must be provided", "addresses.0.city": "must be provided"},
+		},
+		{
+			name: "Violations",
+			input: profile{
+				Name:      strings.Repeat("a", 11),
+				Email:     "jonas@example",
+				Website:   "example.com",
+				ID:        "42",
+				Age:       12,
+				Tags:      []string{"a", "b", "c"},
+				Code:      "ltu1",
+				Nickname:  "odd",
+				Home:      &address{City: "Vilnius", Country: "PL"},
+				Addresses: []address{{City: "Riga"}, {City: "Šiauliai"}},
+			},
+			want: map[string]string{
+				"name":             "must not be more than 10 bytes long",
+				"email":            "must be a valid email address",
+				"website":          "must be a valid URL",
+				"id":               "must be a valid UUID",
+				"age":              "must be at least 18",
+				"tags":             "must not contain more than 2 items",
+				"code":             "is not in the expected format",
+				"Nickname":         "must have an even length",
+				"home.city":        "must not be more than 5 characters long",
+				"home.country":     "must be one of LT, LV, EE",
+				"addresses.1.city": "must not be more than 5 characters long",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New()
+
+			assert.NoError(t, v.Struct(tt.input))
+
+			assert.Equal(t, tt.want, v.Errors())
+		})
+	}
+}
+
+func TestValidator_Struct_Violations(t *testing.T) {
+	v := New()
+
+	err := v.Struct(profile{Name: "Jonas", Age: 12, Home: &address{City: "Kaunas", Country: "PL"}, Addresses: []address{}})
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]Violation{
+		"age":          {Code: "min_value", Params: map[string]any{"min": float64(18)}},
+		"home.city":    {Code: "max_chars", Params: map[string]any{"max": 5}},
+		"home.country": {Code: "one_of", Params: map[string]any{"values": []string{"LT", "LV", "EE"}}},
+		"addresses":    {Code: "required"},
+	}, v.Violations())
+}
+
+func TestValidator_Struct_KeepsImperativeErrors(t *testing.T) {
+	v := New()
+	v.Check(false, "name", "is taken")
+
+	assert.NoError(t, v.Struct(address{}))
+
+	assert.Equal(t, map[string]string{"name": "is taken", "city": "must be provided"}, v.Errors())
+}
+
+func TestValidator_Struct_UnknownRule(t *testing.T) {
+	type input struct {
+		Name string `validate:"shiny"`
+	}
+
+	assert.PanicsWithValue(t, `validator: unknown rule "shiny" in tag "shiny"`, func() { _ = New().Struct(input{}) })
+}
+
+func TestValidator_Struct_NotStruct(t *testing.T) {
+	var nilProfile *profile
+
+	for _, s := range []any{nil, nilProfile, "profile", []profile{}} {
+		assert.ErrorIs(t, New().Struct(s), ErrNotStruct)
+	}
+
+	assert.NoError(t, New().Struct(&address{City: "Riga"}))
+}
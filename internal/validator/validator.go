@@ -2,24 +2,41 @@ package validator
 
 import "regexp"
 
+// CodeInvalid is the code of violations added with a message rather than a code.
+const CodeInvalid = "invalid"
+
+// Violation is a failed rule. Code selects the message in the catalog, Params fill in
+// its placeholders, e.g. min_length with {"min": 8}. Message is the untranslated text of
+// violations added by AddError and Check, it's used instead of the catalog when set.
+type Violation struct {
+	Code    string
+	Params  map[string]any
+	Message string
+}
+
 type Validator struct {
-	errors map[string]string
+	violations map[string]Violation
 }
 
 func New() *Validator {
-	return &Validator{errors: make(map[string]string)}
+	return &Validator{violations: make(map[string]Violation)}
 }
 
 func (v *Validator) Valid() bool {
-	return len(v.errors) == 0
+	return len(v.violations) == 0
 }
 
-func (v *Validator) AddError(key, message string) {
-	if _, exists := v.errors[key]; !exists {
-		v.errors[key] = message
+// Add keeps the first violation of key.
+func (v *Validator) Add(key string, violation Violation) {
+	if _, exists := v.violations[key]; !exists {
+		v.violations[key] = violation
 	}
 }
 
+func (v *Validator) AddError(key, message string) {
+	v.Add(key, Violation{Code: CodeInvalid, Message: message})
+}
+
 //nolint:revive,flag-parameter
 func (v *Validator) Check(ok bool, key, message string) {
 	if !ok {
@@ -27,8 +44,25 @@ func (v *Validator) Check(ok bool, key, message string) {
 	}
 }
 
+//nolint:revive,flag-parameter
+func (v *Validator) CheckCode(ok bool, key, code string, params map[string]any) {
+	if !ok {
+		v.Add(key, Violation{Code: code, Params: params})
+	}
+}
+
+func (v *Validator) Violations() map[string]Violation {
+	return v.violations
+}
+
+// Errors returns the messages of the violations in DefaultLocale.
 func (v *Validator) Errors() map[string]string {
-	return v.errors
+	errors := make(map[string]string, len(v.violations))
+	for key, violation := range v.violations {
+		errors[key] = Translate(DefaultLocale, violation)
+	}
+
+	return errors
 }
 
 func Matches(value string, rx *regexp.Regexp) bool {
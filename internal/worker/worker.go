@@ -1,32 +1,84 @@
 package worker
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kiennyo/syncwatch-be/internal/db"
 )
 
+const tracerName = "github.com/kiennyo/syncwatch-be/internal/worker"
+
 var wg sync.WaitGroup
 
-func Background(fn func()) {
+var pending atomic.Int64
+
+var tasks = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "syncwatch",
+	Subsystem: "worker",
+	Name:      "tasks_total",
+	Help:      "Background tasks by outcome: started, completed or panicked.",
+}, []string{"outcome"})
+
+var _ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+	Namespace: "syncwatch",
+	Subsystem: "worker",
+	Name:      "tasks_pending",
+	Help:      "Background tasks not finished yet.",
+}, func() float64 {
+	return float64(pending.Load())
+})
+
+// Background runs fn in a goroutine Wait waits for. fn gets a context that isn't
+// cancelled with ctx, the request is usually done before the task, and a span of its
+// own trace linked to the span of ctx. The context leaves out the transaction of ctx,
+// which ends before the task does.
+func Background(ctx context.Context, name string, fn func(ctx context.Context)) {
 	wg.Add(1)
+	pending.Add(1)
+	tasks.WithLabelValues("started").Inc()
+
+	ctx, span := otel.Tracer(tracerName).Start(db.WithoutTx(context.WithoutCancel(ctx)), name,
+		trace.WithNewRoot(),
+		trace.WithLinks(trace.LinkFromContext(ctx)),
+	)
 
 	// Launch a background goroutine.
 	go func() {
 		defer wg.Done()
+		defer pending.Add(-1)
+		defer span.End()
 
 		// Recover any panic.
 		defer func() {
 			if err := recover(); err != nil {
+				tasks.WithLabelValues("panicked").Inc()
+				span.SetStatus(codes.Error, fmt.Sprint(err))
 				slog.Error(fmt.Errorf("failed to execute background task: %s", err).Error())
 			}
 		}()
 
 		// Execute the arbitrary function that we passed as the parameter.
-		fn()
+		fn(ctx)
+
+		tasks.WithLabelValues("completed").Inc()
 	}()
 }
 
 func Wait() {
 	wg.Wait()
 }
+
+// Pending returns the number of background tasks not finished yet.
+func Pending() int {
+	return int(pending.Load())
+}
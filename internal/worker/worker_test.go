@@ -1,8 +1,14 @@
 package worker
 
 import (
+	"context"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 //nolint:revive,cognitive-complexity
@@ -24,11 +30,11 @@ func TestBackground(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(_ *testing.T) {
 			if tt.wantErr {
-				Background(func() {
+				Background(context.Background(), "panic", func(_ context.Context) {
 					panic("This is a test panic")
 				})
 			} else {
-				Background(func() {
+				Background(context.Background(), "sleep", func(_ context.Context) {
 					time.Sleep(time.Second)
 				})
 			}
@@ -36,3 +42,34 @@ func TestBackground(t *testing.T) {
 		})
 	}
 }
+
+func TestBackground_LinksSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx, request := otel.Tracer("test").Start(context.Background(), "request")
+	ctx, cancel := context.WithCancel(ctx)
+	cancel() // the request is over
+
+	var taskErr error
+	Background(ctx, "task", func(ctx context.Context) {
+		taskErr = ctx.Err()
+	})
+
+	request.End()
+	Wait()
+
+	assert.NoError(t, taskErr)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+
+	task := spans[1]
+	if task.Name() != "task" {
+		task = spans[0]
+	}
+
+	assert.NotEqual(t, request.SpanContext().TraceID(), task.SpanContext().TraceID())
+	assert.Len(t, task.Links(), 1)
+	assert.Equal(t, request.SpanContext().SpanID(), task.Links()[0].SpanContext.SpanID())
+}
@@ -1,4 +1,4 @@
-DROP TABLE IF EXISTS role;
-DROP TABLE IF EXISTS permission;
+DROP TABLE IF EXISTS "user";
 DROP TABLE IF EXISTS role_permission;
-DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS permission;
+DROP TABLE IF EXISTS role;
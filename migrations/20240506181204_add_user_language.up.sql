@@ -0,0 +1 @@
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT 'en';
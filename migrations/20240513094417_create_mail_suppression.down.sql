@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS mail_send_log;
+DROP TABLE IF EXISTS mail_suppression;
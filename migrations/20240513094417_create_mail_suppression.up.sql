@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS mail_suppression
+(
+    email      CITEXT PRIMARY KEY          NOT NULL,
+    reason     TEXT                        NOT NULL,
+    detail     TEXT                        NOT NULL DEFAULT '',
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS mail_send_log
+(
+    id         UUID PRIMARY KEY            NOT NULL DEFAULT gen_random_uuid(),
+    recipient  CITEXT                      NOT NULL,
+    template   TEXT                        NOT NULL DEFAULT '',
+    status     TEXT                        NOT NULL
+        CHECK (status IN ('sent', 'failed', 'suppressed', 'bounced', 'complained')),
+    attempts   INT                         NOT NULL DEFAULT 0,
+    detail     TEXT                        NOT NULL DEFAULT '',
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS mail_send_log_recipient_idx ON mail_send_log (recipient, created_at);
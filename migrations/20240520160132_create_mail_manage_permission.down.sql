@@ -0,0 +1,2 @@
+DELETE FROM role_permission WHERE permission_id = (SELECT id FROM permission WHERE slug = 'mail:manage');
+DELETE FROM permission WHERE slug = 'mail:manage';
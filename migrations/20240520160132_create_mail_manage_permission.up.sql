@@ -0,0 +1,9 @@
+WITH permission_insertion AS (
+    INSERT INTO permission (title, slug, description)
+        VALUES ('Manage emails', 'mail:manage', 'Preview email templates and send test emails.')
+        RETURNING id AS p_id)
+
+INSERT
+INTO role_permission (role_id, permission_id)
+SELECT (SELECT id FROM role WHERE slug = 'admin'), permission_insertion.p_id
+FROM permission_insertion;
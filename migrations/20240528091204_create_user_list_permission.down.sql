@@ -0,0 +1,2 @@
+DELETE FROM role_permission WHERE permission_id = (SELECT id FROM permission WHERE slug = 'user:list');
+DELETE FROM permission WHERE slug = 'user:list';
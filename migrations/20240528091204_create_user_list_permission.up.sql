@@ -0,0 +1,9 @@
+WITH permission_insertion AS (
+    INSERT INTO permission (title, slug, description)
+        VALUES ('List users', 'user:list', 'Browse, filter and sort all users.')
+        RETURNING id AS p_id)
+
+INSERT
+INTO role_permission (role_id, permission_id)
+SELECT (SELECT id FROM role WHERE slug = 'admin'), permission_insertion.p_id
+FROM permission_insertion;
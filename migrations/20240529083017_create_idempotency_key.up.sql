@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS idempotency_key
+(
+    principal   TEXT                     NOT NULL,
+    route       TEXT                     NOT NULL,
+    key         TEXT                     NOT NULL,
+    fingerprint BYTEA                    NOT NULL,
+    status      INTEGER,
+    header      JSONB,
+    body        BYTEA,
+    locked_at   TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at  TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (principal, route, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_key_expires_at_idx ON idempotency_key (expires_at);
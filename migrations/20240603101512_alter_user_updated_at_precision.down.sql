@@ -0,0 +1 @@
+ALTER TABLE "user" ALTER COLUMN updated_at TYPE TIMESTAMP(0) WITH TIME ZONE;
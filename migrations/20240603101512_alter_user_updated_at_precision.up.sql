@@ -0,0 +1 @@
+ALTER TABLE "user" ALTER COLUMN updated_at TYPE TIMESTAMP(6) WITH TIME ZONE;